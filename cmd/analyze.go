@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/konveyor/cpma/pkg/bundle"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/transform"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <bundle>",
+	Short: "Generates manifests and reports from a bundle",
+	Long: `Runs all transforms against a bundle created by collect. No SSH connection nor kubeconfig is needed,
+the bundle is unpacked into the work directory and analyzed in local mode`,
+	Run: func(cmd *cobra.Command, args []string) {
+		env.InitLogger()
+
+		if err := env.ReadConfig(); err != nil {
+			logrus.Fatal(err)
+		}
+
+		b, err := bundle.ReadFile(args[0])
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		if err := transform.ConfigureBundleAnalysis(b); err != nil {
+			logrus.Fatal(err)
+		}
+
+		if err := transform.Start(); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+	Args: cobra.ExactArgs(1),
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/transform"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(collectCmd)
+}
+
+var collectCmd = &cobra.Command{
+	Use:   "collect [bundle]",
+	Short: "Collects OCP3 source files and API resources into a bundle",
+	Long: `Fetches every source file and API resource list used by transforms and saves them into a checksummed
+tar.gz bundle, which can be analyzed later on, anywhere, using analyze. Default bundle is <work-dir>/cpma-<hostname>.tar.gz`,
+	Run: func(cmd *cobra.Command, args []string) {
+		env.InitLogger()
+
+		if err := env.InitConfig(); err != nil {
+			logrus.Fatal(err)
+		}
+
+		b, err := transform.Collect(BuildVersion)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		bundleFile := filepath.Join(env.Config().GetString("WorkDir"), "cpma-"+env.Config().GetString("Hostname")+".tar.gz")
+		if len(args) > 0 {
+			bundleFile = args[0]
+		}
+
+		if err := b.WriteFile(bundleFile); err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Infof("Bundle saved to %s", bundleFile)
+	},
+	Args: cobra.MaximumNArgs(1),
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configInitCmd.Flags().BoolP("force", "f", false, "overwrite an existing configuration file")
+
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manages CPMA configuration",
+	Long:  `Writes a configuration file template, validates or shows the configuration merged from flags, environment and configuration file`,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init [file]",
+	Short: "Writes a commented configuration file template",
+	Long:  `Writes a commented configuration file template to the given file, --config file or $HOME/cpma.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := env.ReadConfig(); err != nil {
+			logrus.Fatal(err)
+		}
+
+		file := env.ConfigFile
+		if len(args) > 0 {
+			file = args[0]
+		}
+
+		flags := os.O_CREATE | os.O_WRONLY | os.O_EXCL
+		if force, _ := cmd.Flags().GetBool("force"); force {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		}
+
+		f, err := os.OpenFile(file, flags, 0640)
+		if err != nil {
+			if os.IsExist(err) {
+				logrus.Fatalf("%s already exists, use --force to overwrite it", file)
+			}
+			logrus.Fatal(err)
+		}
+		defer f.Close()
+
+		if err := env.WriteConfigTemplate(f); err != nil {
+			logrus.Fatal(err)
+		}
+		fmt.Printf("Configuration template written to %s\n", file)
+	},
+	Args: cobra.MaximumNArgs(1),
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates the configuration",
+	Long: `Checks value types, required values, that config files exist locally in local mode,
+SSH port range and that the cluster has a kubeconfig context`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := env.ReadConfig(); err != nil {
+			logrus.Fatal(err)
+		}
+
+		if problems := env.ValidateConfig(); len(problems) > 0 {
+			fmt.Fprintf(os.Stderr, "Configuration is invalid:\n  - %s\n", strings.Join(problems, "\n  - "))
+			os.Exit(1)
+		}
+		fmt.Println("Configuration is valid")
+	},
+	Args: cobra.MaximumNArgs(0),
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Shows the configuration along with the source of each value",
+	Long:  `Shows the configuration merged from flags, environment and configuration file, secret values are masked`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := env.ReadConfig(); err != nil {
+			logrus.Fatal(err)
+		}
+
+		if err := env.ShowConfig(os.Stdout, cmd.Flags().Changed); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+	Args: cobra.MaximumNArgs(0),
+}
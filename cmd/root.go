@@ -15,10 +15,13 @@
 package cmd
 
 import (
+	"strings"
+
 	//Workaround go mod vendor issue 27063
 	_ "github.com/shurcooL/vfsgen"
 
 	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io/remotehost"
 	"github.com/konveyor/cpma/pkg/transform"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -35,13 +38,28 @@ func init() {
 	env.Config().BindPFlag("InsecureHostKey", rootCmd.PersistentFlags().Lookup("allow-insecure-host"))
 
 	// Set configuration source
-	rootCmd.PersistentFlags().String("config-source", "", "source for OCP3 config files, accepted values: remote or local")
+	rootCmd.PersistentFlags().String("config-source", "", "source for OCP3 config files, accepted values: remote, local, archive or node-exec")
 	env.Config().BindPFlag("ConfigSource", rootCmd.PersistentFlags().Lookup("config-source"))
 
+	// Get archive holding OCP3 config files, used when config source is archive
+	rootCmd.PersistentFlags().String("archive", "", "path to a .tar, .tar.gz or .tar.xz archive holding OCP3 config files, such as a sosreport")
+	env.Config().BindPFlag("Archive", rootCmd.PersistentFlags().Lookup("archive"))
+
+	// Escalate privileges on the master to read files and run commands
+	rootCmd.PersistentFlags().String("become-method", "", "privilege escalation method on the master, accepted values: none, sudo or su (default \""+env.DefaultBecomeMethod+"\")")
+	env.Config().BindPFlag("BecomeMethod", rootCmd.PersistentFlags().Lookup("become-method"))
+
+	rootCmd.PersistentFlags().String("become-user", "", "user files are read and commands run as on the master (default \""+env.DefaultBecomeUser+"\")")
+	env.Config().BindPFlag("BecomeUser", rootCmd.PersistentFlags().Lookup("become-user"))
+
 	// Get OCP3 source cluster name that is used in kubeconfig and save it to viper config
 	rootCmd.PersistentFlags().StringP("cluster-name", "c", "", "OCP3 cluster kubeconfig name")
 	env.Config().BindPFlag("ClusterName", rootCmd.PersistentFlags().Lookup("cluster-name"))
 
+	// Read API resources from a dump instead of a live cluster
+	rootCmd.PersistentFlags().String("cluster-dump", "", "path to a directory of API resources dumped as YAML or JSON, used instead of a live cluster")
+	env.Config().BindPFlag("ClusterDump", rootCmd.PersistentFlags().Lookup("cluster-dump"))
+
 	// Get crio config file location
 	rootCmd.PersistentFlags().String("crio-config", "", "path to crio config file")
 	env.Config().BindPFlag("CrioConfigFile", rootCmd.PersistentFlags().Lookup("crio-config"))
@@ -50,14 +68,33 @@ func init() {
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "show debug ouput")
 	env.Config().BindPFlag("Debug", rootCmd.PersistentFlags().Lookup("debug"))
 
+	// Read files through a debug pod on the node when config source is node-exec
+	rootCmd.PersistentFlags().String("debug-image", "", "image of debug pods reading files on the node, used when config source is node-exec (default \""+env.DefaultDebugImage+"\")")
+	env.Config().BindPFlag("DebugImage", rootCmd.PersistentFlags().Lookup("debug-image"))
+
+	rootCmd.PersistentFlags().String("debug-namespace", "", "namespace of debug pods reading files on the node, used when config source is node-exec (default \""+env.DefaultDebugNamespace+"\")")
+	env.Config().BindPFlag("DebugNamespace", rootCmd.PersistentFlags().Lookup("debug-namespace"))
+
 	// Get etcd config file location
 	rootCmd.PersistentFlags().String("etcd-config", "", "path to etcd config file")
 	env.Config().BindPFlag("ETCDConfigFile", rootCmd.PersistentFlags().Lookup("etcd-config"))
 
+	// Trust SSH host keys matching pinned fingerprints when first connecting to a host
+	rootCmd.PersistentFlags().StringSlice("host-key-fingerprint", nil, "pinned SSH host key fingerprint as host=SHA256:fingerprint, trusted without prompting when the host is first connected to, can be repeated")
+	env.Config().BindPFlag("HostKeyFingerprints", rootCmd.PersistentFlags().Lookup("host-key-fingerprint"))
+
 	// Get OCP3 source cluster and save it to viper config
 	rootCmd.PersistentFlags().StringP("hostname", "n", "", "OCP3 cluster hostname")
 	env.Config().BindPFlag("Hostname", rootCmd.PersistentFlags().Lookup("hostname"))
 
+	// Reach the master through SSH jump hosts
+	rootCmd.PersistentFlags().StringSlice("jump-host", nil, "SSH jump host to reach the master through, as [user@]host[:port][=keyfile], can be repeated in connection order")
+	env.Config().BindPFlag("JumpHosts", rootCmd.PersistentFlags().Lookup("jump-host"))
+
+	// Never prompt for missing values, also the case when stdin isn't a terminal
+	rootCmd.PersistentFlags().Bool("non-interactive", false, "never prompt, apply default values and fail listing every missing required setting")
+	env.Config().BindPFlag("NonInteractive", rootCmd.PersistentFlags().Lookup("non-interactive"))
+
 	// Get node config file location
 	rootCmd.PersistentFlags().String("node-config", "", "path to node config file")
 	env.Config().BindPFlag("NodeConfigFile", rootCmd.PersistentFlags().Lookup("node-config"))
@@ -70,6 +107,22 @@ func init() {
 	rootCmd.PersistentFlags().String("master-config", "", "path to master config file")
 	env.Config().BindPFlag("MasterConfigFile", rootCmd.PersistentFlags().Lookup("master-config"))
 
+	// Limit the number of transforms running concurrently
+	rootCmd.PersistentFlags().Int("parallelism", transform.DefaultParallelism, "maximum number of transforms to run concurrently")
+	env.Config().BindPFlag("Parallelism", rootCmd.PersistentFlags().Lookup("parallelism"))
+
+	// Select components to run
+	components := strings.Join(transform.RegisteredTransforms(), ", ")
+	rootCmd.PersistentFlags().StringSlice("only", nil, "run only listed components, available components: "+components)
+	env.Config().BindPFlag("Only", rootCmd.PersistentFlags().Lookup("only"))
+
+	rootCmd.PersistentFlags().StringSlice("skip", nil, "skip listed components")
+	env.Config().BindPFlag("Skip", rootCmd.PersistentFlags().Lookup("skip"))
+
+	// Select a profile of the configuration file
+	rootCmd.PersistentFlags().String("profile", "", "profile of the configuration file to use, its values override shared ones")
+	env.Config().BindPFlag("Profile", rootCmd.PersistentFlags().Lookup("profile"))
+
 	// Get registries config file location
 	rootCmd.PersistentFlags().String("registries-config", "", "path to registries config file")
 	env.Config().BindPFlag("RegistriesConfigFile", rootCmd.PersistentFlags().Lookup("registries-config"))
@@ -88,6 +141,17 @@ func init() {
 	rootCmd.PersistentFlags().Int16P("ssh-port", "p", 0, "OCP3 ssh port")
 	env.Config().BindPFlag("SSHPort", rootCmd.PersistentFlags().Lookup("ssh-port"))
 
+	// Keep SSH connections alive and limit sessions opened on a host at the same time
+	rootCmd.PersistentFlags().Int("ssh-keepalive", int(remotehost.DefaultKeepAlive.Seconds()), "interval in seconds of SSH keepalive requests, 0 disables them")
+	env.Config().BindPFlag("SSHKeepAlive", rootCmd.PersistentFlags().Lookup("ssh-keepalive"))
+
+	rootCmd.PersistentFlags().Int("ssh-max-sessions", remotehost.DefaultMaxSessions, "maximum number of SSH sessions open at the same time on a host")
+	env.Config().BindPFlag("SSHMaxSessions", rootCmd.PersistentFlags().Lookup("ssh-max-sessions"))
+
+	// Read remote files using sftp-server run as the become user
+	rootCmd.PersistentFlags().String("sftp-server", "", "path of sftp-server on the master, run using the become method when SSH login isn't the become user (default \""+env.DefaultSFTPServer+"\")")
+	env.Config().BindPFlag("SFTPServer", rootCmd.PersistentFlags().Lookup("sftp-server"))
+
 	// Don't output logs to console if true
 	rootCmd.PersistentFlags().BoolP("silent", "s", false, "silent mode, disable logging output to console")
 	env.Config().BindPFlag("Silent", rootCmd.PersistentFlags().Lookup("silent"))
@@ -109,7 +173,9 @@ var rootCmd = &cobra.Command{
 			logrus.Fatal(err)
 		}
 
-		transform.Start()
+		if err := transform.Start(); err != nil {
+			logrus.Fatal(err)
+		}
 	},
 	Args: cobra.MaximumNArgs(0),
 }
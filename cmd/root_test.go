@@ -10,21 +10,33 @@ import (
 )
 
 func TestInitDefaults(t *testing.T) {
+	assert.Equal(t, "", env.Config().GetString("Archive"))
+	assert.Equal(t, "", env.Config().GetString("BecomeMethod"))
+	assert.Equal(t, "", env.Config().GetString("BecomeUser"))
 	assert.Equal(t, "", env.Config().GetString("ConfigSource"))
 	assert.Equal(t, "", env.Config().GetString("ClusterName"))
+	assert.Equal(t, "", env.Config().GetString("ClusterDump"))
 	assert.Equal(t, "", env.Config().GetString("CRIOConfigFile"))
 	assert.Equal(t, false, env.Config().Get("Debug"))
+	assert.Equal(t, "", env.Config().GetString("DebugImage"))
+	assert.Equal(t, "", env.Config().GetString("DebugNamespace"))
 	assert.Equal(t, "", env.Config().GetString("ETCDConfigfile"))
+	assert.Empty(t, env.Config().GetStringSlice("HostKeyFingerprints"))
 	assert.Equal(t, "", env.Config().GetString("Hostname"))
+	assert.Empty(t, env.Config().GetStringSlice("JumpHosts"))
 	assert.Equal(t, false, env.Config().Get("InsecureHostKey"))
+	assert.Equal(t, false, env.Config().Get("NonInteractive"))
 	assert.Equal(t, "", env.Config().GetString("NodeConfigFile"))
 	assert.Equal(t, "", env.Config().GetString("MasterConfigFile"))
 	assert.Equal(t, true, env.Config().Get("Manifests"))
+	assert.Equal(t, "", env.Config().GetString("Profile"))
 	assert.Equal(t, "", env.Config().GetString("RegistriesConfigFile"))
 	assert.Equal(t, true, env.Config().Get("Reporting"))
 	assert.Equal(t, "", env.Config().GetString("SSHPrivateKey"))
 	assert.Equal(t, "", env.Config().GetString("SSHLogin"))
 	assert.Equal(t, "0", env.Config().GetString("SSHPort"))
+	assert.Equal(t, 30, env.Config().GetInt("SSHKeepAlive"))
+	assert.Equal(t, 10, env.Config().GetInt("SSHMaxSessions"))
 	assert.Equal(t, false, env.Config().Get("Silent"))
 	assert.Equal(t, "", env.Config().GetString("WorkDIr"))
 }
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/fleet"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCmd.Flags().Bool("all", false, "process every cluster of the cluster list")
+	runCmd.Flags().String("clusters", "", "cluster list file, profiles of the configuration file are used when not set")
+	runCmd.Flags().Int("fleet-parallelism", 1, "maximum number of clusters processed concurrently")
+	runCmd.Flags().Bool("consolidate", false, "merge cluster-scoped manifests of all clusters into manifests for a single OCP4 cluster")
+
+	rootCmd.AddCommand(runCmd)
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [cluster...]",
+	Short: "Generates manifests and reports for one or several clusters",
+	Long: `Without arguments, processes a single cluster like cpma does.
+With --all or cluster names, processes clusters of the cluster list, each one non-interactively into its own
+work directory subdirectory, then writes a fleet report aggregating their reports.
+With --consolidate, cluster-scoped manifests of all clusters are merged into the work directory manifests
+and conflicting values are added to the fleet report`,
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		if !all && len(args) == 0 {
+			rootCmd.Run(cmd, args)
+			return
+		}
+
+		env.InitLogger()
+
+		if err := env.ReadConfig(); err != nil {
+			logrus.Fatal(err)
+		}
+
+		clusters, err := loadClusters(cmd)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		if !all {
+			if clusters, err = fleet.Select(clusters, args); err != nil {
+				logrus.Fatal(err)
+			}
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		workDir := env.Config().GetString("WorkDir")
+		if workDir == "" {
+			workDir = env.DefaultWorkDir
+			env.Config().Set("WorkDir", workDir)
+		}
+
+		parallelism, _ := cmd.Flags().GetInt("fleet-parallelism")
+		runner := fleet.Runner{
+			Executable:  executable,
+			Env:         forwardedFlags(cmd),
+			WorkDir:     workDir,
+			Parallelism: parallelism,
+		}
+		if env.ConfigFile != "" {
+			runner.Args = []string{"--config", env.ConfigFile}
+		}
+
+		report := runner.Run(clusters)
+		var consolidateErr error
+		if consolidate, _ := cmd.Flags().GetBool("consolidate"); consolidate {
+			consolidateErr = runner.Consolidate(&report)
+		}
+
+		if err := reportoutput.DumpFleetReport(report); err != nil {
+			logrus.Fatal(err)
+		}
+
+		if consolidateErr != nil {
+			logrus.Fatal(consolidateErr)
+		}
+
+		failed := []string{}
+		for _, c := range report.Clusters {
+			if c.Status == reportoutput.StatusFailed || c.Status == reportoutput.StatusPartial {
+				failed = append(failed, c.Name)
+			}
+		}
+		if len(failed) > 0 {
+			logrus.Fatalf("Processing failed for clusters: %s", strings.Join(failed, ", "))
+		}
+	},
+}
+
+// loadClusters reads the cluster list file, or makes a cluster of every profile when there is none
+func loadClusters(cmd *cobra.Command) ([]fleet.Cluster, error) {
+	if file, _ := cmd.Flags().GetString("clusters"); file != "" {
+		return fleet.LoadClusterList(file)
+	}
+
+	profiles := env.ProfileNames()
+	if len(profiles) == 0 {
+		return nil, errors.New("No cluster list given with --clusters and no profiles in configuration file")
+	}
+
+	return fleet.ProfileClusters(profiles), nil
+}
+
+// forwardedFlags returns flags set for the run as environment variables, so they apply to every cluster
+// while cluster settings still override them
+func forwardedFlags(cmd *cobra.Command) []string {
+	environ := []string{}
+	for _, s := range env.Settings {
+		if s.Flag == "" || !cmd.Flags().Changed(s.Flag) {
+			continue
+		}
+
+		switch s.Key {
+		case "WorkDir", "Profile", "NonInteractive":
+			continue
+		}
+
+		value := env.Config().GetString(s.Key)
+		if s.Type == env.ListSetting {
+			value = strings.Join(env.Config().GetStringSlice(s.Key), " ")
+		}
+		environ = append(environ, s.EnvVar()+"="+value)
+	}
+
+	return environ
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	o7tauthv1 "github.com/openshift/api/authorization/v1"
+	o7tquotav1 "github.com/openshift/api/quota/v1"
+	o7troutev1 "github.com/openshift/api/route/v1"
+	o7tsecurityv1 "github.com/openshift/api/security/v1"
+	o7tuserv1 "github.com/openshift/api/user/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/api/apps/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Dump holds API objects read from a directory of YAML or JSON files,
+// such as `oc get -o yaml` outputs or a must-gather, in place of a live cluster
+type Dump struct {
+	objects map[string][]dumpObject
+}
+
+// dumpObject is an API object kept raw until it's decoded into its kind
+type dumpObject struct {
+	namespace string
+	name      string
+	raw       json.RawMessage
+}
+
+// dumpHeader holds fields needed to sort out an API object or a list of them
+type dumpHeader struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// LoadDump reads every .yaml, .yml and .json file found under dir.
+// Files can hold single objects, lists or several YAML documents, objects found more than once are kept once.
+// Files which don't hold API objects, such as JSON arrays, are skipped.
+func LoadDump(dir string) (*Dump, error) {
+	dump := &Dump{objects: make(map[string][]dumpObject)}
+	seen := make(map[string]bool)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if err := dump.add(content, seen); err != nil {
+			logrus.Warnf("Dump:Skipping %s, it doesn't hold API objects: %s", path, err)
+			return nil
+		}
+		logrus.Debugf("Dump:Loaded %s", path)
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load cluster dump")
+	}
+
+	return dump, nil
+}
+
+// add keeps objects of a file, none of them is kept when the file can't be decoded
+func (d *Dump) add(content []byte, seen map[string]bool) error {
+	file := &Dump{objects: make(map[string][]dumpObject)}
+	fileSeen := make(map[string]bool)
+
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+
+		if err := file.addObject(raw, "", fileSeen); err != nil {
+			return err
+		}
+	}
+
+	for kind, objects := range file.objects {
+		for _, object := range objects {
+			key := kind + "/" + object.namespace + "/" + object.name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			d.objects[kind] = append(d.objects[kind], object)
+		}
+	}
+
+	return nil
+}
+
+// addObject keeps an object, or items of a list. Items of typed lists such as PodList
+// may omit their kind, it's then taken from the list kind.
+func (d *Dump) addObject(raw json.RawMessage, defaultKind string, seen map[string]bool) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	var header dumpHeader
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return err
+	}
+
+	kind := header.Kind
+	if kind == "" {
+		kind = defaultKind
+	}
+
+	if strings.HasSuffix(kind, "List") {
+		itemKind := strings.TrimSuffix(kind, "List")
+		for _, item := range header.Items {
+			if err := d.addObject(item, itemKind, seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if kind == "" {
+		return nil
+	}
+
+	key := kind + "/" + header.Metadata.Namespace + "/" + header.Metadata.Name
+	if seen[key] {
+		return nil
+	}
+	seen[key] = true
+
+	d.objects[kind] = append(d.objects[kind], dumpObject{
+		namespace: header.Metadata.Namespace,
+		name:      header.Metadata.Name,
+		raw:       raw,
+	})
+
+	return nil
+}
+
+// items decodes objects of given kind into a list, objects are decoded whatever their API version is.
+// An empty namespace matches every namespace.
+func (d *Dump) items(kind, namespace string, list interface{}) error {
+	objects := make([]dumpObject, 0, len(d.objects[kind]))
+	for _, object := range d.objects[kind] {
+		if namespace == "" || object.namespace == namespace {
+			objects = append(objects, object)
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].namespace != objects[j].namespace {
+			return objects[i].namespace < objects[j].namespace
+		}
+		return objects[i].name < objects[j].name
+	})
+
+	raws := make([]json.RawMessage, 0, len(objects))
+	for _, object := range objects {
+		raws = append(raws, object.raw)
+	}
+
+	content, err := json.Marshal(struct {
+		Items []json.RawMessage `json:"items"`
+	}{Items: raws})
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(json.Unmarshal(content, list), "Failed to decode %s objects", kind)
+}
+
+// Nodes returns nodes found in the dump
+func (d *Dump) Nodes() (*corev1.NodeList, error) {
+	list := &corev1.NodeList{}
+	return list, d.items("Node", "", list)
+}
+
+// ConfigMaps returns config maps of namespace found in the dump
+func (d *Dump) ConfigMaps(namespace string) (*corev1.ConfigMapList, error) {
+	list := &corev1.ConfigMapList{}
+	return list, d.items("ConfigMap", namespace, list)
+}
+
+// namespaces returns names of namespaces found in the dump, either as objects or holding objects
+func (d *Dump) namespaces() []string {
+	found := make(map[string]bool)
+	for kind, objects := range d.objects {
+		for _, object := range objects {
+			if kind == "Namespace" {
+				found[object.name] = true
+			} else if object.namespace != "" {
+				found[object.namespace] = true
+			}
+		}
+	}
+
+	namespaces := make([]string, 0, len(found))
+	for namespace := range found {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	return namespaces
+}
+
+// Resources fills api resources used in report from the dump, like a live cluster would
+func (d *Dump) Resources() (*Resources, error) {
+	resources := &Resources{
+		QuotaList:            &o7tquotav1.ClusterResourceQuotaList{},
+		PersistentVolumeList: &corev1.PersistentVolumeList{},
+		StorageClassList:     &storagev1.StorageClassList{},
+		RBACResources: RBACResources{
+			UsersList:                      &o7tuserv1.UserList{},
+			GroupList:                      &o7tuserv1.GroupList{},
+			ClusterRolesList:               &o7tauthv1.ClusterRoleList{},
+			ClusterRolesBindingsList:       &o7tauthv1.ClusterRoleBindingList{},
+			SecurityContextConstraintsList: &o7tsecurityv1.SecurityContextConstraintsList{},
+		},
+	}
+
+	var err error
+	if resources.NodeList, err = d.Nodes(); err != nil {
+		return nil, err
+	}
+
+	clusterLists := []struct {
+		kind string
+		list interface{}
+	}{
+		{kind: "ClusterResourceQuota", list: resources.QuotaList},
+		{kind: "PersistentVolume", list: resources.PersistentVolumeList},
+		{kind: "StorageClass", list: resources.StorageClassList},
+		{kind: "User", list: resources.RBACResources.UsersList},
+		{kind: "Group", list: resources.RBACResources.GroupList},
+		{kind: "ClusterRole", list: resources.RBACResources.ClusterRolesList},
+		{kind: "ClusterRoleBinding", list: resources.RBACResources.ClusterRolesBindingsList},
+		{kind: "SecurityContextConstraints", list: resources.RBACResources.SecurityContextConstraintsList},
+	}
+	for _, clusterList := range clusterLists {
+		if err := d.items(clusterList.kind, "", clusterList.list); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, namespace := range d.namespaces() {
+		namespaceResources := NamespaceResources{
+			NamespaceName:     namespace,
+			DaemonSetList:     &extv1beta1.DaemonSetList{},
+			DeploymentList:    &v1beta1.DeploymentList{},
+			PodList:           &corev1.PodList{},
+			ResourceQuotaList: &corev1.ResourceQuotaList{},
+			RolesList:         &o7tauthv1.RoleList{},
+			RouteList:         &o7troutev1.RouteList{},
+			PVCList:           &corev1.PersistentVolumeClaimList{},
+		}
+
+		namespaceLists := []struct {
+			kind string
+			list interface{}
+		}{
+			{kind: "DaemonSet", list: namespaceResources.DaemonSetList},
+			{kind: "Deployment", list: namespaceResources.DeploymentList},
+			{kind: "Pod", list: namespaceResources.PodList},
+			{kind: "ResourceQuota", list: namespaceResources.ResourceQuotaList},
+			{kind: "Role", list: namespaceResources.RolesList},
+			{kind: "Route", list: namespaceResources.RouteList},
+			{kind: "PersistentVolumeClaim", list: namespaceResources.PVCList},
+		}
+		for _, namespaceList := range namespaceLists {
+			if err := d.items(namespaceList.kind, namespace, namespaceList.list); err != nil {
+				return nil, err
+			}
+		}
+
+		resources.NamespaceList = append(resources.NamespaceList, namespaceResources)
+	}
+
+	return resources, nil
+}
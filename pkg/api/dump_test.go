@@ -0,0 +1,110 @@
+package api_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDump(t *testing.T) {
+	dump, err := api.LoadDump("testdata/cluster-dump")
+	require.NoError(t, err)
+
+	resources, err := dump.Resources()
+	require.NoError(t, err)
+
+	// Typed lists, whose items have no kind
+	require.Len(t, resources.NodeList.Items, 1)
+	assert.Equal(t, "test-master", resources.NodeList.Items[0].Name)
+	require.Len(t, resources.QuotaList.Items, 1)
+	assert.Equal(t, "test-quota1", resources.QuotaList.Items[0].Name)
+
+	// JSON file
+	require.Len(t, resources.PersistentVolumeList.Items, 1)
+	assert.Equal(t, "testpv", resources.PersistentVolumeList.Items[0].Name)
+
+	// Several YAML documents in one file
+	assert.Len(t, resources.RBACResources.UsersList.Items, 2)
+	assert.Len(t, resources.RBACResources.GroupList.Items, 2)
+	assert.Len(t, resources.RBACResources.ClusterRolesList.Items, 1)
+	assert.Len(t, resources.RBACResources.ClusterRolesBindingsList.Items, 1)
+	assert.Len(t, resources.RBACResources.SecurityContextConstraintsList.Items, 1)
+
+	require.Len(t, resources.NamespaceList, 1)
+	namespace := resources.NamespaceList[0]
+	assert.Equal(t, "namespacetest1", namespace.NamespaceName)
+
+	// Pods found both in a list and in their own file are kept once,
+	// those of a file which also holds something else than API objects are left out
+	require.Len(t, namespace.PodList.Items, 2)
+	assert.Equal(t, "test-pod1", namespace.PodList.Items[0].Name)
+	assert.Equal(t, "test-pod2", namespace.PodList.Items[1].Name)
+
+	// apps/v1 deployments are decoded into the version used by reports
+	require.Len(t, namespace.DeploymentList.Items, 1)
+	assert.Equal(t, "testDeployment", namespace.DeploymentList.Items[0].Name)
+
+	assert.Len(t, namespace.DaemonSetList.Items, 1)
+	assert.Len(t, namespace.ResourceQuotaList.Items, 1)
+	assert.Len(t, namespace.RolesList.Items, 1)
+	assert.Len(t, namespace.RouteList.Items, 1)
+	assert.Len(t, namespace.PVCList.Items, 1)
+}
+
+func TestLoadDumpNamespaceFromObjects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-dump")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: node-config-compute
+  namespace: openshift-node
+data:
+  node-config.yaml: ""
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "configmap.yaml"), []byte(content), 0644))
+
+	dump, err := api.LoadDump(dir)
+	require.NoError(t, err)
+
+	configMaps, err := dump.ConfigMaps("openshift-node")
+	require.NoError(t, err)
+	require.Len(t, configMaps.Items, 1)
+	assert.Equal(t, "node-config-compute", configMaps.Items[0].Name)
+
+	configMaps, err = dump.ConfigMaps("default")
+	require.NoError(t, err)
+	assert.Empty(t, configMaps.Items)
+
+	// Namespace has no object of its own but holds the config map
+	resources, err := dump.Resources()
+	require.NoError(t, err)
+	require.Len(t, resources.NamespaceList, 1)
+	assert.Equal(t, "openshift-node", resources.NamespaceList[0].NamespaceName)
+}
+
+func TestLoadDumpSkippedFiles(t *testing.T) {
+	_, err := api.LoadDump("testdata/missing-dump")
+	assert.Error(t, err)
+
+	dir, err := ioutil.TempDir("", "cpma-dump")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Files which can't be decoded are skipped
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("kind: [}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "array.json"), []byte(`[{"kind": "Node"}]`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "scalar.yaml"), []byte("text"), 0644))
+	dump, err := api.LoadDump(dir)
+	require.NoError(t, err)
+	nodes, err := dump.Nodes()
+	require.NoError(t, err)
+	assert.Empty(t, nodes.Items)
+}
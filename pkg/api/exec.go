@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// execProtocol is the WebSocket subprotocol of the exec subresource, every message is prefixed by its stream and
+// the outcome of the command is sent as a Status on the error stream
+const execProtocol = "v4.channel.k8s.io"
+
+// Streams of execProtocol
+const (
+	stdoutStream = 1
+	stderrStream = 2
+	errorStream  = 3
+)
+
+// restConfig is the configuration K8sClient was created from, exec requests are authenticated using it
+var restConfig *rest.Config
+
+// ExecInPod runs command in container of pod, its output is copied to stdout and stderr. An error is returned when
+// the command fails.
+var ExecInPod = func(namespace, pod, container string, command []string, stdout, stderr io.Writer) error {
+	if K8sClient == nil || restConfig == nil {
+		return errors.New("Kubernetes API client isn't initialized")
+	}
+
+	execURL := K8sClient.CoreV1().RESTClient().Get().
+		Namespace(namespace).
+		Resource("pods").
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec).
+		URL()
+
+	conn, err := dialExec(execURL.String())
+	if err != nil {
+		return errors.Wrapf(err, "Cannot exec in pod %s/%s", namespace, pod)
+	}
+	defer conn.Close()
+
+	var status []byte
+	for {
+		var message []byte
+		if err := websocket.Message.Receive(conn, &message); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return errors.Wrapf(err, "Cannot read output of pod %s/%s", namespace, pod)
+		}
+		// Each stream is opened by an empty message
+		if len(message) < 2 {
+			continue
+		}
+
+		switch message[0] {
+		case stdoutStream:
+			stdout.Write(message[1:])
+		case stderrStream:
+			stderr.Write(message[1:])
+		case errorStream:
+			status = append(status, message[1:]...)
+		}
+	}
+
+	return execStatusError(status)
+}
+
+// dialExec opens a WebSocket connection to execURL, authenticated like requests of K8sClient
+func dialExec(execURL string) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(strings.Replace(execURL, "http", "ws", 1), restConfig.Host)
+	if err != nil {
+		return nil, err
+	}
+	config.Protocol = []string{execProtocol}
+
+	if config.TlsConfig, err = rest.TLSConfigFor(restConfig); err != nil {
+		return nil, err
+	}
+
+	// Headers set by the wrappers, such as Authorization, are captured rather than sent
+	headers := headerRecorder{}
+	rt, err := rest.HTTPWrappersForConfig(restConfig, headers)
+	if err != nil {
+		return nil, err
+	}
+	req, _ := http.NewRequest(http.MethodGet, execURL, nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		return nil, err
+	}
+	for key, values := range headers {
+		config.Header[key] = values
+	}
+
+	return websocket.DialConfig(config)
+}
+
+// headerRecorder records headers of requests it is given
+type headerRecorder http.Header
+
+func (h headerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	for key, values := range req.Header {
+		h[key] = values
+	}
+
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+// execStatusError returns the error of a command from the Status sent on the error stream, nil on success
+func execStatusError(raw []byte) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	status := metav1.Status{}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return errors.Wrap(err, "Cannot decode exec status")
+	}
+	if status.Status == metav1.StatusSuccess {
+		return nil
+	}
+
+	if status.Details != nil {
+		for _, cause := range status.Details.Causes {
+			if cause.Type == "ExitCode" {
+				return errors.Errorf("command terminated with exit code %s", cause.Message)
+			}
+		}
+	}
+
+	return errors.New(status.Message)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// execRequest is an exec request received by the test API server
+type execRequest struct {
+	authorization string
+	container     string
+	command       []string
+}
+
+// serveExec answers exec requests using the v4 channel protocol, writing stdout and stderr then status
+func serveExec(requests chan<- execRequest, stdout, stderr string, status metav1.Status) http.Handler {
+	return websocket.Server{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			config.Protocol = []string{execProtocol}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			req := conn.Request()
+			requests <- execRequest{
+				authorization: req.Header.Get("Authorization"),
+				container:     req.URL.Query().Get("container"),
+				command:       req.URL.Query()["command"],
+			}
+
+			// Streams are opened by an empty message
+			for _, stream := range []byte{stdoutStream, stderrStream, errorStream} {
+				websocket.Message.Send(conn, []byte{stream})
+			}
+			websocket.Message.Send(conn, append([]byte{stdoutStream}, stdout...))
+			websocket.Message.Send(conn, append([]byte{stderrStream}, stderr...))
+			raw, _ := json.Marshal(status)
+			websocket.Message.Send(conn, append([]byte{errorStream}, raw...))
+			conn.Close()
+		},
+	}
+}
+
+func TestExecInPod(t *testing.T) {
+	savedClient, savedConfig := K8sClient, restConfig
+	defer func() { K8sClient, restConfig = savedClient, savedConfig }()
+
+	testCases := []struct {
+		name        string
+		status      metav1.Status
+		expectedErr string
+	}{
+		{
+			name:   "command succeeding",
+			status: metav1.Status{Status: metav1.StatusSuccess},
+		},
+		{
+			name: "command failing",
+			status: metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: "command terminated with non-zero exit code",
+				Details: &metav1.StatusDetails{Causes: []metav1.StatusCause{{Type: "ExitCode", Message: "2"}}},
+			},
+			expectedErr: "command terminated with exit code 2",
+		},
+		{
+			name:        "exec failing",
+			status:      metav1.Status{Status: metav1.StatusFailure, Message: "container debug not found"},
+			expectedErr: "container debug not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := make(chan execRequest, 1)
+			mux := http.NewServeMux()
+			mux.Handle("/api/v1/namespaces/default/pods/debug/exec", serveExec(requests, "out\x00put", "warning", tc.status))
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			restConfig = &rest.Config{Host: server.URL, BearerToken: "token"}
+			K8sClient = kubernetes.NewForConfigOrDie(restConfig)
+
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			err := ExecInPod("default", "debug", "container", []string{"cat", "/etc/hosts"}, stdout, stderr)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, "out\x00put", stdout.String())
+			assert.Equal(t, "warning", stderr.String())
+			assert.Equal(t, execRequest{
+				authorization: "Bearer token",
+				container:     "container",
+				command:       []string{"cat", "/etc/hosts"},
+			}, <-requests)
+		})
+	}
+}
+
+func TestExecInPodWithoutClient(t *testing.T) {
+	savedClient, savedConfig := K8sClient, restConfig
+	defer func() { K8sClient, restConfig = savedClient, savedConfig }()
+	K8sClient, restConfig = nil, nil
+
+	err := ExecInPod("default", "debug", "container", []string{"true"}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.EqualError(t, err, "Kubernetes API client isn't initialized")
+}
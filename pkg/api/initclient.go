@@ -82,6 +82,7 @@ func CreateK8sClient(contextCluster string) error {
 		}
 
 		K8sClient = NewK8SOrDie(config)
+		restConfig = config
 		logrus.Debugf("Kubernetes API client initialized for %s", contextCluster)
 	}
 	return nil
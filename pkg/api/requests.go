@@ -6,6 +6,7 @@ import (
 	o7troutev1 "github.com/openshift/api/route/v1"
 	o7tsecurityv1 "github.com/openshift/api/security/v1"
 	o7tuserv1 "github.com/openshift/api/user/v1"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/api/apps/v1beta1"
@@ -77,12 +78,13 @@ func ListPVs(client *kubernetes.Clientset, ch chan<- *corev1.PersistentVolumeLis
 }
 
 // ListNodes list all nodes, wrapper around client-go
-func ListNodes(client *kubernetes.Clientset, ch chan<- *corev1.NodeList) {
+func ListNodes(client *kubernetes.Clientset) (*corev1.NodeList, error) {
 	nodes, err := client.CoreV1().Nodes().List(listOptions)
 	if err != nil {
-		logrus.Fatal(err)
+		return nil, errors.Wrap(err, "Unable to list nodes")
 	}
-	ch <- nodes
+
+	return nodes, nil
 }
 
 // ListQuotas list all cluster quotas classes, wrapper around client-go
@@ -201,3 +203,14 @@ func ListPVCs(client *kubernetes.Clientset, namespace string, ch chan<- *corev1.
 	}
 	ch <- pvcs
 }
+
+// ListConfigMaps list all config maps in namespace, wrapper around client-go. Errors are returned rather than fatal,
+// so that only the transform listing them fails.
+func ListConfigMaps(client *kubernetes.Clientset, namespace string) (*corev1.ConfigMapList, error) {
+	configMaps, err := client.CoreV1().ConfigMaps(namespace).List(listOptions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to list config maps in namespace %s", namespace)
+	}
+
+	return configMaps, nil
+}
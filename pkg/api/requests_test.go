@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestListConfigMaps(t *testing.T) {
+	testCases := []struct {
+		name        string
+		status      int
+		body        string
+		expectedErr string
+		expectedLen int
+	}{
+		{
+			name:        "list config maps",
+			status:      http.StatusOK,
+			body:        `{"kind":"ConfigMapList","apiVersion":"v1","items":[{"metadata":{"name":"node-config-master","namespace":"openshift-node"}}]}`,
+			expectedLen: 1,
+		},
+		{
+			name:        "forbidden",
+			status:      http.StatusForbidden,
+			body:        `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403,"message":"configmaps is forbidden"}`,
+			expectedErr: "Unable to list config maps in namespace openshift-node: configmaps is forbidden",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v1/namespaces/openshift-node/configmaps", r.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+			configMaps, err := ListConfigMaps(client, "openshift-node")
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, configMaps.Items, tc.expectedLen)
+		})
+	}
+}
+
+func TestListNodes(t *testing.T) {
+	testCases := []struct {
+		name        string
+		status      int
+		body        string
+		expectedErr string
+		expectedLen int
+	}{
+		{
+			name:        "list nodes",
+			status:      http.StatusOK,
+			body:        `{"kind":"NodeList","apiVersion":"v1","items":[{"metadata":{"name":"master-0"}},{"metadata":{"name":"compute-0"}}]}`,
+			expectedLen: 2,
+		},
+		{
+			name:        "forbidden",
+			status:      http.StatusForbidden,
+			body:        `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403,"message":"nodes is forbidden"}`,
+			expectedErr: "Unable to list nodes: nodes is forbidden",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v1/nodes", r.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			client := kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+			nodes, err := ListNodes(client)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, nodes.Items, tc.expectedLen)
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FormatVersion is the version of the bundle layout written by this build
+	FormatVersion = 1
+	// ManifestName is the name of the bundle entry describing its content
+	ManifestName = "manifest.json"
+	// SourceFilesDir holds source files fetched from the OCP3 cluster, under their original path
+	SourceFilesDir = "files"
+	// ResourcesDir holds API resources lists, in the cluster dump layout
+	ResourcesDir = "resources"
+)
+
+// Manifest describes a bundle, it's the first entry of the archive
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	CPMAVersion   string    `json:"cpmaVersion"`
+	Created       time.Time `json:"created"`
+	// Config holds configuration values the bundle was collected with, such as Hostname or MasterConfigFile
+	Config map[string]string `json:"config"`
+	Files  []File            `json:"files"`
+}
+
+// File is a bundle entry along with its checksum
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Bundle holds source files and API resources of an OCP3 cluster, it's safe for concurrent use
+type Bundle struct {
+	Manifest
+	mutex sync.Mutex
+	files map[string][]byte
+}
+
+// New creates an empty bundle
+func New(cpmaVersion string, config map[string]string) *Bundle {
+	return &Bundle{
+		Manifest: Manifest{
+			FormatVersion: FormatVersion,
+			CPMAVersion:   cpmaVersion,
+			Created:       time.Now().UTC(),
+			Config:        config,
+		},
+		files: make(map[string][]byte),
+	}
+}
+
+// AddSourceFile adds a file fetched from the OCP3 cluster, src is its path on the cluster
+func (b *Bundle) AddSourceFile(src string, content []byte) {
+	b.add(path.Join(SourceFilesDir, filepath.ToSlash(src)), content)
+}
+
+// AddResources adds a file of API resources, name is relative to the resources directory
+func (b *Bundle) AddResources(name string, content []byte) {
+	b.add(path.Join(ResourcesDir, name), content)
+}
+
+func (b *Bundle) add(name string, content []byte) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.files[name] = content
+}
+
+// SourceFiles returns source files indexed by their path on the cluster
+func (b *Bundle) SourceFiles() map[string][]byte {
+	return b.filesUnder(SourceFilesDir)
+}
+
+// ResourceFiles returns API resources files indexed by their name in the resources directory
+func (b *Bundle) ResourceFiles() map[string][]byte {
+	return b.filesUnder(ResourcesDir)
+}
+
+func (b *Bundle) filesUnder(dir string) map[string][]byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	files := make(map[string][]byte)
+	for name, content := range b.files {
+		if strings.HasPrefix(name, dir+"/") {
+			files[strings.TrimPrefix(name, dir)] = content
+		}
+	}
+
+	return files
+}
+
+// Write writes the bundle as a gzipped tarball, manifest first
+func (b *Bundle) Write(w io.Writer) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	names := make([]string, 0, len(b.files))
+	for name := range b.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.Files = make([]File, 0, len(names))
+	for _, name := range names {
+		b.Files = append(b.Files, File{
+			Name:   name,
+			Size:   int64(len(b.files[name])),
+			SHA256: checksum(b.files[name]),
+		})
+	}
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if err := writeEntry(tarWriter, ManifestName, manifest, b.Created); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := writeEntry(tarWriter, name, b.files[name], b.Created); err != nil {
+			return err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+
+	return gzipWriter.Close()
+}
+
+// WriteFile writes the bundle to a file
+func (b *Bundle) WriteFile(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0750); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := b.Write(f); err != nil {
+		return errors.Wrapf(err, "Failed to write bundle %s", file)
+	}
+
+	return f.Close()
+}
+
+func writeEntry(tarWriter *tar.Writer, name string, content []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0640,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := tarWriter.Write(content)
+	return err
+}
+
+// Read reads a bundle, its format version and the checksum of every entry are verified
+func Read(r io.Reader) (*Bundle, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "Bundle is not a gzipped tarball")
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+
+	b := &Bundle{files: make(map[string][]byte)}
+	manifestFound := false
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read bundle")
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read bundle entry %s", header.Name)
+		}
+
+		if header.Name == ManifestName {
+			if err := json.Unmarshal(content, &b.Manifest); err != nil {
+				return nil, errors.Wrap(err, "Failed to decode bundle manifest")
+			}
+			manifestFound = true
+			continue
+		}
+		b.files[header.Name] = content
+	}
+
+	if !manifestFound {
+		return nil, errors.Errorf("Bundle has no %s", ManifestName)
+	}
+
+	if err := b.verify(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// ReadFile reads a bundle from a file
+func ReadFile(file string) (*Bundle, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+func (b *Bundle) verify() error {
+	if b.FormatVersion < 1 || b.FormatVersion > FormatVersion {
+		return errors.Errorf("Bundle format version %d is not supported, this build supports up to version %d",
+			b.FormatVersion, FormatVersion)
+	}
+
+	listed := make(map[string]bool)
+	for _, file := range b.Files {
+		content, ok := b.files[file.Name]
+		if !ok {
+			return errors.Errorf("Bundle entry %s is missing", file.Name)
+		}
+		if sum := checksum(content); sum != file.SHA256 {
+			return errors.Errorf("Bundle entry %s is corrupted, sha256 is %s instead of %s", file.Name, sum, file.SHA256)
+		}
+		listed[file.Name] = true
+	}
+
+	for name := range b.files {
+		if !listed[name] {
+			return errors.Errorf("Bundle entry %s is not listed in %s", name, ManifestName)
+		}
+	}
+
+	return nil
+}
+
+// Unpack writes source files under sourceDir, keeping their path on the cluster,
+// and API resources under resourcesDir
+func (b *Bundle) Unpack(sourceDir, resourcesDir string) error {
+	if err := unpackFiles(sourceDir, b.SourceFiles()); err != nil {
+		return err
+	}
+
+	return unpackFiles(resourcesDir, b.ResourceFiles())
+}
+
+func unpackFiles(dir string, files map[string][]byte) error {
+	for name, content := range files {
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		// Entries must not escape the destination directory
+		if rel, err := filepath.Rel(dir, dst); err != nil || strings.HasPrefix(rel, "..") {
+			return errors.Errorf("Bundle entry %s is outside of %s", name, dir)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(dst, content, 0640); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
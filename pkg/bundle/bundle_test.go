@@ -0,0 +1,160 @@
+package bundle_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/bundle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBundle() *bundle.Bundle {
+	b := bundle.New("v1.0.0", map[string]string{"Hostname": "master.example.com"})
+	b.AddSourceFile("/etc/origin/master/master-config.yaml", []byte("kind: MasterConfig\n"))
+	b.AddResources("nodes.yaml", []byte("kind: NodeList\n"))
+	return b
+}
+
+// writeArchive writes a bundle tarball from raw entries, manifest included
+func writeArchive(t *testing.T, manifest bundle.Manifest, entries map[string][]byte) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	content, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	entries[bundle.ManifestName] = content
+
+	for name, content := range entries {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0640, Size: int64(len(content))}))
+		_, err := tarWriter.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+
+	return buf
+}
+
+func TestRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, newTestBundle().Write(buf))
+
+	b, err := bundle.Read(buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, bundle.FormatVersion, b.FormatVersion)
+	assert.Equal(t, "v1.0.0", b.CPMAVersion)
+	assert.Equal(t, "master.example.com", b.Config["Hostname"])
+	assert.Len(t, b.Files, 2)
+	assert.Equal(t, map[string][]byte{"/etc/origin/master/master-config.yaml": []byte("kind: MasterConfig\n")}, b.SourceFiles())
+	assert.Equal(t, map[string][]byte{"/nodes.yaml": []byte("kind: NodeList\n")}, b.ResourceFiles())
+}
+
+func TestReadErrors(t *testing.T) {
+	content := []byte("kind: NodeList\n")
+	validFiles := func() []bundle.File {
+		buf := &bytes.Buffer{}
+		b := bundle.New("v1.0.0", nil)
+		b.AddResources("nodes.yaml", content)
+		require.NoError(t, b.Write(buf))
+		return b.Files
+	}
+
+	testCases := []struct {
+		name          string
+		manifest      bundle.Manifest
+		entries       map[string][]byte
+		expectedError string
+	}{
+		{
+			name:          "unsupported format version",
+			manifest:      bundle.Manifest{FormatVersion: bundle.FormatVersion + 1, Files: validFiles()},
+			entries:       map[string][]byte{"resources/nodes.yaml": content},
+			expectedError: "Bundle format version 2 is not supported, this build supports up to version 1",
+		},
+		{
+			name:          "corrupted entry",
+			manifest:      bundle.Manifest{FormatVersion: bundle.FormatVersion, Files: validFiles()},
+			entries:       map[string][]byte{"resources/nodes.yaml": []byte("kind: PodList\n")},
+			expectedError: "Bundle entry resources/nodes.yaml is corrupted",
+		},
+		{
+			name:          "missing entry",
+			manifest:      bundle.Manifest{FormatVersion: bundle.FormatVersion, Files: validFiles()},
+			entries:       map[string][]byte{},
+			expectedError: "Bundle entry resources/nodes.yaml is missing",
+		},
+		{
+			name:          "unlisted entry",
+			manifest:      bundle.Manifest{FormatVersion: bundle.FormatVersion, Files: validFiles()},
+			entries:       map[string][]byte{"resources/nodes.yaml": content, "resources/pods.yaml": content},
+			expectedError: "Bundle entry resources/pods.yaml is not listed in manifest.json",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := bundle.Read(writeArchive(t, tc.manifest, tc.entries))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedError)
+		})
+	}
+
+	_, err := bundle.Read(bytes.NewBufferString("not a bundle"))
+	assert.Error(t, err)
+}
+
+func TestUnpack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-bundle")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bundleFile := filepath.Join(dir, "bundle.tar.gz")
+	require.NoError(t, newTestBundle().WriteFile(bundleFile))
+
+	b, err := bundle.ReadFile(bundleFile)
+	require.NoError(t, err)
+
+	sourceDir := filepath.Join(dir, "master.example.com")
+	resourcesDir := filepath.Join(dir, "cluster-dump")
+	require.NoError(t, b.Unpack(sourceDir, resourcesDir))
+
+	content, err := ioutil.ReadFile(filepath.Join(sourceDir, "etc", "origin", "master", "master-config.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "kind: MasterConfig\n", string(content))
+
+	content, err = ioutil.ReadFile(filepath.Join(resourcesDir, "nodes.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "kind: NodeList\n", string(content))
+}
+
+func TestUnpackOutsideDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-bundle")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	name := "resources/../../escaped.yaml"
+	content := []byte("kind: List\n")
+	sum := sha256.Sum256(content)
+	manifest := bundle.Manifest{
+		FormatVersion: bundle.FormatVersion,
+		Files:         []bundle.File{{Name: name, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])}},
+	}
+
+	b, err := bundle.Read(writeArchive(t, manifest, map[string][]byte{name: content}))
+	require.NoError(t, err)
+
+	err = b.Unpack(filepath.Join(dir, "source"), filepath.Join(dir, "a", "b"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is outside of")
+}
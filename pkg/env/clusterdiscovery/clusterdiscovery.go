@@ -5,7 +5,6 @@ import (
 	"github.com/konveyor/cpma/pkg/api"
 	"github.com/pkg/errors"
 
-	k8sapicore "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -56,9 +55,10 @@ func SurveyClusters() string {
 }
 
 func queryNodes(client *kubernetes.Clientset) ([]string, error) {
-	chanNodes := make(chan *k8sapicore.NodeList)
-	go api.ListNodes(client, chanNodes)
-	nodeList := <-chanNodes
+	nodeList, err := api.ListNodes(client)
+	if err != nil {
+		return nil, err
+	}
 
 	nodes := make([]string, 0, len(nodeList.Items))
 	for _, node := range nodeList.Items {
@@ -1,6 +1,7 @@
 package env
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
@@ -40,41 +41,87 @@ func Config() *viper.Viper {
 
 // InitConfig initializes application's configuration
 func InitConfig() (err error) {
-	// Fill in environment variables that match
-	viperConfig.SetEnvPrefix("CPMA")
-	viperConfig.AutomaticEnv()
-
-	if err := setConfigLocation(); err != nil {
+	if err := initSources(); err != nil {
 		return err
 	}
 
+	nonInteractive := NonInteractive()
+
 	// If a config file is found, read it in.
 	readConfigErr := viperConfig.ReadInConfig()
+	if err := applyProfile(readConfigErr); err != nil {
+		return err
+	}
 	// If no config file and save config file is undetermined, ask to create or save it for future use
-	if readConfigErr != nil && viperConfig.GetString("SaveConfig") != "false" {
+	if readConfigErr != nil && !nonInteractive && viperConfig.GetString("SaveConfig") != "false" {
 		if err := surveySaveConfig(); err != nil {
 			return handleInterrupt(err)
 		}
 		logrus.Debug("Can't read config file, all values were prompted and new config was asked to be created, err: ", readConfigErr)
 	}
 
-	// Parse kubeconfig for creating api client later
-	if err := api.ParseKubeConfig(); err != nil {
-		return errors.Wrap(err, "kubeconfig parsing failed")
+	// Missing values can't be prompted, defaults are applied and the configuration is checked before anything else
+	if nonInteractive {
+		logrus.Debug("Running in non-interactive mode, missing values are not prompted")
+		if err := applyDefaults(); err != nil {
+			return err
+		}
+	}
+
+	// Parse kubeconfig for creating api client later, not needed when API resources are read from a dump
+	if viperConfig.GetString("ClusterDump") == "" {
+		if err := api.ParseKubeConfig(); err != nil {
+			return errors.Wrap(err, "kubeconfig parsing failed")
+		}
 	}
 
 	// Ask for all values that are missing in ENV, flags or config yaml
-	if err := surveyMissingValues(); err != nil {
-		return handleInterrupt(err)
+	if !nonInteractive {
+		if err := surveyMissingValues(); err != nil {
+			return handleInterrupt(err)
+		}
+	}
+
+	if err := createAPIClients(); err != nil {
+		return err
 	}
 
 	if viperConfig.GetString("SaveConfig") == "true" {
-		viperConfig.WriteConfig()
+		// Values of the profile would be saved as shared ones
+		if profile := viperConfig.GetString("Profile"); profile != "" {
+			logrus.Warnf("Configuration isn't saved when using a profile, edit profile %s in %s instead", profile, ConfigFile)
+		} else {
+			viperConfig.WriteConfig()
+		}
 	}
 
 	return nil
 }
 
+// ReadConfig reads application's configuration from environment and configuration file,
+// without asking for missing values nor connecting to the cluster
+func ReadConfig() error {
+	if err := initSources(); err != nil {
+		return err
+	}
+
+	readConfigErr := viperConfig.ReadInConfig()
+	if readConfigErr != nil {
+		logrus.Debug("Can't read config file, err: ", readConfigErr)
+	}
+
+	return applyProfile(readConfigErr)
+}
+
+// initSources sets where configuration values are read from
+func initSources() error {
+	// Fill in environment variables that match
+	viperConfig.SetEnvPrefix("CPMA")
+	viperConfig.AutomaticEnv()
+
+	return setConfigLocation()
+}
+
 // setConfigLocation sets location for CPMA configuration
 func setConfigLocation() (err error) {
 	var home string
@@ -126,10 +173,10 @@ func surveyMissingValues() error {
 	}
 
 	if viperConfig.GetString("WorkDir") == "" {
-		workDir := "."
+		workDir := DefaultWorkDir
 		prompt := &survey.Input{
 			Message: "Path to application data, skip to use current directory",
-			Default: ".",
+			Default: DefaultWorkDir,
 		}
 		if err := survey.AskOne(prompt, &workDir); err != nil {
 			return err
@@ -138,6 +185,16 @@ func surveyMissingValues() error {
 		viperConfig.Set("WorkDir", workDir)
 	}
 
+	return nil
+}
+
+// createAPIClients creates clients for the cluster set in ClusterName, unless API resources are read from a dump
+func createAPIClients() error {
+	if viperConfig.GetString("ClusterDump") != "" {
+		logrus.Debugf("API resources are read from %s, no api client is created", viperConfig.GetString("ClusterDump"))
+		return nil
+	}
+
 	srcClusterName := viperConfig.GetString("ClusterName")
 	// set current context to selected cluster for cclient-go
 	api.KubeConfig.CurrentContext = api.ClusterNames[srcClusterName]
@@ -157,7 +214,7 @@ func surveyConfigSource() error {
 	if viperConfig.GetString("ConfigSource") == "" {
 		prompt := &survey.Select{
 			Message: "What will be the source for OCP3 config files?",
-			Options: []string{"Remote host", "Local"},
+			Options: []string{"Remote host", "Local", "Archive", "Node exec (Kubernetes API)"},
 		}
 		if err := survey.AskOne(prompt, &configSource); err != nil {
 			return err
@@ -167,8 +224,31 @@ func surveyConfigSource() error {
 			viperConfig.Set("ConfigSource", "remote")
 		case "Local":
 			viperConfig.Set("ConfigSource", "local")
+		case "Archive":
+			viperConfig.Set("ConfigSource", "archive")
+		case "Node exec (Kubernetes API)":
+			viperConfig.Set("ConfigSource", "node-exec")
+		}
+
+	}
+
+	if viperConfig.GetString("ConfigSource") == "archive" {
+		return surveyArchive()
+	}
+	return nil
+}
+
+func surveyArchive() error {
+	archive := viperConfig.GetString("Archive")
+	if !viperConfig.InConfig("archive") && archive == "" {
+		prompt := &survey.Input{
+			Message: "Path to the archive holding OCP3 config files, such as a sosreport",
+		}
+		if err := survey.AskOne(prompt, &archive, survey.WithValidator(survey.Required)); err != nil {
+			return err
 		}
 
+		viperConfig.Set("Archive", archive)
 	}
 	return nil
 }
@@ -219,13 +299,16 @@ func surveyHostname() error {
 		clusterName := ""
 		var err error
 
-		// Ask for source of master hostname, prompt or find it using KUBECONFIG
-		prompt := &survey.Select{
-			Message: "Do wish to find source cluster using KUBECONFIG or prompt it?",
-			Options: []string{"KUBECONFIG", "prompt"},
-		}
-		if err := survey.AskOne(prompt, &discoverCluster); err != nil {
-			return err
+		// Ask for source of master hostname, prompt or find it using KUBECONFIG.
+		// There is no cluster to discover when API resources are read from a dump.
+		if viperConfig.GetString("ClusterDump") == "" {
+			prompt := &survey.Select{
+				Message: "Do wish to find source cluster using KUBECONFIG or prompt it?",
+				Options: []string{"KUBECONFIG", "prompt"},
+			}
+			if err := survey.AskOne(prompt, &discoverCluster); err != nil {
+				return err
+			}
 		}
 
 		if discoverCluster == "KUBECONFIG" {
@@ -261,8 +344,7 @@ func surveySSHConfigValues() error {
 	login := viperConfig.GetString("SSHLogin")
 	if !viperConfig.InConfig("sshlogin") && login == "" {
 		prompt := &survey.Input{
-			Message: "SSH login",
-			Default: "root",
+			Message: fmt.Sprintf("SSH login, skip to use SSH config or %s", DefaultSSHLogin),
 		}
 		if err := survey.AskOne(prompt, &login); err != nil {
 			return err
@@ -274,8 +356,7 @@ func surveySSHConfigValues() error {
 	port := ""
 	if !viperConfig.InConfig("sshport") && viperConfig.GetInt("SSHPort") == 0 {
 		prompt := &survey.Input{
-			Message: "SSH Port",
-			Default: "22",
+			Message: fmt.Sprintf("SSH Port, skip to use SSH config or %d", DefaultSSHPort),
 		}
 		if err := survey.AskOne(prompt, &port); err != nil {
 			return err
@@ -287,11 +368,16 @@ func surveySSHConfigValues() error {
 	}
 
 	privatekey := viperConfig.GetString("SSHPrivateKey")
-	if !viperConfig.InConfig("sshprivatekey") && privatekey == "" {
+	if !viperConfig.InConfig("sshprivatekey") && !sshKeyProvided() {
 		prompt := &survey.Input{
 			Message: "Path to private SSH key",
 		}
-		if err := survey.AskOne(prompt, &privatekey, survey.WithValidator(survey.Required)); err != nil {
+		opts := []survey.AskOpt{survey.WithValidator(survey.Required)}
+		if sshAuthProvided() {
+			prompt.Message = "Path to private SSH key, skip to use ssh-agent or SSH password"
+			opts = nil
+		}
+		if err := survey.AskOne(prompt, &privatekey, opts...); err != nil {
 			return err
 		}
 
@@ -306,7 +392,7 @@ func surveyConfigPaths() error {
 	if !viperConfig.InConfig("crioconfigfile") && config == "" {
 		prompt := &survey.Input{
 			Message: "Path to crio config file",
-			Default: "/etc/crio/crio.conf",
+			Default: DefaultCrioConfigFile,
 		}
 		if err := survey.AskOne(prompt, &config); err != nil {
 			return err
@@ -318,7 +404,7 @@ func surveyConfigPaths() error {
 	if !viperConfig.InConfig("etcdconfigfile") && config == "" {
 		prompt := &survey.Input{
 			Message: "Path to etcd config file",
-			Default: "/etc/etcd/etcd.conf",
+			Default: DefaultETCDConfigFile,
 		}
 		if err := survey.AskOne(prompt, &config); err != nil {
 			return err
@@ -330,7 +416,7 @@ func surveyConfigPaths() error {
 	if !viperConfig.InConfig("masterconfigfile") && config == "" {
 		prompt := &survey.Input{
 			Message: "Path to master config file",
-			Default: "/etc/origin/master/master-config.yaml",
+			Default: DefaultMasterConfigFile,
 		}
 		if err := survey.AskOne(prompt, &config); err != nil {
 			return err
@@ -342,7 +428,7 @@ func surveyConfigPaths() error {
 	if !viperConfig.InConfig("nodeconfigfile") && config == "" {
 		prompt := &survey.Input{
 			Message: "Path to node config file",
-			Default: "/etc/origin/node/node-config.yaml",
+			Default: DefaultNodeConfigFile,
 		}
 		if err := survey.AskOne(prompt, &config); err != nil {
 			return err
@@ -354,7 +440,7 @@ func surveyConfigPaths() error {
 	if !viperConfig.InConfig("registriesconfigfile") && config == "" {
 		prompt := &survey.Input{
 			Message: "Path to registries config file",
-			Default: "/etc/containers/registries.conf",
+			Default: DefaultRegistriesConfigFile,
 		}
 		if err := survey.AskOne(prompt, &config); err != nil {
 			return err
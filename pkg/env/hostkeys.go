@@ -0,0 +1,69 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fingerprintPrefix starts SHA256 fingerprints, as shown by ssh-keygen -l
+const fingerprintPrefix = "SHA256:"
+
+// HostKeyFingerprints returns pinned host key fingerprints from HostKeyFingerprints, by hostname.
+// They are given as host=SHA256:fingerprint strings, or as a map of hostnames to fingerprints in the configuration file.
+func HostKeyFingerprints() (map[string]string, error) {
+	var specs []string
+	switch value := viperConfig.Get("HostKeyFingerprints").(type) {
+	case nil:
+		return nil, nil
+	case string:
+		// Environment variable, fingerprints are separated by spaces or commas
+		specs = strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' })
+	case []string:
+		specs = value
+	case []interface{}:
+		for _, v := range value {
+			specs = append(specs, fmt.Sprint(v))
+		}
+	case map[string]interface{}:
+		for host, fingerprint := range value {
+			specs = append(specs, fmt.Sprintf("%s=%v", host, fingerprint))
+		}
+	case map[interface{}]interface{}:
+		for host, fingerprint := range value {
+			specs = append(specs, fmt.Sprintf("%v=%v", host, fingerprint))
+		}
+	default:
+		return nil, errors.Errorf("HostKeyFingerprints must be a list or a map, got %T", value)
+	}
+
+	fingerprints := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		host, fingerprint, err := ParseHostKeyFingerprint(spec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid HostKeyFingerprints")
+		}
+		if pinned, ok := fingerprints[host]; ok && pinned != fingerprint {
+			return nil, errors.Errorf("Invalid HostKeyFingerprints: %s is pinned to both %s and %s", host, pinned, fingerprint)
+		}
+		fingerprints[host] = fingerprint
+	}
+
+	return fingerprints, nil
+}
+
+// ParseHostKeyFingerprint parses a host=SHA256:fingerprint string
+func ParseHostKeyFingerprint(spec string) (string, string, error) {
+	i := strings.Index(spec, "=")
+	if i <= 0 {
+		return "", "", errors.Errorf("host key fingerprint %q must be given as host=%sfingerprint", spec, fingerprintPrefix)
+	}
+
+	host, fingerprint := spec[:i], spec[i+1:]
+	if !strings.HasPrefix(fingerprint, fingerprintPrefix) || len(fingerprint) == len(fingerprintPrefix) {
+		return "", "", errors.Errorf("host key fingerprint of %s must be a SHA256 fingerprint as shown by ssh-keygen -l, got %q", host, fingerprint)
+	}
+
+	return host, fingerprint, nil
+}
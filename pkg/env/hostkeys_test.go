@@ -0,0 +1,70 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostKeyFingerprints(t *testing.T) {
+	savedConfig := viperConfig
+	defer func() { viperConfig = savedConfig }()
+
+	master := "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8"
+	bastion := "SHA256:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU"
+
+	testCases := []struct {
+		name                 string
+		value                interface{}
+		expectedFingerprints map[string]string
+		expectedErr          string
+	}{
+		{
+			name: "no fingerprints",
+		},
+		{
+			name:                 "flags",
+			value:                []string{"master0.example.com=" + master, "bastion.example.com=" + bastion},
+			expectedFingerprints: map[string]string{"master0.example.com": master, "bastion.example.com": bastion},
+		},
+		{
+			name:                 "environment variable",
+			value:                "master0.example.com=" + master + ",bastion.example.com=" + bastion,
+			expectedFingerprints: map[string]string{"master0.example.com": master, "bastion.example.com": bastion},
+		},
+		{
+			name:                 "configuration file map",
+			value:                map[string]interface{}{"master0.example.com": master},
+			expectedFingerprints: map[string]string{"master0.example.com": master},
+		},
+		{
+			name:        "no hostname",
+			value:       []string{master},
+			expectedErr: `Invalid HostKeyFingerprints: host key fingerprint "` + master + `" must be given as host=SHA256:fingerprint`,
+		},
+		{
+			name:        "host pinned twice",
+			value:       []string{"master0.example.com=" + master, "master0.example.com=" + bastion},
+			expectedErr: "Invalid HostKeyFingerprints: master0.example.com is pinned to both " + master + " and " + bastion,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viperConfig = viper.New()
+			if tc.value != nil {
+				viperConfig.Set("HostKeyFingerprints", tc.value)
+			}
+
+			fingerprints, err := HostKeyFingerprints()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedFingerprints, fingerprints)
+		})
+	}
+}
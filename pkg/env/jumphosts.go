@@ -0,0 +1,148 @@
+package env
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// JumpHost is an SSH host the master is reached through, empty values are set from SSH config or master values
+type JumpHost struct {
+	Host    string
+	Port    int
+	User    string
+	KeyFile string
+}
+
+// String returns the jump host as [user@]host[:port]
+func (j JumpHost) String() string {
+	s := j.Host
+	if j.User != "" {
+		s = j.User + "@" + s
+	}
+	if j.Port != 0 {
+		s = fmt.Sprintf("%s:%d", s, j.Port)
+	}
+
+	return s
+}
+
+// JumpHosts returns jump hosts from JumpHosts, in the order they are connected to.
+// They are given as [user@]host[:port][=keyfile] strings, or as maps of host, user, port and keyfile in the configuration file.
+func JumpHosts() ([]JumpHost, error) {
+	var values []interface{}
+	switch value := viperConfig.Get("JumpHosts").(type) {
+	case nil:
+		return nil, nil
+	case string:
+		// Environment variable, jump hosts are separated by spaces or commas
+		for _, spec := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' }) {
+			values = append(values, spec)
+		}
+	case []string:
+		for _, spec := range value {
+			values = append(values, spec)
+		}
+	case []interface{}:
+		values = value
+	default:
+		return nil, errors.Errorf("JumpHosts must be a list, got %T", value)
+	}
+
+	jumpHosts := make([]JumpHost, 0, len(values))
+	for _, value := range values {
+		var jumpHost JumpHost
+		var err error
+		switch v := value.(type) {
+		case string:
+			jumpHost, err = ParseJumpHost(v)
+		case map[interface{}]interface{}:
+			m := make(map[string]interface{}, len(v))
+			for key, value := range v {
+				m[fmt.Sprint(key)] = value
+			}
+			jumpHost, err = jumpHostFromMap(m)
+		case map[string]interface{}:
+			jumpHost, err = jumpHostFromMap(v)
+		default:
+			err = errors.Errorf("jump host must be a string or a map, got %T", value)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid JumpHosts")
+		}
+
+		jumpHosts = append(jumpHosts, jumpHost)
+	}
+
+	return jumpHosts, nil
+}
+
+// ParseJumpHost parses a [user@]host[:port][=keyfile] jump host
+func ParseJumpHost(spec string) (JumpHost, error) {
+	jumpHost := JumpHost{}
+
+	if i := strings.Index(spec, "="); i >= 0 {
+		jumpHost.KeyFile = spec[i+1:]
+		spec = spec[:i]
+	}
+
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		jumpHost.User = spec[:i]
+		spec = spec[i+1:]
+	}
+
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		port, err := parsePort(spec[i+1:])
+		if err != nil {
+			return JumpHost{}, errors.Wrapf(err, "jump host %s", spec)
+		}
+		jumpHost.Port = port
+		spec = spec[:i]
+	}
+
+	if spec == "" {
+		return JumpHost{}, errors.New("jump host has no hostname")
+	}
+	jumpHost.Host = spec
+
+	return jumpHost, nil
+}
+
+func jumpHostFromMap(m map[string]interface{}) (JumpHost, error) {
+	jumpHost := JumpHost{}
+	for key, value := range m {
+		switch strings.ToLower(key) {
+		case "host":
+			jumpHost.Host = fmt.Sprint(value)
+		case "user":
+			jumpHost.User = fmt.Sprint(value)
+		case "port":
+			port, err := parsePort(fmt.Sprint(value))
+			if err != nil {
+				return JumpHost{}, errors.Wrapf(err, "jump host %v", m["host"])
+			}
+			jumpHost.Port = port
+		case "keyfile":
+			jumpHost.KeyFile = fmt.Sprint(value)
+		default:
+			return JumpHost{}, errors.Errorf("jump host %v has unknown key %s, accepted keys: host, user, port, keyfile", m["host"], key)
+		}
+	}
+
+	if jumpHost.Host == "" {
+		return JumpHost{}, errors.New("jump host has no hostname")
+	}
+
+	return jumpHost, nil
+}
+
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, errors.Errorf("port must be between 1 and 65535, got %q", value)
+	}
+
+	return port, nil
+}
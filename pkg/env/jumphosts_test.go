@@ -0,0 +1,97 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJumpHost(t *testing.T) {
+	testCases := []struct {
+		spec             string
+		expectedJumpHost JumpHost
+		expectedErr      string
+	}{
+		{spec: "bastion.example.com", expectedJumpHost: JumpHost{Host: "bastion.example.com"}},
+		{spec: "admin@bastion.example.com:2222", expectedJumpHost: JumpHost{Host: "bastion.example.com", User: "admin", Port: 2222}},
+		{
+			spec:             "admin@bastion.example.com=/home/user/.ssh/bastion",
+			expectedJumpHost: JumpHost{Host: "bastion.example.com", User: "admin", KeyFile: "/home/user/.ssh/bastion"},
+		},
+		{spec: "bastion.example.com:ssh", expectedErr: `jump host bastion.example.com:ssh: port must be between 1 and 65535, got "ssh"`},
+		{spec: "admin@:22", expectedErr: "jump host has no hostname"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			jumpHost, err := ParseJumpHost(tc.spec)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedJumpHost, jumpHost)
+		})
+	}
+}
+
+func TestJumpHosts(t *testing.T) {
+	savedConfig := viperConfig
+	defer func() { viperConfig = savedConfig }()
+
+	bastion := JumpHost{Host: "bastion.example.com", User: "admin"}
+	inner := JumpHost{Host: "inner.example.com", Port: 2222, KeyFile: "/home/user/.ssh/inner"}
+
+	testCases := []struct {
+		name              string
+		value             interface{}
+		expectedJumpHosts []JumpHost
+		expectedErr       string
+	}{
+		{
+			name: "no jump hosts",
+		},
+		{
+			name:              "flags",
+			value:             []string{"admin@bastion.example.com", "inner.example.com:2222=/home/user/.ssh/inner"},
+			expectedJumpHosts: []JumpHost{bastion, inner},
+		},
+		{
+			name:              "environment variable",
+			value:             "admin@bastion.example.com inner.example.com:2222=/home/user/.ssh/inner",
+			expectedJumpHosts: []JumpHost{bastion, inner},
+		},
+		{
+			name: "configuration file maps",
+			value: []interface{}{
+				map[interface{}]interface{}{"host": "bastion.example.com", "user": "admin"},
+				map[interface{}]interface{}{"host": "inner.example.com", "port": 2222, "keyfile": "/home/user/.ssh/inner"},
+			},
+			expectedJumpHosts: []JumpHost{bastion, inner},
+		},
+		{
+			name:        "unknown key",
+			value:       []interface{}{map[string]interface{}{"host": "bastion.example.com", "login": "admin"}},
+			expectedErr: "Invalid JumpHosts: jump host bastion.example.com has unknown key login, accepted keys: host, user, port, keyfile",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viperConfig = viper.New()
+			if tc.value != nil {
+				viperConfig.Set("JumpHosts", tc.value)
+			}
+
+			jumpHosts, err := JumpHosts()
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedJumpHosts, jumpHosts)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/konveyor/cpma/pkg/env/sshconfig"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Default values, prompted in interactive mode and applied in non-interactive mode
+const (
+	DefaultBecomeMethod         = "sudo"
+	DefaultBecomeUser           = "root"
+	DefaultCrioConfigFile       = "/etc/crio/crio.conf"
+	DefaultDebugImage           = "registry.access.redhat.com/rhel7/rhel-tools"
+	DefaultDebugNamespace       = "default"
+	DefaultETCDConfigFile       = "/etc/etcd/etcd.conf"
+	DefaultMasterConfigFile     = "/etc/origin/master/master-config.yaml"
+	DefaultNodeConfigFile       = "/etc/origin/node/node-config.yaml"
+	DefaultRegistriesConfigFile = "/etc/containers/registries.conf"
+	DefaultSFTPServer           = "/usr/libexec/openssh/sftp-server"
+	DefaultWorkDir              = "."
+)
+
+// SSH login and port used when neither settings nor SSH config set them
+const (
+	DefaultSSHLogin = "root"
+	DefaultSSHPort  = 22
+)
+
+// configSources are accepted values of ConfigSource
+var configSources = []string{"remote", "local", "archive", "node-exec"}
+
+// becomeMethods are accepted values of BecomeMethod
+var becomeMethods = []string{"none", "sudo", "su"}
+
+// commonSettings are used whatever the config source is
+var commonSettings = []string{
+	"SaveConfig",
+	"Manifests",
+	"Reporting",
+	"ConfigSource",
+	"CrioConfigFile",
+	"ETCDConfigFile",
+	"MasterConfigFile",
+	"NodeConfigFile",
+	"RegistriesConfigFile",
+	"Hostname",
+	"WorkDir",
+}
+
+// clusterSettings are needed to query the API of a live cluster, when no cluster dump is set
+var clusterSettings = []string{"ClusterName"}
+
+var archiveSettings = []string{"Archive"}
+
+// requiredSettings returns settings needed to run with the current config source
+func requiredSettings() []Setting {
+	keys := append([]string{}, commonSettings...)
+	if viperConfig.GetString("ClusterDump") == "" {
+		keys = append(keys, clusterSettings...)
+	}
+	if viperConfig.GetString("ConfigSource") == "archive" {
+		keys = append(keys, archiveSettings...)
+	}
+
+	settings := make([]Setting, 0, len(keys))
+	for _, key := range keys {
+		s, _ := LookupSetting(key)
+		settings = append(settings, s)
+	}
+
+	return settings
+}
+
+// checkRequired lists settings which are missing and have no default value, along with an unsupported config source
+func checkRequired(settings []Setting) []string {
+	var problems []string
+	for _, s := range settings {
+		if s.missing() && s.Default == nil {
+			problems = append(problems, fmt.Sprintf("%s is missing, set it using %s", s.Key, s.sources()))
+		}
+	}
+
+	// SSH login and port default to SSH config values then root and 22, the key file has no default
+	if viperConfig.GetString("ConfigSource") == "remote" && !sshAuthProvided() {
+		s, _ := LookupSetting("SSHPrivateKey")
+		problems = append(problems, fmt.Sprintf("%s is missing, set it using %s, or IdentityFile in SSH config, "+
+			"unless ssh-agent or CPMA_SSHPASSWORD is used", s.Key, s.sources()))
+	}
+
+	if configSource := viperConfig.GetString("ConfigSource"); configSource != "" && !contains(configSources, configSource) {
+		problems = append(problems, fmt.Sprintf("ConfigSource %q is not supported, accepted values: %s",
+			configSource, strings.Join(configSources, ", ")))
+	}
+
+	return problems
+}
+
+// sshKeyProvided tells if a private key to authenticate to the master is given, using SSHPrivateKey or SSH config
+func sshKeyProvided() bool {
+	if viperConfig.GetString("SSHPrivateKey") != "" {
+		return true
+	}
+
+	home := viperConfig.GetString("home")
+	config, err := sshconfig.ReadUser(home)
+	if err != nil {
+		logrus.Debug(err)
+		return false
+	}
+
+	return config.IdentityFile(viperConfig.GetString("Hostname"), home) != ""
+}
+
+// sshAuthProvided tells if the master can be authenticated to without prompting: using a private key,
+// ssh-agent or SSHPassword
+func sshAuthProvided() bool {
+	return sshKeyProvided() || os.Getenv("SSH_AUTH_SOCK") != "" || viperConfig.GetString("SSHPassword") != ""
+}
+
+// NonInteractive tells if values can't be prompted, either because it was asked to or because stdin isn't a terminal
+func NonInteractive() bool {
+	return viperConfig.GetBool("NonInteractive") || !terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// applyDefaults sets missing settings to their default value and checks the resulting configuration.
+// The error lists every missing required setting along with invalid ones.
+func applyDefaults() error {
+	settings := requiredSettings()
+	for _, s := range settings {
+		if s.missing() && s.Default != nil {
+			logrus.Debugf("%s is missing, using default value: %v", s.Key, s.Default)
+			viperConfig.Set(s.Key, s.Default)
+		}
+	}
+
+	if problems := checkRequired(settings); len(problems) > 0 {
+		return errors.Errorf("Configuration is incomplete, values can't be prompted in non-interactive mode:\n  - %s",
+			strings.Join(problems, "\n  - "))
+	}
+
+	if viperConfig.GetString("ConfigSource") == "remote" {
+		viperConfig.Set("FetchFromRemote", true)
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+package env
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	savedConfig := viperConfig
+	savedAuthSock, authSockSet := os.LookupEnv("SSH_AUTH_SOCK")
+	defer func() {
+		viperConfig = savedConfig
+		if authSockSet {
+			os.Setenv("SSH_AUTH_SOCK", savedAuthSock)
+		} else {
+			os.Unsetenv("SSH_AUTH_SOCK")
+		}
+	}()
+
+	testCases := []struct {
+		name           string
+		values         map[string]interface{}
+		sshConfigKey   string
+		authSock       string
+		expectedValues map[string]interface{}
+		expectedErrors []string
+	}{
+		{
+			name:   "local with defaults",
+			values: map[string]interface{}{"ConfigSource": "local", "Hostname": "master0.example.com", "ClusterName": "master0", "Manifests": false},
+			expectedValues: map[string]interface{}{
+				"SaveConfig":           false,
+				"Manifests":            false,
+				"Reporting":            true,
+				"CrioConfigFile":       DefaultCrioConfigFile,
+				"ETCDConfigFile":       DefaultETCDConfigFile,
+				"MasterConfigFile":     DefaultMasterConfigFile,
+				"NodeConfigFile":       DefaultNodeConfigFile,
+				"RegistriesConfigFile": DefaultRegistriesConfigFile,
+				"WorkDir":              DefaultWorkDir,
+			},
+		},
+		{
+			name:   "remote with defaults",
+			values: map[string]interface{}{"ConfigSource": "remote", "Hostname": "master0.example.com", "ClusterName": "master0", "SSHPrivateKey": "/root/.ssh/id_rsa"},
+			// SSH login and port are read from SSH config when connecting
+			expectedValues: map[string]interface{}{
+				"SSHLogin":        nil,
+				"SSHPort":         nil,
+				"FetchFromRemote": true,
+			},
+		},
+		{
+			name:           "remote with SSH config key",
+			values:         map[string]interface{}{"ConfigSource": "remote", "Hostname": "master0.example.com", "ClusterName": "master0"},
+			sshConfigKey:   "/root/.ssh/master0",
+			expectedValues: map[string]interface{}{"SSHPrivateKey": nil},
+		},
+		{
+			name:     "remote with ssh-agent",
+			values:   map[string]interface{}{"ConfigSource": "remote", "Hostname": "master0.example.com", "ClusterName": "master0"},
+			authSock: "/tmp/ssh-agent.sock",
+		},
+		{
+			name:   "remote with SSH password",
+			values: map[string]interface{}{"ConfigSource": "remote", "Hostname": "master0.example.com", "ClusterName": "master0", "SSHPassword": "secret"},
+		},
+		{
+			name: "nothing set",
+			expectedErrors: []string{
+				"ConfigSource is missing, set it using --config-source, CPMA_CONFIGSOURCE, configsource key in configuration file",
+				"Hostname is missing, set it using --hostname, CPMA_HOSTNAME, hostname key in configuration file",
+				"ClusterName is missing, set it using --cluster-name, CPMA_CLUSTERNAME, clustername key in configuration file",
+			},
+		},
+		{
+			name:           "live cluster without cluster name",
+			values:         map[string]interface{}{"ConfigSource": "remote", "Hostname": "master0.example.com", "SSHPrivateKey": "/root/.ssh/id_rsa"},
+			expectedErrors: []string{"ClusterName is missing"},
+		},
+		{
+			name:   "cluster dump without cluster name",
+			values: map[string]interface{}{"ConfigSource": "local", "Hostname": "master0.example.com", "ClusterDump": "/tmp/cluster-dump"},
+		},
+		{
+			name:   "remote without SSH key",
+			values: map[string]interface{}{"ConfigSource": "remote", "SSHPort": 0},
+			expectedErrors: []string{
+				"Hostname is missing",
+				"SSHPrivateKey is missing, set it using --ssh-keyfile, CPMA_SSHPRIVATEKEY, sshprivatekey key in configuration file, or IdentityFile in SSH config, unless ssh-agent or CPMA_SSHPASSWORD is used",
+			},
+		},
+		{
+			name:           "archive without archive",
+			values:         map[string]interface{}{"ConfigSource": "archive", "Hostname": "master0.example.com"},
+			expectedErrors: []string{"Archive is missing, set it using --archive, CPMA_ARCHIVE, archive key in configuration file"},
+		},
+		{
+			name:           "unsupported config source",
+			values:         map[string]interface{}{"ConfigSource": "ftp", "Hostname": "master0.example.com"},
+			expectedErrors: []string{`ConfigSource "ftp" is not supported, accepted values: remote, local, archive`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viperConfig = viper.New()
+			home, err := ioutil.TempDir("", "cpma-home")
+			require.NoError(t, err)
+			defer os.RemoveAll(home)
+			if tc.sshConfigKey != "" {
+				require.NoError(t, os.Mkdir(filepath.Join(home, ".ssh"), 0700))
+				sshConfig := "Host " + tc.values["Hostname"].(string) + "\n    IdentityFile " + tc.sshConfigKey + "\n"
+				require.NoError(t, ioutil.WriteFile(filepath.Join(home, ".ssh", "config"), []byte(sshConfig), 0600))
+			}
+			viperConfig.Set("home", home)
+			os.Setenv("SSH_AUTH_SOCK", tc.authSock)
+			for key, value := range tc.values {
+				viperConfig.Set(key, value)
+			}
+
+			err = applyDefaults()
+			if len(tc.expectedErrors) > 0 {
+				require.Error(t, err)
+				for _, expectedError := range tc.expectedErrors {
+					assert.Contains(t, err.Error(), expectedError)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			for key, expectedValue := range tc.expectedValues {
+				assert.Equal(t, expectedValue, viperConfig.Get(key), key)
+			}
+		})
+	}
+}
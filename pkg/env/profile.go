@@ -0,0 +1,86 @@
+package env
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// profilesKey is the configuration file key holding named profiles
+const profilesKey = "profiles"
+
+// profileKeys holds keys set by the profile in use
+var profileKeys = make(map[string]bool)
+
+// applyProfile merges values of the profile set in Profile over shared values of the configuration file,
+// flags and environment variables still take precedence. readConfigErr is the error reading the configuration file.
+func applyProfile(readConfigErr error) error {
+	profileKeys = make(map[string]bool)
+
+	name := viperConfig.GetString("Profile")
+	if name == "" {
+		return nil
+	}
+
+	if readConfigErr != nil {
+		return errors.Wrapf(readConfigErr, "Profile %s can't be used, configuration file %s can't be read", name, ConfigFile)
+	}
+
+	profiles := viperConfig.GetStringMap(profilesKey)
+	profile, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		return errors.Errorf("Profile %s not found in %s, available profiles: %s", name, ConfigFile, strings.Join(profileNames(profiles), ", "))
+	}
+
+	values, ok := profile.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("Profile %s in %s must be a map of settings", name, ConfigFile)
+	}
+
+	// Shared values as read from the configuration file, without flags nor environment variables
+	fileConfig := viper.New()
+	fileConfig.SetConfigFile(viperConfig.ConfigFileUsed())
+	if err := fileConfig.ReadInConfig(); err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		s, ok := LookupSetting(key)
+		if !ok || s.Key == "Profile" {
+			return errors.Errorf("Profile %s in %s sets %s which is not a setting", name, ConfigFile, key)
+		}
+
+		// Values of a different type than the shared one wouldn't be merged
+		if shared := fileConfig.Get(s.Key); shared != nil && reflect.TypeOf(shared) != reflect.TypeOf(value) {
+			return errors.Errorf("Profile %s in %s sets %s as %T while its shared value is %T", name, ConfigFile, key, value, shared)
+		}
+
+		profileKeys[s.ConfigKey()] = true
+	}
+
+	if err := viperConfig.MergeConfigMap(values); err != nil {
+		return errors.Wrapf(err, "Failed to apply profile %s", name)
+	}
+	logrus.Debugf("Using profile %s from %s", name, ConfigFile)
+
+	return nil
+}
+
+func profileNames(profiles map[string]interface{}) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ProfileNames returns names of profiles defined in the configuration file
+func ProfileNames() []string {
+	return profileNames(viperConfig.GetStringMap(profilesKey))
+}
@@ -0,0 +1,119 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyProfile(t *testing.T) {
+	savedConfig, savedConfigFile := viperConfig, ConfigFile
+	defer func() {
+		viperConfig, ConfigFile = savedConfig, savedConfigFile
+		profileKeys = make(map[string]bool)
+	}()
+
+	testCases := []struct {
+		name           string
+		profile        string
+		overrides      map[string]interface{}
+		expectedValues map[string]interface{}
+		expectedSource map[string]string
+		expectedError  string
+	}{
+		{
+			name:    "no profile",
+			profile: "",
+			expectedValues: map[string]interface{}{
+				"SSHPort": 22,
+				"WorkDir": "data",
+			},
+		},
+		{
+			name:    "profile over shared values",
+			profile: "cluster2",
+			expectedValues: map[string]interface{}{
+				"ClusterName":  "cluster2",
+				"ConfigSource": "remote",
+				"Hostname":     "master0.cluster2.example.com",
+				"SSHLogin":     "root",
+				"SSHPort":      2222,
+				"WorkDir":      "data/cluster2",
+			},
+			expectedSource: map[string]string{
+				"sshport":      SourceProfile,
+				"sshlogin":     SourceFile,
+				"configsource": SourceFile,
+			},
+		},
+		{
+			name:      "values set explicitly take precedence",
+			profile:   "cluster1",
+			overrides: map[string]interface{}{"WorkDir": "elsewhere"},
+			expectedValues: map[string]interface{}{
+				"Hostname": "master0.cluster1.example.com",
+				"SSHPort":  22,
+				"WorkDir":  "elsewhere",
+			},
+		},
+		{
+			name:          "profile not found",
+			profile:       "cluster3",
+			expectedError: "Profile cluster3 not found in testdata/cpma-profiles.yml, available profiles: badtype, cluster1, cluster2, typo",
+		},
+		{
+			name:          "profile value of another type",
+			profile:       "badtype",
+			expectedError: "Profile badtype in testdata/cpma-profiles.yml sets sshport as string while its shared value is int",
+		},
+		{
+			name:          "unknown setting",
+			profile:       "typo",
+			expectedError: "Profile typo in testdata/cpma-profiles.yml sets hostnme which is not a setting",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viperConfig = viper.New()
+			ConfigFile = "testdata/cpma-profiles.yml"
+			viperConfig.SetConfigFile(ConfigFile)
+			viperConfig.Set("Profile", tc.profile)
+			for key, value := range tc.overrides {
+				viperConfig.Set(key, value)
+			}
+
+			err := applyProfile(viperConfig.ReadInConfig())
+			if tc.expectedError != "" {
+				require.Error(t, err)
+				assert.Equal(t, tc.expectedError, err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			for key, expectedValue := range tc.expectedValues {
+				assert.Equal(t, expectedValue, viperConfig.Get(key), key)
+			}
+			for key, expectedSource := range tc.expectedSource {
+				s, _ := LookupSetting(key)
+				assert.Equal(t, expectedSource, s.Source(nil), key)
+			}
+		})
+	}
+}
+
+func TestApplyProfileWithoutConfigFile(t *testing.T) {
+	savedConfig, savedConfigFile := viperConfig, ConfigFile
+	defer func() { viperConfig, ConfigFile = savedConfig, savedConfigFile }()
+
+	viperConfig = viper.New()
+	ConfigFile = "testdata/missing.yml"
+	viperConfig.SetConfigFile(ConfigFile)
+	viperConfig.Set("Profile", "cluster1")
+
+	err := applyProfile(viperConfig.ReadInConfig())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Profile cluster1 can't be used, configuration file testdata/missing.yml can't be read")
+}
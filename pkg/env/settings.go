@@ -0,0 +1,321 @@
+package env
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/konveyor/cpma/pkg/api"
+)
+
+// SettingType is the type of a setting value
+type SettingType string
+
+// Setting types
+const (
+	StringSetting SettingType = "string"
+	BoolSetting   SettingType = "bool"
+	IntSetting    SettingType = "int"
+	ListSetting   SettingType = "list"
+)
+
+// Value sources, from highest to lowest precedence
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceProfile = "profile"
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
+// maskedValue replaces secret values when shown
+const maskedValue = "********"
+
+// Setting describes a configuration value, given using a flag, an environment variable or the configuration file
+type Setting struct {
+	Key         string
+	Flag        string
+	Type        SettingType
+	Description string
+	// Default is applied in non-interactive mode and written in the configuration template, nil when there is none
+	Default interface{}
+	// Secret values are masked when shown
+	Secret bool
+}
+
+// Settings lists every configuration value, sorted by key
+var Settings = []Setting{
+	{Key: "Archive", Flag: "archive", Type: StringSetting, Description: "Path to a .tar, .tar.gz or .tar.xz archive holding OCP3 config files, used when config source is archive"},
+	{Key: "BecomeMethod", Flag: "become-method", Type: StringSetting, Description: "Privilege escalation method on the master: none, sudo or su", Default: DefaultBecomeMethod},
+	{Key: "BecomePassword", Type: StringSetting, Description: "Password asked by sudo or su, prompted when missing in interactive mode", Secret: true},
+	{Key: "BecomeUser", Flag: "become-user", Type: StringSetting, Description: "User files are read and commands run as on the master", Default: DefaultBecomeUser},
+	{Key: "ClusterDump", Flag: "cluster-dump", Type: StringSetting, Description: "Path to a directory of API resources dumped as YAML or JSON, used instead of a live cluster"},
+	{Key: "ClusterName", Flag: "cluster-name", Type: StringSetting, Description: "OCP3 cluster kubeconfig name"},
+	{Key: "ConfigSource", Flag: "config-source", Type: StringSetting, Description: "Source for OCP3 config files: remote, local, archive or node-exec"},
+	{Key: "CrioConfigFile", Flag: "crio-config", Type: StringSetting, Description: "Path to crio config file", Default: DefaultCrioConfigFile},
+	{Key: "Debug", Flag: "debug", Type: BoolSetting, Description: "Show debug output", Default: false},
+	{Key: "DebugImage", Flag: "debug-image", Type: StringSetting, Description: "Image of debug pods reading files on the node, used when config source is node-exec", Default: DefaultDebugImage},
+	{Key: "DebugNamespace", Flag: "debug-namespace", Type: StringSetting, Description: "Namespace of debug pods reading files on the node, used when config source is node-exec", Default: DefaultDebugNamespace},
+	{Key: "ETCDConfigFile", Flag: "etcd-config", Type: StringSetting, Description: "Path to etcd config file", Default: DefaultETCDConfigFile},
+	{Key: "HostKeyFingerprints", Flag: "host-key-fingerprint", Type: ListSetting, Description: "Pinned SSH host key fingerprints as host=SHA256:fingerprint, trusted without prompting when a host is first connected to"},
+	{Key: "Hostname", Flag: "hostname", Type: StringSetting, Description: "OCP3 cluster hostname"},
+	{Key: "InsecureHostKey", Flag: "allow-insecure-host", Type: BoolSetting, Description: "Allow insecure SSH host key", Default: false},
+	{Key: "JumpHosts", Flag: "jump-host", Type: ListSetting, Description: "SSH jump hosts to reach the master through, in connection order, as [user@]host[:port][=keyfile], used when config source is remote"},
+	{Key: "Manifests", Flag: "manifests", Type: BoolSetting, Description: "Generate manifests", Default: true},
+	{Key: "MasterConfigFile", Flag: "master-config", Type: StringSetting, Description: "Path to master config file", Default: DefaultMasterConfigFile},
+	{Key: "NodeConfigFile", Flag: "node-config", Type: StringSetting, Description: "Path to node config file", Default: DefaultNodeConfigFile},
+	{Key: "NonInteractive", Flag: "non-interactive", Type: BoolSetting, Description: "Never prompt for missing values", Default: false},
+	{Key: "Only", Flag: "only", Type: ListSetting, Description: "Run only listed components"},
+	{Key: "Parallelism", Flag: "parallelism", Type: IntSetting, Description: "Maximum number of transforms to run concurrently"},
+	{Key: "Profile", Flag: "profile", Type: StringSetting, Description: "Profile of the configuration file to use, its values override shared ones"},
+	{Key: "RegistriesConfigFile", Flag: "registries-config", Type: StringSetting, Description: "Path to registries config file", Default: DefaultRegistriesConfigFile},
+	{Key: "Reporting", Flag: "reporting", Type: BoolSetting, Description: "Generate reporting", Default: true},
+	{Key: "SaveConfig", Type: BoolSetting, Description: "Save configuration for future use", Default: false},
+	{Key: "SFTPServer", Flag: "sftp-server", Type: StringSetting, Description: "Path of sftp-server on the master, run using BecomeMethod when SSH login isn't the become user", Default: DefaultSFTPServer},
+	{Key: "Silent", Flag: "silent", Type: BoolSetting, Description: "Disable logging output to console", Default: false},
+	{Key: "Skip", Flag: "skip", Type: ListSetting, Description: "Skip listed components"},
+	{Key: "SSHKeepAlive", Flag: "ssh-keepalive", Type: IntSetting, Description: "Interval in seconds of SSH keepalive requests, 0 disables them"},
+	{Key: "SSHKeyPassphrase", Type: StringSetting, Description: "Passphrase of encrypted SSH private keys, prompted when missing in interactive mode", Secret: true},
+	{Key: "SSHLogin", Flag: "ssh-login", Type: StringSetting, Description: "OCP3 SSH login, used when config source is remote, read from SSH config or root when missing"},
+	{Key: "SSHMaxSessions", Flag: "ssh-max-sessions", Type: IntSetting, Description: "Maximum number of SSH sessions open at the same time on a host"},
+	{Key: "SSHPassword", Type: StringSetting, Description: "SSH password, used when key authentication fails, prompted when missing in interactive mode", Secret: true},
+	{Key: "SSHPort", Flag: "ssh-port", Type: IntSetting, Description: "OCP3 SSH port, used when config source is remote, read from SSH config or 22 when missing"},
+	{Key: "SSHPrivateKey", Flag: "ssh-keyfile", Type: StringSetting, Description: "OCP3 SSH private key path, used when config source is remote, read from SSH config when missing", Secret: true},
+	{Key: "WorkDir", Flag: "work-dir", Type: StringSetting, Description: "Application data working directory", Default: DefaultWorkDir},
+}
+
+// LookupSetting returns the setting of key
+func LookupSetting(key string) (Setting, bool) {
+	for _, s := range Settings {
+		if strings.EqualFold(s.Key, key) {
+			return s, true
+		}
+	}
+
+	return Setting{}, false
+}
+
+// EnvVar returns the environment variable setting s
+func (s Setting) EnvVar() string {
+	return "CPMA_" + strings.ToUpper(s.Key)
+}
+
+// ConfigKey returns the configuration file key setting s
+func (s Setting) ConfigKey() string {
+	return strings.ToLower(s.Key)
+}
+
+// sources describes how a setting can be given
+func (s Setting) sources() string {
+	sources := []string{}
+	if s.Flag != "" {
+		sources = append(sources, "--"+s.Flag)
+	}
+	sources = append(sources, s.EnvVar(), s.ConfigKey()+" key in configuration file")
+
+	return strings.Join(sources, ", ")
+}
+
+// missing tells if a setting has no value, a port set to 0 is missing
+func (s Setting) missing() bool {
+	value := viperConfig.GetString(s.Key)
+	return value == "" || (s.Key == "SSHPort" && viperConfig.GetInt(s.Key) == 0)
+}
+
+// Source returns where the value of s comes from, flagChanged tells if a command line flag was set
+func (s Setting) Source(flagChanged func(flag string) bool) string {
+	if s.Flag != "" && flagChanged != nil && flagChanged(s.Flag) {
+		return SourceFlag
+	}
+
+	if _, ok := os.LookupEnv(s.EnvVar()); ok {
+		return SourceEnv
+	}
+
+	if profileKeys[s.ConfigKey()] {
+		return SourceProfile
+	}
+
+	if viperConfig.InConfig(s.ConfigKey()) {
+		return SourceFile
+	}
+
+	return SourceDefault
+}
+
+// Value returns the value of s as shown to users, the default value when missing. Secret values are masked.
+func (s Setting) Value() string {
+	var value string
+	switch {
+	case s.Type == ListSetting:
+		value = strings.Join(viperConfig.GetStringSlice(s.Key), ",")
+	case s.missing() && s.Default != nil:
+		value = fmt.Sprint(s.Default)
+	default:
+		value = viperConfig.GetString(s.Key)
+	}
+
+	if s.Secret && value != "" {
+		return maskedValue
+	}
+
+	return value
+}
+
+// ShowConfig writes the merged configuration along with the source of every value
+func ShowConfig(w io.Writer, flagChanged func(flag string) bool) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range Settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.ConfigKey(), s.Value(), s.Source(flagChanged))
+	}
+
+	return tw.Flush()
+}
+
+// WriteConfigTemplate writes a commented configuration file, every setting is commented out with its default value
+func WriteConfigTemplate(w io.Writer) error {
+	fmt.Fprintln(w, "# CPMA configuration file")
+	fmt.Fprintln(w, "# Uncomment and set values, flags and CPMA_* environment variables take precedence over this file")
+	for _, s := range Settings {
+		line := s.ConfigKey() + ":"
+		switch {
+		case s.Type == ListSetting:
+			line += " []"
+		case s.Default != nil:
+			line += " " + fmt.Sprint(s.Default)
+		}
+
+		fmt.Fprintf(w, "\n# %s (%s)\n#%s\n", s.Description, s.Type, line)
+	}
+
+	fmt.Fprint(w, `
+# Named profiles, one per source cluster, selected using --profile, CPMA_PROFILE or profile key.
+# Values of the selected profile override shared values above.
+#profiles:
+#  cluster1:
+#    clustername: cluster1-example-com
+#    hostname: master0.cluster1.example.com
+#    workdir: data/cluster1
+#  cluster2:
+#    clustername: cluster2-example-com
+#    hostname: master0.cluster2.example.com
+#    sshprivatekey: /home/user/.ssh/cluster2
+#    workdir: data/cluster2
+`)
+
+	return nil
+}
+
+// ValidateConfig checks the merged configuration: value types, required values, paths used in local and archive
+// modes, port range and kubeconfig context. Every problem found is returned.
+func ValidateConfig() []string {
+	var problems []string
+
+	for _, s := range Settings {
+		value := viperConfig.GetString(s.Key)
+		if value == "" {
+			continue
+		}
+
+		switch s.Type {
+		case BoolSetting:
+			if _, err := strconv.ParseBool(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s must be true or false, got %q", s.Key, value))
+			}
+		case IntSetting:
+			if _, err := strconv.Atoi(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s must be an integer, got %q", s.Key, value))
+			}
+		}
+	}
+
+	problems = append(problems, checkRequired(requiredSettings())...)
+
+	if viperConfig.GetString("Parallelism") != "" && viperConfig.GetInt("Parallelism") < 1 {
+		problems = append(problems, fmt.Sprintf("Parallelism must be at least 1, got %d", viperConfig.GetInt("Parallelism")))
+	}
+
+	switch viperConfig.GetString("ConfigSource") {
+	case "local":
+		hostDir := filepath.Join(valueOrDefault("WorkDir"), viperConfig.GetString("Hostname"))
+		for _, key := range []string{"CrioConfigFile", "ETCDConfigFile", "MasterConfigFile", "NodeConfigFile", "RegistriesConfigFile"} {
+			file := filepath.Join(hostDir, valueOrDefault(key))
+			if _, err := os.Stat(file); err != nil {
+				problems = append(problems, fmt.Sprintf("%s %s doesn't exist locally in %s", key, valueOrDefault(key), hostDir))
+			}
+		}
+	case "archive":
+		if archive := viperConfig.GetString("Archive"); archive != "" && !isFile(archive) {
+			problems = append(problems, fmt.Sprintf("Archive %s doesn't exist", archive))
+		}
+	case "node-exec":
+		if viperConfig.GetString("ClusterDump") != "" {
+			problems = append(problems, "ClusterDump can't be used when config source is node-exec, files are read through the API of a live cluster")
+		}
+	case "remote":
+		if port := viperConfig.GetInt("SSHPort"); viperConfig.GetString("SSHPort") != "" && (port < 0 || port > 65535) {
+			problems = append(problems, fmt.Sprintf("SSHPort must be between 1 and 65535, got %d", port))
+		}
+		if method := viperConfig.GetString("BecomeMethod"); method != "" && !contains(becomeMethods, method) {
+			problems = append(problems, fmt.Sprintf("BecomeMethod must be one of %s, got %q", strings.Join(becomeMethods, ", "), method))
+		}
+		if viperConfig.GetString("SSHKeepAlive") != "" && viperConfig.GetInt("SSHKeepAlive") < 0 {
+			problems = append(problems, fmt.Sprintf("SSHKeepAlive must be at least 0, got %d", viperConfig.GetInt("SSHKeepAlive")))
+		}
+		if viperConfig.GetString("SSHMaxSessions") != "" && viperConfig.GetInt("SSHMaxSessions") < 1 {
+			problems = append(problems, fmt.Sprintf("SSHMaxSessions must be at least 1, got %d", viperConfig.GetInt("SSHMaxSessions")))
+		}
+		if key := viperConfig.GetString("SSHPrivateKey"); key != "" && !isFile(key) {
+			problems = append(problems, "SSHPrivateKey doesn't exist")
+		}
+		if _, err := HostKeyFingerprints(); err != nil {
+			problems = append(problems, err.Error())
+		}
+		jumpHosts, err := JumpHosts()
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		for _, j := range jumpHosts {
+			if j.KeyFile != "" && !isFile(j.KeyFile) {
+				problems = append(problems, fmt.Sprintf("Key file of jump host %s doesn't exist", j))
+			}
+		}
+	}
+
+	if clusterDump := viperConfig.GetString("ClusterDump"); clusterDump != "" {
+		if info, err := os.Stat(clusterDump); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("ClusterDump %s isn't a directory", clusterDump))
+		}
+	} else if err := api.ParseKubeConfig(); err != nil {
+		problems = append(problems, fmt.Sprintf("kubeconfig can't be parsed: %s", err))
+	} else if clusterName := viperConfig.GetString("ClusterName"); clusterName != "" {
+		if _, ok := api.ClusterNames[clusterName]; !ok {
+			problems = append(problems, fmt.Sprintf("ClusterName %s has no context in kubeconfig", clusterName))
+		}
+	}
+
+	return problems
+}
+
+// valueOrDefault returns the value of key, or its default value when missing
+func valueOrDefault(key string) string {
+	if value := viperConfig.GetString(key); value != "" {
+		return value
+	}
+
+	if s, ok := LookupSetting(key); ok && s.Default != nil {
+		return fmt.Sprint(s.Default)
+	}
+
+	return ""
+}
+
+func isFile(file string) bool {
+	info, err := os.Stat(file)
+	return err == nil && !info.IsDir()
+}
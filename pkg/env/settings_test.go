@@ -0,0 +1,164 @@
+package env
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowConfig(t *testing.T) {
+	savedConfig := viperConfig
+	defer func() { viperConfig = savedConfig }()
+
+	viperConfig = viper.New()
+	viperConfig.SetConfigFile("testdata/cpma-config.yml")
+	require.NoError(t, viperConfig.ReadInConfig())
+	viperConfig.SetEnvPrefix("CPMA")
+	viperConfig.AutomaticEnv()
+
+	os.Setenv("CPMA_PARALLELISM", "2")
+	defer os.Unsetenv("CPMA_PARALLELISM")
+
+	buf := &bytes.Buffer{}
+	flagChanged := func(flag string) bool { return flag == "ssh-login" }
+	require.NoError(t, ShowConfig(buf, flagChanged))
+
+	lines := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n")[1:] {
+		fields := strings.Fields(line)
+		lines[fields[0]] = fields[1:]
+	}
+
+	assert.Equal(t, []string{"root", SourceFlag}, lines["sshlogin"])
+	assert.Equal(t, []string{"2", SourceEnv}, lines["parallelism"])
+	assert.Equal(t, []string{"remote", SourceFile}, lines["configsource"])
+	assert.Equal(t, []string{"false", SourceDefault}, lines["insecurehostkey"])
+	assert.Equal(t, []string{maskedValue, SourceFile}, lines["sshprivatekey"])
+	assert.Len(t, lines, len(Settings))
+}
+
+func TestWriteConfigTemplate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteConfigTemplate(buf))
+
+	// Every setting is commented out, uncommented template is a valid configuration
+	uncommented := []string{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "#") && strings.Contains(line, ":") && !strings.HasPrefix(line, "# ") {
+			uncommented = append(uncommented, strings.TrimPrefix(line, "#"))
+		}
+	}
+
+	values := make(map[string]interface{})
+	require.NoError(t, yaml.Unmarshal([]byte(strings.Join(uncommented, "\n")), &values))
+	assert.Len(t, values, len(Settings)+1)
+	assert.Contains(t, values, profilesKey)
+	assert.Equal(t, DefaultWorkDir, values["workdir"])
+	assert.Equal(t, DefaultBecomeUser, values["becomeuser"])
+	// SSH port has no default, it is read from SSH config when missing
+	assert.Nil(t, values["sshport"])
+	assert.Equal(t, true, values["manifests"])
+}
+
+func TestValidateConfig(t *testing.T) {
+	savedConfig := viperConfig
+	defer func() { viperConfig = savedConfig }()
+
+	testCases := []struct {
+		name             string
+		values           map[string]interface{}
+		expectedProblems []string
+	}{
+		{
+			name: "valid local configuration",
+			values: map[string]interface{}{
+				"ConfigSource":         "local",
+				"Hostname":             "testdata",
+				"WorkDir":              ".",
+				"ClusterDump":          "testdata",
+				"CrioConfigFile":       "cpma-config.yml",
+				"ETCDConfigFile":       "cpma-config.yml",
+				"MasterConfigFile":     "cpma-config.yml",
+				"NodeConfigFile":       "cpma-config.yml",
+				"RegistriesConfigFile": "cpma-config.yml",
+			},
+		},
+		{
+			name: "local files missing",
+			values: map[string]interface{}{
+				"ConfigSource": "local",
+				"Hostname":     "testdata",
+				"ClusterDump":  "testdata",
+			},
+			expectedProblems: []string{
+				"CrioConfigFile /etc/crio/crio.conf doesn't exist locally in testdata",
+				"ETCDConfigFile /etc/etcd/etcd.conf doesn't exist locally in testdata",
+				"MasterConfigFile /etc/origin/master/master-config.yaml doesn't exist locally in testdata",
+				"NodeConfigFile /etc/origin/node/node-config.yaml doesn't exist locally in testdata",
+				"RegistriesConfigFile /etc/containers/registries.conf doesn't exist locally in testdata",
+			},
+		},
+		{
+			name: "invalid types and port",
+			values: map[string]interface{}{
+				"ConfigSource":        "remote",
+				"Hostname":            "master0.example.com",
+				"ClusterDump":         "testdata/missing",
+				"Manifests":           "maybe",
+				"SSHPort":             70000,
+				"SSHPrivateKey":       "testdata/missing-key",
+				"SSHMaxSessions":      0,
+				"BecomeMethod":        "doas",
+				"Parallelism":         0,
+				"HostKeyFingerprints": []string{"master0.example.com=MD5:16:27:ac:a5"},
+			},
+			expectedProblems: []string{
+				`Manifests must be true or false, got "maybe"`,
+				"Parallelism must be at least 1, got 0",
+				"SSHPort must be between 1 and 65535, got 70000",
+				`BecomeMethod must be one of none, sudo, su, got "doas"`,
+				"SSHMaxSessions must be at least 1, got 0",
+				"SSHPrivateKey doesn't exist",
+				`Invalid HostKeyFingerprints: host key fingerprint of master0.example.com must be a SHA256 fingerprint as shown by ssh-keygen -l, got "MD5:16:27:ac:a5"`,
+				"ClusterDump testdata/missing isn't a directory",
+			},
+		},
+		{
+			name: "missing archive",
+			values: map[string]interface{}{
+				"ConfigSource": "archive",
+				"Hostname":     "master0.example.com",
+				"ClusterDump":  "testdata",
+				"Archive":      "testdata/missing.tar.gz",
+			},
+			expectedProblems: []string{"Archive testdata/missing.tar.gz doesn't exist"},
+		},
+		{
+			name: "node-exec using a cluster dump",
+			values: map[string]interface{}{
+				"ConfigSource": "node-exec",
+				"Hostname":     "master0.example.com",
+				"ClusterDump":  "testdata",
+			},
+			expectedProblems: []string{"ClusterDump can't be used when config source is node-exec, files are read through the API of a live cluster"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viperConfig = viper.New()
+			for key, value := range tc.values {
+				viperConfig.Set(key, value)
+			}
+
+			problems := ValidateConfig()
+			assert.ElementsMatch(t, tc.expectedProblems, problems)
+		})
+	}
+}
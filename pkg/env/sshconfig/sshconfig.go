@@ -0,0 +1,158 @@
+package sshconfig
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Host is a Host entry of an OpenSSH client configuration file
+type Host struct {
+	patterns []string
+	// options holds values by lowercase keyword
+	options map[string]string
+}
+
+// Config holds Host entries of an OpenSSH client configuration file, in file order
+type Config []Host
+
+// warned holds SSH config lines already warned about, SSH config is read each time a host is dialed
+var warned = struct {
+	sync.Mutex
+	lines map[string]bool
+}{lines: make(map[string]bool)}
+
+// Read reads Host entries of an OpenSSH client configuration file, a missing file has no entries.
+// Only keywords used to connect are read: HostName, User, Port, IdentityFile and ProxyJump.
+// Include directives and Match blocks are skipped, along with options up to the next Host entry for Match.
+func Read(file string) (Config, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to read SSH config %s", file)
+	}
+	defer f.Close()
+
+	// Options before the first Host entry apply to every host
+	config := Config{{patterns: []string{"*"}, options: map[string]string{}}}
+	current := &config[0]
+
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword, args := splitLine(line)
+		switch strings.ToLower(keyword) {
+		case "host":
+			config = append(config, Host{patterns: args, options: map[string]string{}})
+			current = &config[len(config)-1]
+		case "include":
+			warnOnce(file, lineNumber, "Include is not supported, %s is not read", strings.Join(args, " "))
+		case "match":
+			warnOnce(file, lineNumber, "Match is not supported, options up to the next Host entry are ignored")
+			config = append(config, Host{options: map[string]string{}})
+			current = &config[len(config)-1]
+		case "hostname", "user", "port", "identityfile", "proxyjump":
+			// The first value obtained is used
+			if _, ok := current.options[strings.ToLower(keyword)]; !ok && len(args) > 0 {
+				current.options[strings.ToLower(keyword)] = args[0]
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "Unable to read SSH config %s", file)
+	}
+
+	return config, nil
+}
+
+// warnOnce logs a warning about a line of file, unless it was already logged
+func warnOnce(file string, lineNumber int, format string, args ...interface{}) {
+	warned.Lock()
+	defer warned.Unlock()
+
+	key := fmt.Sprintf("%s:%d", file, lineNumber)
+	if warned.lines[key] {
+		return
+	}
+	warned.lines[key] = true
+
+	logrus.Warnf("SSH config %s: "+format, append([]interface{}{key}, args...)...)
+}
+
+// ReadUser reads the SSH config of the user, $HOME/.ssh/config
+func ReadUser(home string) (Config, error) {
+	return Read(filepath.Join(home, ".ssh", "config"))
+}
+
+// splitLine splits a line into its keyword and arguments, keyword and arguments can be separated by '='
+func splitLine(line string) (string, []string) {
+	fields := strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' || r == '=' })
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	args := fields[1:]
+	for i, arg := range args {
+		args[i] = strings.Trim(arg, `"`)
+	}
+
+	return fields[0], args
+}
+
+// Get returns the value of keyword for host, from the first entry matching host which sets it
+func (c Config) Get(host, keyword string) string {
+	for _, entry := range c {
+		if !entry.matches(host) {
+			continue
+		}
+		if value, ok := entry.options[strings.ToLower(keyword)]; ok {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// IdentityFile returns the IdentityFile set for host, expanded, empty when there is none
+func (c Config) IdentityFile(host, home string) string {
+	return ExpandPath(c.Get(host, "IdentityFile"), home)
+}
+
+// matches tells if host matches the entry patterns, a matching negated pattern excludes the host
+func (h Host) matches(host string) bool {
+	matched := false
+	for _, pattern := range h.patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		if ok, _ := path.Match(strings.TrimPrefix(pattern, "!"), host); ok {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// ExpandPath expands a leading ~ and %d to the home directory
+func ExpandPath(file, home string) string {
+	if strings.HasPrefix(file, "~/") {
+		file = filepath.Join(home, file[2:])
+	}
+
+	return strings.Replace(file, "%d", home, -1)
+}
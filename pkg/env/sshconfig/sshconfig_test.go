@@ -0,0 +1,53 @@
+package sshconfig
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead(t *testing.T) {
+	config, err := Read("testdata/ssh_config")
+	require.NoError(t, err)
+
+	assert.Equal(t, "master0.cluster1.example.com", config.Get("master0", "HostName"))
+	assert.Equal(t, "bastion,admin@inner.example.com:2200", config.Get("master0", "proxyjump"))
+	assert.Equal(t, "jumper", config.Get("bastion", "User"))
+	assert.Equal(t, "2222", config.Get("bastion", "Port"))
+	// First obtained value is used
+	assert.Equal(t, "~/.ssh/bastion", config.Get("bastion", "IdentityFile"))
+	assert.Equal(t, "%d/.ssh/example", config.Get("inner.example.com", "IdentityFile"))
+	assert.Equal(t, "", config.Get("legacy.example.com", "IdentityFile"))
+	// Match blocks are skipped
+	assert.Equal(t, "default", config.Get("other", "User"))
+
+	assert.Equal(t, "/home/user/.ssh/bastion", config.IdentityFile("bastion", "/home/user"))
+	assert.Equal(t, "", config.IdentityFile("legacy.example.com", "/home/user"))
+
+	missing, err := Read("testdata/missing")
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestReadWarnings(t *testing.T) {
+	var out bytes.Buffer
+	logrus.SetOutput(&out)
+	defer logrus.SetOutput(os.Stderr)
+	warned.lines = make(map[string]bool)
+
+	for i := 0; i < 2; i++ {
+		_, err := Read("testdata/ssh_config")
+		require.NoError(t, err)
+	}
+
+	// Skipped Include and Match are warned about once
+	logs := out.String()
+	assert.Equal(t, 2, strings.Count(logs, "level=warning"))
+	assert.Contains(t, logs, "testdata/ssh_config:3: Include is not supported, config.d/* is not read")
+	assert.Contains(t, logs, "testdata/ssh_config:18: Match is not supported, options up to the next Host entry are ignored")
+}
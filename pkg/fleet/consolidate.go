@@ -0,0 +1,56 @@
+package fleet
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/cpma/pkg/transform"
+	"github.com/konveyor/cpma/pkg/transform/consolidation"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Consolidate merges cluster-scoped manifests into the manifests directory of the work directory,
+// conflicts are added to the fleet report. Clusters which failed are left out, so are components
+// which failed on partial clusters or were skipped.
+func (r Runner) Consolidate(report *reportoutput.FleetReport) error {
+	sources := []consolidation.Source{}
+	for _, c := range report.Clusters {
+		if c.Status == reportoutput.StatusFailed {
+			logrus.Warnf("Fleet:Cluster %s failed, it is left out of consolidation", c.Name)
+			continue
+		}
+
+		failed := c.FailedComponents()
+		if len(failed) > 0 {
+			logrus.Warnf("Fleet:Cluster %s: %s failed, left out of consolidation", c.Name, strings.Join(failed, ", "))
+		}
+		skipped := c.SkippedComponents()
+		if len(skipped) > 0 {
+			logrus.Infof("Fleet:Cluster %s: %s skipped, left out of consolidation", c.Name, strings.Join(skipped, ", "))
+		}
+		sources = append(sources, consolidation.Source{
+			Cluster:           c.Name,
+			ManifestsDir:      filepath.Join(c.WorkDir, manifestsDirName),
+			FailedComponents:  failed,
+			SkippedComponents: skipped,
+		})
+	}
+
+	if len(sources) == 0 {
+		return errors.New("Every cluster failed, nothing to consolidate")
+	}
+
+	manifests, conflicts, err := consolidation.Consolidate(sources)
+	if err != nil {
+		return errors.Wrap(err, "Consolidation failed")
+	}
+
+	for _, conflict := range conflicts {
+		logrus.Warnf("Fleet:Conflict %s %s: %s", conflict.Kind, conflict.Field, conflict.Comment)
+	}
+	report.Conflicts = conflicts
+
+	return transform.ManifestOutput{Manifests: manifests}.Flush()
+}
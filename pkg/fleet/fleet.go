@@ -0,0 +1,264 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// outputFileName is the file, in the cluster work directory, holding the output of the cluster run
+const outputFileName = "cpma-run.log"
+
+// reportFileName is the JSON report written by a cluster run
+const reportFileName = "report.json"
+
+// manifestsDirName is the directory manifests of a cluster run are written to
+const manifestsDirName = "manifests"
+
+// Cluster is a source cluster to process
+type Cluster struct {
+	Name string `json:"name"`
+	// Profile of the configuration file used for the cluster, none when empty
+	Profile string `json:"profile,omitempty"`
+	// Settings override configuration values for the cluster, keys are setting names
+	Settings map[string]interface{} `json:"settings,omitempty"`
+}
+
+// ClusterList is the content of a cluster list file
+type ClusterList struct {
+	Clusters []Cluster `json:"clusters"`
+}
+
+// LoadClusterList reads and checks a cluster list file
+func LoadClusterList(file string) ([]Cluster, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read cluster list %s", file)
+	}
+
+	list := ClusterList{}
+	if err := yaml.Unmarshal(content, &list); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse cluster list %s", file)
+	}
+
+	if len(list.Clusters) == 0 {
+		return nil, errors.Errorf("Cluster list %s has no clusters", file)
+	}
+
+	names := make(map[string]bool)
+	for _, c := range list.Clusters {
+		if err := c.check(); err != nil {
+			return nil, errors.Wrapf(err, "Invalid cluster list %s", file)
+		}
+		if names[c.Name] {
+			return nil, errors.Errorf("Invalid cluster list %s: cluster %s is listed twice", file, c.Name)
+		}
+		names[c.Name] = true
+	}
+
+	return list.Clusters, nil
+}
+
+// ProfileClusters returns a cluster for each profile, named after it
+func ProfileClusters(profiles []string) []Cluster {
+	clusters := make([]Cluster, 0, len(profiles))
+	for _, profile := range profiles {
+		clusters = append(clusters, Cluster{Name: profile, Profile: profile})
+	}
+
+	return clusters
+}
+
+// Select returns clusters named in names, in the order of names
+func Select(clusters []Cluster, names []string) ([]Cluster, error) {
+	selected := make([]Cluster, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, c := range clusters {
+			if c.Name == name {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("Cluster %s isn't listed", name)
+		}
+	}
+
+	return selected, nil
+}
+
+// check makes sure the cluster name can be used as a directory and its settings exist
+func (c Cluster) check() error {
+	if c.Name == "" || c.Name == "." || c.Name == ".." || filepath.Base(c.Name) != c.Name {
+		return errors.Errorf("cluster name %q can't be used as a directory name", c.Name)
+	}
+
+	for key := range c.Settings {
+		s, ok := env.LookupSetting(key)
+		if !ok {
+			return errors.Errorf("cluster %s sets %s which is not a setting", c.Name, key)
+		}
+		switch s.Key {
+		case "WorkDir", "Profile", "NonInteractive":
+			return errors.Errorf("cluster %s can't set %s, it is set for every cluster", c.Name, key)
+		}
+	}
+
+	return nil
+}
+
+// environ returns settings of the cluster as environment variables
+func (c Cluster) environ() []string {
+	environ := make([]string, 0, len(c.Settings))
+	for key, value := range c.Settings {
+		s, _ := env.LookupSetting(key)
+		environ = append(environ, s.EnvVar()+"="+envValue(value))
+	}
+
+	return environ
+}
+
+// envValue formats a setting value as an environment variable value, list items are separated by spaces
+func envValue(value interface{}) string {
+	if items, ok := value.([]interface{}); ok {
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			values = append(values, fmt.Sprint(item))
+		}
+		return strings.Join(values, " ")
+	}
+
+	return fmt.Sprint(value)
+}
+
+// Runner runs cpma once for every cluster, each cluster in its own subdirectory of WorkDir
+type Runner struct {
+	// Executable is the cpma binary run for every cluster
+	Executable string
+	// Args are passed to every cluster run, before cluster specific arguments
+	Args []string
+	// Env is added to the environment of every cluster run, before cluster settings
+	Env []string
+	// WorkDir holds a subdirectory per cluster
+	WorkDir string
+	// Parallelism is the maximum number of clusters processed concurrently
+	Parallelism int
+}
+
+// ClusterDir returns the work directory of cluster c
+func (r Runner) ClusterDir(c Cluster) string {
+	return filepath.Join(r.WorkDir, c.Name)
+}
+
+// Run processes every cluster and aggregates their reports, clusters which failed are reported as failed
+func (r Runner) Run(clusters []Cluster) reportoutput.FleetReport {
+	parallelism := r.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	errs := make([]error, len(clusters))
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, c := range clusters {
+		wg.Add(1)
+		go func(i int, c Cluster) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			logrus.Infof("Fleet:Cluster %s: running in %s", c.Name, r.ClusterDir(c))
+			errs[i] = r.runCluster(c)
+			if errs[i] != nil {
+				logrus.Errorf("Fleet:Cluster %s: %s", c.Name, errs[i])
+				return
+			}
+			logrus.Infof("Fleet:Cluster %s: done", c.Name)
+		}(i, c)
+	}
+	wg.Wait()
+
+	fleet := reportoutput.FleetReport{}
+	for i, c := range clusters {
+		report, err := readReport(r.ClusterDir(c))
+		if err != nil && errs[i] == nil {
+			errs[i] = err
+		}
+		fleet.AddCluster(c.Name, r.ClusterDir(c), report, errs[i])
+	}
+
+	return fleet
+}
+
+// runCluster runs cpma non-interactively for cluster c, its output is written to the cluster work directory
+func (r Runner) runCluster(c Cluster) error {
+	dir := r.ClusterDir(c)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return errors.Wrapf(err, "Failed to create %s", dir)
+	}
+
+	// A report or manifests left by a previous run mustn't be taken for the result of this one,
+	// components which fail or are skipped now would otherwise keep their previous manifests
+	if err := os.Remove(filepath.Join(dir, reportFileName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(dir, manifestsDirName)); err != nil {
+		return err
+	}
+
+	outputFile := filepath.Join(dir, outputFileName)
+	output, err := os.Create(outputFile)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create %s", outputFile)
+	}
+	defer output.Close()
+
+	args := append([]string{}, r.Args...)
+	args = append(args, "--non-interactive", "--work-dir", dir, "--profile="+c.Profile)
+
+	cmd := exec.Command(r.Executable, args...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Later values take precedence, the fleet report needs every cluster report
+	cmd.Env = append(os.Environ(), r.Env...)
+	cmd.Env = append(cmd.Env, c.environ()...)
+	cmd.Env = append(cmd.Env, "CPMA_REPORTING=true")
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "run failed, see %s", outputFile)
+	}
+
+	return nil
+}
+
+// readReport reads the JSON report of a cluster run, nil when there is none
+func readReport(dir string) (*reportoutput.ReportOutput, error) {
+	file := filepath.Join(dir, reportFileName)
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("%s not found", file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &reportoutput.ReportOutput{}
+	if err := json.Unmarshal(content, report); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse %s", file)
+	}
+
+	return report, nil
+}
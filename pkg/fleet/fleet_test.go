@@ -0,0 +1,266 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadClusterList(t *testing.T) {
+	testCases := []struct {
+		name             string
+		content          string
+		expectedClusters []Cluster
+		expectedErr      string
+	}{
+		{
+			name: "load clusters",
+			content: `clusters:
+- name: cluster1
+  profile: cluster1
+- name: cluster2
+  settings:
+    hostname: master0.cluster2.example.com
+    sshport: 2222
+    skip: [ETCD, Crio]
+`,
+			expectedClusters: []Cluster{
+				{Name: "cluster1", Profile: "cluster1"},
+				{Name: "cluster2", Settings: map[string]interface{}{
+					"hostname": "master0.cluster2.example.com",
+					"sshport":  float64(2222),
+					"skip":     []interface{}{"ETCD", "Crio"},
+				}},
+			},
+		},
+		{
+			name:        "no clusters",
+			content:     "clusters: []\n",
+			expectedErr: "has no clusters",
+		},
+		{
+			name:        "name used as a directory",
+			content:     "clusters:\n- name: ../cluster1\n",
+			expectedErr: `cluster name "../cluster1" can't be used as a directory name`,
+		},
+		{
+			name:        "cluster listed twice",
+			content:     "clusters:\n- name: cluster1\n- name: cluster1\n",
+			expectedErr: "cluster cluster1 is listed twice",
+		},
+		{
+			name:        "unknown setting",
+			content:     "clusters:\n- name: cluster1\n  settings:\n    hostnme: master0.example.com\n",
+			expectedErr: "cluster cluster1 sets hostnme which is not a setting",
+		},
+		{
+			name:        "work directory set",
+			content:     "clusters:\n- name: cluster1\n  settings:\n    workdir: data\n",
+			expectedErr: "cluster cluster1 can't set workdir, it is set for every cluster",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := ioutil.TempFile("", "cpma-clusters")
+			require.NoError(t, err)
+			defer os.Remove(file.Name())
+			_, err = file.WriteString(tc.content)
+			require.NoError(t, err)
+			file.Close()
+
+			clusters, err := LoadClusterList(file.Name())
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedClusters, clusters)
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	clusters := ProfileClusters([]string{"cluster1", "cluster2", "cluster3"})
+
+	selected, err := Select(clusters, []string{"cluster3", "cluster1"})
+	require.NoError(t, err)
+	assert.Equal(t, []Cluster{{Name: "cluster3", Profile: "cluster3"}, {Name: "cluster1", Profile: "cluster1"}}, selected)
+
+	_, err = Select(clusters, []string{"cluster4"})
+	assert.EqualError(t, err, "Cluster cluster4 isn't listed")
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-fleet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	runner := Runner{
+		Executable:  os.Args[0],
+		Args:        []string{"-test.run=TestHelperProcess", "--"},
+		Env:         []string{"CPMA_TEST_HELPER_PROCESS=1", "CPMA_HOSTNAME=shared.example.com"},
+		WorkDir:     dir,
+		Parallelism: 2,
+	}
+
+	clusters := []Cluster{
+		{Name: "cluster1", Profile: "cluster1"},
+		{Name: "cluster2", Settings: map[string]interface{}{"hostname": "master0.cluster2.example.com", "skip": []interface{}{"ETCD", "Crio"}}},
+		{Name: "failing", Settings: map[string]interface{}{"hostname": "fail"}},
+	}
+
+	// Manifests of a previous run are removed
+	staleManifest := filepath.Join(dir, "cluster1", manifestsDirName, "100_CPMA-cluster-config-sdn.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(staleManifest), 0750))
+	require.NoError(t, ioutil.WriteFile(staleManifest, []byte("kind: Network\n"), 0640))
+
+	fleet := runner.Run(clusters)
+	require.Len(t, fleet.Clusters, 3)
+	_, err = os.Stat(staleManifest)
+	assert.True(t, os.IsNotExist(err), "stale manifest must be removed")
+
+	assert.Equal(t, reportoutput.StatusSucceeded, fleet.Clusters[0].Status)
+	assert.Equal(t, []string{"shared.example.com"}, fleet.Clusters[0].NetworkPlugins)
+	assert.Equal(t, []string{"cluster1"}, fleet.Clusters[0].IdentityProviders)
+
+	// Cluster settings override shared values
+	assert.Equal(t, reportoutput.StatusSucceeded, fleet.Clusters[1].Status)
+	assert.Equal(t, []string{"master0.cluster2.example.com"}, fleet.Clusters[1].NetworkPlugins)
+	assert.Equal(t, []string{"ETCD Crio"}, fleet.Clusters[1].IdentityProviders)
+	assert.Equal(t, filepath.Join(dir, "cluster2"), fleet.Clusters[1].WorkDir)
+
+	assert.Equal(t, reportoutput.StatusFailed, fleet.Clusters[2].Status)
+	assert.Contains(t, fleet.Clusters[2].Error, "run failed, see "+filepath.Join(dir, "failing", outputFileName))
+	output, err := ioutil.ReadFile(filepath.Join(dir, "failing", outputFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "failing on purpose\n", string(output))
+}
+
+// TestHelperProcess stands for cpma in cluster runs of TestRun, it writes a report made of received values
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("CPMA_TEST_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+
+	var workDir, profile string
+	for i, arg := range args {
+		switch {
+		case arg == "--work-dir":
+			workDir = args[i+1]
+		case strings.HasPrefix(arg, "--profile="):
+			profile = strings.TrimPrefix(arg, "--profile=")
+		}
+	}
+
+	if os.Getenv("CPMA_HOSTNAME") == "fail" {
+		os.Stderr.WriteString("failing on purpose\n")
+		os.Exit(1)
+	}
+
+	identityProvider := profile
+	if identityProvider == "" {
+		identityProvider = os.Getenv("CPMA_SKIP")
+	}
+
+	report := reportoutput.ReportOutput{
+		ComponentReports: []reportoutput.ComponentReport{
+			{
+				Component: "Test",
+				Reports: []reportoutput.Report{
+					{Name: identityProvider, Kind: "IdentityProviders", Supported: true},
+					{Name: os.Getenv("CPMA_HOSTNAME"), Kind: "NetworkPlugin", Supported: true},
+				},
+			},
+		},
+	}
+
+	content, _ := json.Marshal(report)
+	if err := ioutil.WriteFile(filepath.Join(workDir, reportFileName), content, 0640); err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+func TestConsolidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-fleet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	savedWorkDir := env.Config().GetString("WorkDir")
+	defer env.Config().Set("WorkDir", savedWorkDir)
+	env.Config().Set("WorkDir", dir)
+
+	sdnManifest := `apiVersion: operator.openshift.io/v1
+kind: Network
+metadata:
+  name: cluster
+spec:
+  clusterNetwork:
+  - cidr: %s
+    hostPrefix: 23
+  serviceNetwork:
+  - 172.30.0.0/16
+`
+	sdnFailed := []reportoutput.ComponentStatus{
+		{Component: "Image", Status: reportoutput.StatusSucceeded},
+		{Component: "SDN", Status: reportoutput.StatusFailed},
+	}
+	sdnSkipped := []reportoutput.ComponentStatus{
+		{Component: "Image", Status: reportoutput.StatusSucceeded},
+		{Component: "SDN", Status: reportoutput.StatusSkipped},
+	}
+	report := &reportoutput.FleetReport{}
+	for _, c := range []struct {
+		name, cidr, status string
+		runStatus          []reportoutput.ComponentStatus
+	}{
+		{"cluster1", "10.128.0.0/14", reportoutput.StatusSucceeded, nil},
+		{"cluster2", "10.132.0.0/14", reportoutput.StatusSucceeded, nil},
+		{"partial", "10.140.0.0/14", reportoutput.StatusPartial, sdnFailed},
+		{"skipping", "10.144.0.0/14", reportoutput.StatusSucceeded, sdnSkipped},
+		{"failing", "10.136.0.0/14", reportoutput.StatusFailed, nil},
+	} {
+		manifests := filepath.Join(dir, c.name, "manifests")
+		require.NoError(t, os.MkdirAll(manifests, 0750))
+		content := fmt.Sprintf(sdnManifest, c.cidr)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(manifests, "100_CPMA-cluster-config-sdn.yaml"), []byte(content), 0640))
+		report.Clusters = append(report.Clusters, reportoutput.FleetCluster{
+			Name:      c.name,
+			WorkDir:   filepath.Join(dir, c.name),
+			Status:    c.status,
+			RunStatus: c.runStatus,
+		})
+	}
+
+	runner := Runner{WorkDir: dir}
+	require.NoError(t, runner.Consolidate(report))
+
+	require.Len(t, report.Conflicts, 1)
+	assert.Equal(t, "spec.clusterNetwork", report.Conflicts[0].Field)
+	// Clusters which failed, components which failed on partial clusters and skipped components are left out
+	require.Len(t, report.Conflicts[0].Values, 2)
+	assert.Equal(t, "cluster2", report.Conflicts[0].Values[1].Cluster)
+
+	consolidated, err := ioutil.ReadFile(filepath.Join(dir, "manifests", "100_CPMA-cluster-config-sdn.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(consolidated), "10.128.0.0/14")
+
+	failed := &reportoutput.FleetReport{Clusters: []reportoutput.FleetCluster{{Name: "failing", Status: reportoutput.StatusFailed}}}
+	assert.EqualError(t, runner.Consolidate(failed), "Every cluster failed, nothing to consolidate")
+}
@@ -0,0 +1,322 @@
+package io
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	goio "io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxSymlinks is the number of symbolic links followed when resolving a path inside an archive
+const maxSymlinks = 10
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// Archive gives access to files of a .tar, .tar.gz or .tar.xz archive, such as a sosreport,
+// using their path on the OCP3 host. Files can be under any root directory inside the archive,
+// like sosreport-<host>-<date>/ or <hostname>/. Entries are indexed in a single pass when the archive is opened,
+// contents of files are only read when they are first fetched, then kept. Files can be read concurrently.
+type Archive struct {
+	file    string
+	entries map[string]*tar.Header
+	mutex   sync.Mutex
+	content map[string][]byte
+	missing map[string]bool
+}
+
+var (
+	sourceArchive *Archive
+	archiveMutex  sync.Mutex
+)
+
+// FetchFromArchive retrieves file from the archive set as Archive, files which aren't found are recorded as missing
+func FetchFromArchive(src string) ([]byte, error) {
+	archive, err := currentArchive()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := archive.ReadFile(src)
+	if err != nil && archive.IsMissing(src) {
+		recordFetch(FileRecord{Host: env.Config().GetString("Hostname"), Path: src, State: FileMissing, Error: err.Error()})
+	}
+
+	return content, err
+}
+
+// MissingArchiveFiles returns files which were looked for but not found in the archive set as Archive
+func MissingArchiveFiles() []string {
+	archiveMutex.Lock()
+	defer archiveMutex.Unlock()
+
+	if sourceArchive == nil {
+		return nil
+	}
+
+	return sourceArchive.Missing()
+}
+
+// currentArchive opens the archive set as Archive, once
+func currentArchive() (*Archive, error) {
+	archiveMutex.Lock()
+	defer archiveMutex.Unlock()
+
+	file := env.Config().GetString("Archive")
+	if file == "" {
+		return nil, errors.New("No archive set, it's needed when config source is archive")
+	}
+
+	if sourceArchive == nil || sourceArchive.file != file {
+		archive, err := OpenArchive(file)
+		if err != nil {
+			return nil, err
+		}
+		sourceArchive = archive
+	}
+
+	return sourceArchive, nil
+}
+
+// OpenArchive indexes entries of an archive, compression is detected from the content
+func OpenArchive(file string) (*Archive, error) {
+	archive := &Archive{
+		file:    file,
+		entries: make(map[string]*tar.Header),
+		content: make(map[string][]byte),
+		missing: make(map[string]bool),
+	}
+
+	err := archive.walk(func(header *tar.Header, r goio.Reader) (bool, error) {
+		archive.entries[entryName(header.Name)] = header
+		return false, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read archive %s", file)
+	}
+	logrus.Debugf("Archive:%s: %d entries found", file, len(archive.entries))
+
+	return archive, nil
+}
+
+// ReadFile returns the content of a file, src is its path on the OCP3 host
+func (a *Archive) ReadFile(src string) ([]byte, error) {
+	name, err := a.resolve(src)
+	if err != nil {
+		a.mutex.Lock()
+		a.missing[src] = true
+		a.mutex.Unlock()
+		logrus.Warnf("Archive:%s", err)
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	content, ok := a.content[name]
+	a.mutex.Unlock()
+	if ok {
+		return content, nil
+	}
+
+	logrus.Debugf("Fetching file %s from archive %s:%s", src, a.file, name)
+	err = a.walk(func(header *tar.Header, r goio.Reader) (bool, error) {
+		if entryName(header.Name) != name {
+			return false, nil
+		}
+		content, err = ioutil.ReadAll(r)
+		return true, err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read %s from archive %s", name, a.file)
+	}
+
+	a.mutex.Lock()
+	a.content[name] = content
+	a.mutex.Unlock()
+
+	return content, nil
+}
+
+// IsMissing tells whether src was looked for but not found
+func (a *Archive) IsMissing(src string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.missing[src]
+}
+
+// Missing returns files which were looked for but not found, sorted
+func (a *Archive) Missing() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	missing := make([]string, 0, len(a.missing))
+	for src := range a.missing {
+		missing = append(missing, src)
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+// resolve finds the archive entry of src, following symbolic links
+func (a *Archive) resolve(src string) (string, error) {
+	rel := strings.TrimPrefix(path.Clean("/"+src), "/")
+
+	name, found := a.lookup(rel)
+	if !found {
+		return "", errors.Errorf("File %s not found in archive %s", src, a.file)
+	}
+
+	for i := 0; i < maxSymlinks; i++ {
+		header := a.entries[name]
+		switch {
+		case isRegular(header):
+			return name, nil
+		case header.Typeflag == tar.TypeSymlink, header.Typeflag == tar.TypeLink:
+		default:
+			return "", errors.Errorf("File %s is not a regular file in archive %s", src, a.file)
+		}
+
+		target := entryName(header.Linkname)
+		if header.Typeflag == tar.TypeSymlink {
+			root := strings.TrimSuffix(name, rel)
+			if path.IsAbs(header.Linkname) {
+				target = entryName(root + header.Linkname)
+				rel = strings.TrimPrefix(path.Clean(header.Linkname), "/")
+			} else {
+				target = path.Join(path.Dir(name), header.Linkname)
+				rel = strings.TrimPrefix(target, root)
+			}
+		}
+
+		if _, ok := a.entries[target]; !ok {
+			return "", errors.Errorf("File %s links to %s which isn't in archive %s", src, header.Linkname, a.file)
+		}
+		name = target
+	}
+
+	return "", errors.Errorf("File %s has too many levels of symbolic links in archive %s", src, a.file)
+}
+
+// lookup finds the entry whose name is rel, or ends with rel under a root directory.
+// When several roots hold rel, the shortest entry name wins.
+func (a *Archive) lookup(rel string) (string, bool) {
+	var candidates []string
+	for name := range a.entries {
+		if name == rel || strings.HasSuffix(name, "/"+rel) {
+			candidates = append(candidates, name)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i]) != len(candidates[j]) {
+			return len(candidates[i]) < len(candidates[j])
+		}
+		return candidates[i] < candidates[j]
+	})
+
+	return candidates[0], true
+}
+
+// walk calls fn for every archive entry until it returns true
+func (a *Archive) walk(fn func(header *tar.Header, r goio.Reader) (bool, error)) error {
+	r, err := openTarStream(a.file)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == goio.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		done, err := fn(header, tarReader)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// openTarStream returns the uncompressed tar stream of file.
+// xz isn't supported by the standard library, xz command is used instead.
+func openTarStream(file string) (goio.ReadCloser, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(f)
+	magic, _ := buffered.Peek(len(xzMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{Reader: gzipReader, close: f.Close}, nil
+	case bytes.HasPrefix(magic, xzMagic):
+		cmd := exec.Command("xz", "--decompress", "--stdout")
+		cmd.Stdin = buffered
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			f.Close()
+			return nil, errors.Wrap(err, "xz command is needed to read .tar.xz archives")
+		}
+		return readCloser{Reader: stdout, close: func() error {
+			stdout.Close()
+			cmd.Wait()
+			return f.Close()
+		}}, nil
+	default:
+		return readCloser{Reader: buffered, close: f.Close}, nil
+	}
+}
+
+type readCloser struct {
+	goio.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error {
+	return r.close()
+}
+
+// isRegular tells whether an archive entry is a regular file
+func isRegular(header *tar.Header) bool {
+	return header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA
+}
+
+// entryName normalizes an archive entry name, dropping a leading ./ or /
+func entryName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,232 @@
+package io
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type archiveEntry struct {
+	name     string
+	content  string
+	linkname string
+	typeflag byte
+}
+
+var sosreportEntries = []archiveEntry{
+	{name: "sosreport-master0/", typeflag: tar.TypeDir},
+	{name: "sosreport-master0/etc/origin/master/master-config.yaml", content: "kind: MasterConfig\n"},
+	{name: "sosreport-master0/etc/origin/node/bootstrap-node-config.yaml", content: "kind: NodeConfig\n"},
+	{name: "sosreport-master0/etc/origin/node/node-config.yaml", linkname: "bootstrap-node-config.yaml", typeflag: tar.TypeSymlink},
+	{name: "sosreport-master0/etc/etcd/etcd.conf", linkname: "/etc/etcd/etcd.conf.orig", typeflag: tar.TypeSymlink},
+	{name: "sosreport-master0/etc/etcd/etcd.conf.orig", content: "ETCD_NAME=master0\n"},
+	{name: "sosreport-master0/etc/crio/crio.conf", linkname: "crio.conf", typeflag: tar.TypeSymlink},
+	{name: "sosreport-master0/sos_commands/etc/crio/crio.conf", content: "[crio]\n"},
+}
+
+// writeTestArchive writes a tar archive of entries into dir, compressed with gzip or xz unless compression is empty
+func writeTestArchive(t *testing.T, dir, compression string, entries []archiveEntry) string {
+	buf := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buf)
+	for _, entry := range entries {
+		typeflag := entry.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		header := &tar.Header{
+			Name:     entry.name,
+			Linkname: entry.linkname,
+			Typeflag: typeflag,
+			Mode:     0644,
+			Size:     int64(len(entry.content)),
+		}
+		require.NoError(t, tarWriter.WriteHeader(header))
+		_, err := tarWriter.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+
+	content := buf.Bytes()
+	file := filepath.Join(dir, "archive.tar")
+	switch compression {
+	case "gzip":
+		compressed := &bytes.Buffer{}
+		gzipWriter := gzip.NewWriter(compressed)
+		_, err := gzipWriter.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, gzipWriter.Close())
+		content = compressed.Bytes()
+		file += ".gz"
+	case "xz":
+		cmd := exec.Command("xz", "--compress", "--stdout")
+		cmd.Stdin = bytes.NewReader(content)
+		compressed, err := cmd.Output()
+		require.NoError(t, err)
+		content = compressed
+		file += ".xz"
+	}
+
+	require.NoError(t, ioutil.WriteFile(file, content, 0644))
+	return file
+}
+
+func TestArchiveReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	compressions := []string{"", "gzip"}
+	if _, err := exec.LookPath("xz"); err == nil {
+		compressions = append(compressions, "xz")
+	} else {
+		t.Log("xz command not found, .tar.xz archives are not tested")
+	}
+
+	testCases := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name:     "file under sosreport directory",
+			src:      "/etc/origin/master/master-config.yaml",
+			expected: "kind: MasterConfig\n",
+		},
+		{
+			name:     "relative symbolic link",
+			src:      "/etc/origin/node/node-config.yaml",
+			expected: "kind: NodeConfig\n",
+		},
+		{
+			name:     "absolute symbolic link",
+			src:      "/etc/etcd/etcd.conf",
+			expected: "ETCD_NAME=master0\n",
+		},
+	}
+
+	for _, compression := range compressions {
+		file := writeTestArchive(t, dir, compression, sosreportEntries)
+		archive, err := OpenArchive(file)
+		require.NoError(t, err)
+
+		for _, tc := range testCases {
+			t.Run(compression+" "+tc.name, func(t *testing.T) {
+				content, err := archive.ReadFile(tc.src)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, string(content))
+			})
+		}
+	}
+}
+
+func TestArchiveMissingFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	archive, err := OpenArchive(writeTestArchive(t, dir, "gzip", sosreportEntries))
+	require.NoError(t, err)
+
+	_, err = archive.ReadFile("/etc/containers/registries.conf")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "File /etc/containers/registries.conf not found in archive")
+
+	// Symbolic link to itself
+	_, err = archive.ReadFile("/etc/crio/crio.conf")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too many levels of symbolic links")
+
+	_, err = archive.ReadFile("/etc/origin")
+	require.Error(t, err)
+
+	assert.Equal(t, []string{"/etc/containers/registries.conf", "/etc/crio/crio.conf", "/etc/origin"}, archive.Missing())
+}
+
+func TestArchiveReadOnDemand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	entries := []archiveEntry{
+		{name: "./master0/var/log/messages", content: strings.Repeat("x", 1<<21)},
+		{name: "./master0/etc/etcd/etcd.conf", content: "ETCD_NAME=master0\n"},
+	}
+	file := writeTestArchive(t, dir, "gzip", entries)
+	archive, err := OpenArchive(file)
+	require.NoError(t, err)
+
+	// Only entries are indexed when the archive is opened
+	assert.Len(t, archive.entries, 2)
+	assert.Empty(t, archive.content)
+
+	content, err := archive.ReadFile("/etc/etcd/etcd.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "ETCD_NAME=master0\n", string(content))
+	assert.Len(t, archive.content, 1)
+
+	// Fetched files are kept, the archive isn't read again
+	require.NoError(t, os.Remove(file))
+	content, err = archive.ReadFile("/etc/etcd/etcd.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "ETCD_NAME=master0\n", string(content))
+}
+
+func TestArchiveShortestPathWins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	entries := []archiveEntry{
+		{name: "./master0/backup/etc/etcd/etcd.conf", content: "backup\n"},
+		{name: "./master0/etc/etcd/etcd.conf", content: "current\n"},
+	}
+	archive, err := OpenArchive(writeTestArchive(t, dir, "", entries))
+	require.NoError(t, err)
+
+	content, err := archive.ReadFile("/etc/etcd/etcd.conf")
+	require.NoError(t, err)
+	assert.Equal(t, "current\n", string(content))
+}
+
+func TestFetchFileFromArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	env.Config().Set("FetchFromRemote", false)
+	env.Config().Set("Hostname", "master0")
+	env.Config().Set("ConfigSource", "archive")
+	env.Config().Set("Archive", writeTestArchive(t, dir, "gzip", sosreportEntries))
+	defer func() {
+		env.Config().Set("Hostname", "")
+		env.Config().Set("ConfigSource", "")
+		env.Config().Set("Archive", "")
+		sourceArchive = nil
+	}()
+	ResetFetchedFiles()
+	defer ResetFetchedFiles()
+
+	content, err := FetchFile("/etc/origin/master/master-config.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "kind: MasterConfig\n", string(content))
+
+	_, err = FetchFile("/etc/origin/master/htpasswd")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"/etc/origin/master/htpasswd"}, MissingArchiveFiles())
+
+	records := FetchedFiles()
+	require.Len(t, records, 1)
+	assert.Equal(t, "master0", records[0].Host)
+	assert.Equal(t, "/etc/origin/master/htpasswd", records[0].Path)
+	assert.Equal(t, FileMissing, records[0].State)
+}
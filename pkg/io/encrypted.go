@@ -0,0 +1,54 @@
+package io
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+)
+
+// PEM block types of values encrypted by oc adm ca encrypt and of the key they are encrypted with
+const (
+	encryptedBlockType     = "ENCRYPTED STRING"
+	encryptingKeyBlockType = "ENCRYPTING KEY"
+)
+
+// decryptStringSource decrypts value, encrypted by oc adm ca encrypt using the key held by keyFile.
+// Errors never hold the value nor the key.
+func decryptStringSource(value []byte, keyFile string) (string, error) {
+	keyContent, err := FetchSecretFile(keyFile)
+	if err != nil {
+		return "", errors.Wrapf(err, "Cannot read key file %s", keyFile)
+	}
+
+	keyBlock := findPEMBlock(keyContent, encryptingKeyBlockType)
+	if keyBlock == nil {
+		return "", errors.Errorf("Key file %s holds no %s PEM block", keyFile, encryptingKeyBlockType)
+	}
+
+	block := findPEMBlock(value, encryptedBlockType)
+	if block == nil {
+		return "", errors.Errorf("Value holds no %s PEM block", encryptedBlockType)
+	}
+
+	plaintext, err := x509.DecryptPEMBlock(block, keyBlock.Bytes)
+	if err != nil {
+		return "", errors.Wrapf(err, "Cannot decrypt value using key file %s", keyFile)
+	}
+
+	return string(plaintext), nil
+}
+
+// findPEMBlock returns the first PEM block of data having blockType, nil when there is none
+func findPEMBlock(data []byte, blockType string) *pem.Block {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil
+		}
+		if block.Type == blockType {
+			return block
+		}
+		data = rest
+	}
+}
@@ -0,0 +1,98 @@
+package io
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encrypt encrypts value as oc adm ca encrypt does, returning the encrypted value and the key file content
+func encrypt(t *testing.T, value string) ([]byte, []byte) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	block, err := x509.EncryptPEMBlock(rand.Reader, encryptedBlockType, []byte(value), key, x509.PEMCipherAES256)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(block), pem.EncodeToMemory(&pem.Block{Type: encryptingKeyBlockType, Bytes: key})
+}
+
+func TestFetchEncryptedStringSource(t *testing.T) {
+	encrypted, key := encrypt(t, "bind password")
+	_, otherKey := encrypt(t, "other")
+
+	files := map[string][]byte{
+		"/etc/origin/master/bindPassword.encrypted": encrypted,
+		"/etc/origin/master/bindPassword.key":       key,
+		"/etc/origin/master/other.key":              otherKey,
+		"/etc/origin/master/plain":                  []byte("bind password\n"),
+	}
+	savedFetchFile := FetchFile
+	defer func() { FetchFile = savedFetchFile }()
+	FetchFile = func(src string) ([]byte, error) {
+		if content, ok := files[src]; ok {
+			return content, nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	testCases := []struct {
+		name        string
+		source      legacyconfigv1.StringSource
+		expected    string
+		expectedErr string
+	}{
+		{
+			name: "decrypt file",
+			source: legacyconfigv1.StringSource{StringSourceSpec: legacyconfigv1.StringSourceSpec{
+				File: "/etc/origin/master/bindPassword.encrypted", KeyFile: "/etc/origin/master/bindPassword.key"}},
+			expected: "bind password",
+		},
+		{
+			name: "decrypt inline value",
+			source: legacyconfigv1.StringSource{StringSourceSpec: legacyconfigv1.StringSourceSpec{
+				Value: string(encrypted), KeyFile: "/etc/origin/master/bindPassword.key"}},
+			expected: "bind password",
+		},
+		{
+			name: "wrong key",
+			source: legacyconfigv1.StringSource{StringSourceSpec: legacyconfigv1.StringSourceSpec{
+				File: "/etc/origin/master/bindPassword.encrypted", KeyFile: "/etc/origin/master/other.key"}},
+			expectedErr: "Cannot decrypt file /etc/origin/master/bindPassword.encrypted: Cannot decrypt value using key file /etc/origin/master/other.key",
+		},
+		{
+			name: "missing key file",
+			source: legacyconfigv1.StringSource{StringSourceSpec: legacyconfigv1.StringSourceSpec{
+				File: "/etc/origin/master/bindPassword.encrypted", KeyFile: "/etc/origin/master/missing.key"}},
+			expectedErr: "Cannot decrypt file /etc/origin/master/bindPassword.encrypted: Cannot read key file /etc/origin/master/missing.key",
+		},
+		{
+			name: "value not encrypted",
+			source: legacyconfigv1.StringSource{StringSourceSpec: legacyconfigv1.StringSourceSpec{
+				File: "/etc/origin/master/plain", KeyFile: "/etc/origin/master/bindPassword.key"}},
+			expectedErr: "Cannot decrypt file /etc/origin/master/plain: Value holds no ENCRYPTED STRING PEM block",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := FetchStringSource(tc.source)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				assert.NotContains(t, err.Error(), "bind password")
+				assert.Empty(t, value)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, value)
+			}
+		})
+	}
+}
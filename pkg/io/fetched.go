@@ -0,0 +1,167 @@
+package io
+
+import (
+	"os"
+	"sort"
+	"sync"
+)
+
+// States of a file fetched from the source cluster
+const (
+	// FileFetched is the state of a file read and saved
+	FileFetched = "fetched"
+	// FileEmpty is the state of a file which exists but is empty
+	FileEmpty = "empty"
+	// FileMissing is the state of a file which doesn't exist
+	FileMissing = "missing"
+	// FileDenied is the state of a file the become user isn't allowed to read, or privileges couldn't be escalated
+	FileDenied = "denied"
+	// FileFailed is the state of a file which couldn't be read
+	FileFailed = "failed"
+)
+
+// FileRecord describes a file fetched from the source cluster, attributes are set when it could be read
+type FileRecord struct {
+	Host   string
+	Path   string
+	State  string
+	Size   int64
+	Mode   os.FileMode
+	UID    uint32
+	GID    uint32
+	SHA256 string
+	Error  string
+}
+
+var fetched struct {
+	sync.Mutex
+	records map[string]FileRecord
+	// secrets holds paths of files holding secrets, their checksum isn't recorded
+	secrets map[string]bool
+}
+
+// recordFetch records the outcome of a fetch, replacing the previous one of the same file
+func recordFetch(record FileRecord) {
+	fetched.Lock()
+	defer fetched.Unlock()
+
+	if fetched.records == nil {
+		fetched.records = make(map[string]FileRecord)
+	}
+	if fetched.secrets[record.Path] {
+		record.SHA256 = ""
+	}
+	fetched.records[record.Host+":"+record.Path] = record
+}
+
+// recordSecret drops the checksum of file from its records, now and on later fetches. An unsalted checksum of
+// a secret with little entropy, such as a password, would reveal it.
+func recordSecret(file string) {
+	fetched.Lock()
+	defer fetched.Unlock()
+
+	if fetched.secrets == nil {
+		fetched.secrets = make(map[string]bool)
+	}
+	fetched.secrets[file] = true
+
+	for key, record := range fetched.records {
+		if record.Path == file {
+			record.SHA256 = ""
+			fetched.records[key] = record
+		}
+	}
+}
+
+// FetchedFiles returns records of files fetched from the source cluster, sorted by host and path
+func FetchedFiles() []FileRecord {
+	fetched.Lock()
+	defer fetched.Unlock()
+
+	records := make([]FileRecord, 0, len(fetched.records))
+	for _, record := range fetched.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Host != records[j].Host {
+			return records[i].Host < records[j].Host
+		}
+		return records[i].Path < records[j].Path
+	})
+
+	return records
+}
+
+// ResetFetchedFiles forgets files fetched so far
+func ResetFetchedFiles() {
+	fetched.Lock()
+	defer fetched.Unlock()
+
+	fetched.records = nil
+	fetched.secrets = nil
+}
+
+// States of an env variable looked up in the master service environment
+const (
+	// EnvResolved is the state of a variable found in the master service environment, or locally
+	EnvResolved = "resolved"
+	// EnvUnresolved is the state of a variable which isn't set
+	EnvUnresolved = "unresolved"
+)
+
+// localEnvSource is the source of variables read from the environment of CPMA
+const localEnvSource = "local environment"
+
+// EnvRecord describes an env variable looked up in the master service environment, Source tells where it was found
+type EnvRecord struct {
+	Host   string
+	Name   string
+	State  string
+	Source string
+}
+
+var envs struct {
+	sync.Mutex
+	records map[string]EnvRecord
+}
+
+// recordEnv records the outcome of an env variable lookup, replacing the previous one of the same variable
+func recordEnv(record EnvRecord) {
+	envs.Lock()
+	defer envs.Unlock()
+
+	if envs.records == nil {
+		envs.records = make(map[string]EnvRecord)
+	}
+	envs.records[record.Host+":"+record.Name] = record
+}
+
+// FetchedEnv returns records of env variables looked up, sorted by host and name
+func FetchedEnv() []EnvRecord {
+	envs.Lock()
+	defer envs.Unlock()
+
+	records := make([]EnvRecord, 0, len(envs.records))
+	for _, record := range envs.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Host != records[j].Host {
+			return records[i].Host < records[j].Host
+		}
+		return records[i].Name < records[j].Name
+	})
+
+	return records
+}
+
+// ResetFetchedEnv forgets env variables looked up so far, along with the master service environment read
+func ResetFetchedEnv() {
+	envs.Lock()
+	envs.records = nil
+	envs.Unlock()
+
+	serviceEnvs.Lock()
+	serviceEnvs.hosts = nil
+	serviceEnvs.Unlock()
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/konveyor/cpma/pkg/env"
@@ -18,13 +19,20 @@ import (
 // FetchFile first tries to retrieve file from local disk (workDir/<Hostname>/).
 // If it fails then connects to Hostname to retrieve file and stores it locally
 // To force a network connection remove workDir/... prior to exec.
+// When config source is archive, file is read from the archive instead.
+// When config source is node-exec, file is read through a debug pod on the node instead.
 var FetchFile = func(src string) ([]byte, error) {
 	var f []byte
 	var err error
 
-	if env.Config().GetBool("FetchFromRemote") {
+	switch {
+	case env.Config().GetBool("FetchFromRemote"):
 		f, err = fetchFromRemote(src)
-	} else {
+	case env.Config().GetString("ConfigSource") == "node-exec":
+		f, err = fetchFromNode(src)
+	case env.Config().GetString("ConfigSource") == "archive":
+		f, err = FetchFromArchive(src)
+	default:
 		f, err = FetchFromLocal(src)
 	}
 
@@ -32,42 +40,51 @@ var FetchFile = func(src string) ([]byte, error) {
 }
 
 func fetchFromRemote(src string) ([]byte, error) {
+	return fetchWith(remotehost.ReadRemoteFile, src)
+}
+
+// fetchWith reads src on Hostname using read, records the outcome and saves the file locally
+func fetchWith(read func(host, file string) (*remotehost.File, error), src string) ([]byte, error) {
 	host := env.Config().GetString("Hostname")
 	dst := filepath.Join(host, src)
 
 	logrus.Debugf("Fetching Remote File %s:%s", host, src)
-	cmd := fmt.Sprintf("sudo sh -c 'if [[ ! -f %s ]]; then echo not-found; fi'", src)
-	output0, err := remotehost.RunCMD(host, cmd)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Error accessing file %s", dst)
+	file, err := read(host, src)
+	if os.IsNotExist(errors.Cause(err)) {
+		recordFetch(FileRecord{Host: host, Path: src, State: FileMissing})
+		return nil, errors.Errorf("File %s not found", dst)
 	}
-
-	if output0 == "not-found" {
-		msg := fmt.Sprintf("File %s not found", dst)
-		return nil, errors.New(msg)
+	if remotehost.IsPermissionDenied(err) {
+		recordFetch(FileRecord{Host: host, Path: src, State: FileDenied, Error: err.Error()})
+		return nil, errors.Wrapf(err, "Permission denied reading file %s", dst)
 	}
-
-	cmd = fmt.Sprintf("sudo cat %s", src)
-	output, err := remotehost.RunCMD(host, cmd)
 	if err != nil {
+		recordFetch(FileRecord{Host: host, Path: src, State: FileFailed, Error: err.Error()})
 		return nil, errors.Wrapf(err, "Error accessing file %s", dst)
 	}
 
-	if output == "" {
-		msg := fmt.Sprintf("Empty file: %s", dst)
-		return nil, errors.New(msg)
+	record := FileRecord{
+		Host:   host,
+		Path:   src,
+		State:  FileFetched,
+		Size:   file.Size,
+		Mode:   file.Mode,
+		UID:    file.UID,
+		GID:    file.GID,
+		SHA256: file.SHA256,
+	}
+	if len(file.Content) == 0 {
+		logrus.Warnf("File %s is empty", dst)
+		record.State = FileEmpty
 	}
+	recordFetch(record)
 
-	if err := WriteFile([]byte(output), dst); err != nil {
+	if err := WriteFile(file.Content, dst); err != nil {
 		logrus.Errorf("Unable to save file: %s", dst)
 		return nil, err
 	}
 
-	netFile, err := ReadFile(dst)
-	if err != nil {
-		return nil, err
-	}
-	return netFile, nil
+	return file.Content, nil
 }
 
 // FetchFromLocal retrieve file from local WorkDir
@@ -85,52 +102,94 @@ func FetchFromLocal(src string) ([]byte, error) {
 	return nil, errors.New(msg)
 }
 
-// FetchEnv Fetch env vars from either the source cluster or localhost
+// envVarName matches names of environment variables which can be expanded by a shell
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// FetchEnv Fetch env vars from the environment of the master service on host, read from its environment files,
+// systemd units and static pod manifests. In local and archive modes, variables missing from copies of those files
+// are read from the local environment. Where each variable was found is recorded.
 func FetchEnv(host, envVar string) (string, error) {
-	var output string
+	if !envVarName.MatchString(envVar) {
+		return "", errors.Errorf("Invalid env variable name %q", envVar)
+	}
 
-	if env.Config().GetBool("FetchFromRemote") {
-		var err error
-		cmd := fmt.Sprintf("printf $%s", envVar)
-		output, err = remotehost.RunCMD(host, cmd)
-		if err != nil {
-			return "", errors.Wrap(err, "Can't fetch env variable")
-		}
-	} else {
-		output = os.Getenv(envVar)
+	value, source, ok := masterServiceEnv(host).lookup(envVar)
+	if !ok && !canRunCommands() {
+		value, ok = os.LookupEnv(envVar)
+		source = localEnvSource
+	}
+	if !ok {
+		recordEnv(EnvRecord{Host: host, Name: envVar, State: EnvUnresolved})
+		return "", errors.Errorf("Env variable %s isn't set in the environment of the master service on %s", envVar, host)
 	}
 
-	logrus.Debugf("Env:loaded: %s", envVar)
+	recordEnv(EnvRecord{Host: host, Name: envVar, State: EnvResolved, Source: source})
+	logrus.Debugf("Env:loaded: %s from %s", envVar, source)
 
-	return output, nil
+	return value, nil
 }
 
-// FetchStringSource fetches a string from an source cluster
+// FetchStringSource fetches a string from an source cluster. A value encrypted by oc adm ca encrypt is decrypted
+// using KeyFile, the plaintext must only be written to secrets.
 func FetchStringSource(stringSource legacyconfigv1.StringSource) (string, error) {
+	value := fetchStringSourceValue(stringSource)
+	if value == "" || stringSource.KeyFile == "" {
+		return value, nil
+	}
+
+	plaintext, err := decryptStringSource([]byte(value), stringSource.KeyFile)
+	if err != nil {
+		return "", errors.Wrapf(err, "Cannot decrypt %s", describeStringSource(stringSource))
+	}
+
+	return plaintext, nil
+}
+
+// fetchStringSourceValue returns the value of a string source as found, an empty string when it can't be fetched
+func fetchStringSourceValue(stringSource legacyconfigv1.StringSource) string {
 	if stringSource.Value != "" {
-		return stringSource.Value, nil
+		return stringSource.Value
 	}
 
 	if stringSource.File != "" {
-		fileContent, err := FetchFile(stringSource.File)
+		fileContent, err := FetchSecretFile(stringSource.File)
 		if err != nil {
-			return "", nil
+			return ""
 		}
 
-		fileString := strings.TrimSuffix(string(fileContent), "\n")
-		return fileString, nil
+		return strings.TrimSuffix(string(fileContent), "\n")
 	}
 
 	if stringSource.Env != "" {
 		env, err := FetchEnv(env.Config().GetString("Hostname"), stringSource.Env)
 		if err != nil {
-			return "", nil
+			logrus.Warnf("Unable to resolve env variable: %s", err)
+			return ""
 		}
 
-		return env, nil
+		return env
 	}
 
-	return "", nil
+	return ""
+}
+
+// FetchSecretFile fetches a file holding a secret, such as a password or a private key, its checksum is left out
+// of fetched file records
+func FetchSecretFile(src string) ([]byte, error) {
+	recordSecret(src)
+	return FetchFile(src)
+}
+
+// describeStringSource tells where the value of a string source comes from, without its value
+func describeStringSource(stringSource legacyconfigv1.StringSource) string {
+	switch {
+	case stringSource.Value != "":
+		return "inline value"
+	case stringSource.File != "":
+		return "file " + stringSource.File
+	default:
+		return "env variable " + stringSource.Env
+	}
 }
 
 // ReadFile reads a file from WorkDir and returns its contents
@@ -1,6 +1,7 @@
 package io
 
 import (
+	"errors"
 	"os"
 	"testing"
 
@@ -14,23 +15,85 @@ import (
 // Save before overriding
 var _RunCMD = remotehost.RunCMD
 
-// Overriding
+// Overriding, commands have no output: no environment file or unit is found
 func mockRunCMD(hostname, cmd string) (string, error) {
-	return "remote value", nil
+	return "", nil
+}
+
+// Save before overriding
+var _ReadRemoteFile = remotehost.ReadRemoteFile
+
+// Overriding
+func mockReadRemoteFile(hostname, file string) (*remotehost.File, error) {
+	switch file {
+	case "testdata/missing-file":
+		return nil, os.ErrNotExist
+	case "testdata/denied-file":
+		return nil, &remotehost.PermissionError{Host: "master0", Reason: "root can't read " + file}
+	case "testdata/failed-file":
+		return nil, errors.New("connection lost")
+	case "testdata/empty-file":
+		return &remotehost.File{Path: file, Content: []byte{}, Mode: 0600, SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}, nil
+	}
+
+	return &remotehost.File{Path: file, Content: []byte("remote value"), Size: 12, Mode: 0640, UID: 0, GID: 994, SHA256: "checksum"}, nil
 }
 
 func TestFetchFile(t *testing.T) {
 	testCases := []struct {
-		name     string
-		remote   bool
-		expected string
-		filename string
+		name           string
+		remote         bool
+		expected       string
+		expectedErr    string
+		expectedRecord *FileRecord
+		filename       string
 	}{
 		{
 			name:     "Fetch from remote",
 			remote:   true,
 			filename: "testdata/remote-file",
 			expected: "remote value",
+			expectedRecord: &FileRecord{
+				Path:   "testdata/remote-file",
+				State:  FileFetched,
+				Size:   12,
+				Mode:   0640,
+				GID:    994,
+				SHA256: "checksum",
+			},
+		},
+		{
+			name:     "Fetch empty file from remote",
+			remote:   true,
+			filename: "testdata/empty-file",
+			expected: "",
+			expectedRecord: &FileRecord{
+				Path:   "testdata/empty-file",
+				State:  FileEmpty,
+				Mode:   0600,
+				SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			},
+		},
+		{
+			name:           "Fetch missing file from remote",
+			remote:         true,
+			filename:       "testdata/missing-file",
+			expectedErr:    "File testdata/missing-file not found",
+			expectedRecord: &FileRecord{Path: "testdata/missing-file", State: FileMissing},
+		},
+		{
+			name:           "Fetch unreadable file from remote",
+			remote:         true,
+			filename:       "testdata/denied-file",
+			expectedErr:    "Permission denied reading file testdata/denied-file: permission denied on master0: root can't read testdata/denied-file",
+			expectedRecord: &FileRecord{Path: "testdata/denied-file", State: FileDenied, Error: "permission denied on master0: root can't read testdata/denied-file"},
+		},
+		{
+			name:           "Fetch file from remote failing",
+			remote:         true,
+			filename:       "testdata/failed-file",
+			expectedErr:    "Error accessing file testdata/failed-file: connection lost",
+			expectedRecord: &FileRecord{Path: "testdata/failed-file", State: FileFailed, Error: "connection lost"},
 		},
 		{
 			name:     "Fetch from local",
@@ -42,37 +105,52 @@ func TestFetchFile(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
+			ResetFetchedFiles()
 			env.Config().Set("FetchFromRemote", tc.remote)
 			if tc.remote {
-				defer func() { remotehost.RunCMD = _RunCMD }()
-				defer func() {
-					if err := os.Remove(tc.filename); err != nil {
-						t.Logf("Cannot remove file: %s", tc.filename)
-					}
-				}()
-				remotehost.RunCMD = mockRunCMD
+				defer func() { remotehost.ReadRemoteFile = _ReadRemoteFile }()
+				defer os.Remove(tc.filename)
+				remotehost.ReadRemoteFile = mockReadRemoteFile
 			}
 			f, err := FetchFile(tc.filename)
-			require.NoError(t, err)
-			assert.Equal(t, f, []byte(tc.expected))
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, []byte(tc.expected), f)
+			}
+
+			if tc.expectedRecord != nil {
+				assert.Equal(t, []FileRecord{*tc.expectedRecord}, FetchedFiles())
+			} else {
+				assert.Empty(t, FetchedFiles())
+			}
 		})
 	}
 }
 
 func TestFetchEnv(t *testing.T) {
 	testCases := []struct {
-		name     string
-		host     string
-		env      string
-		expected string
-		remote   bool
+		name        string
+		host        string
+		env         string
+		expected    string
+		expectedErr string
+		remote      bool
 	}{
 		{
-			name:     "Fetch remote ENV variable",
-			host:     "remote.test.com",
-			env:      "CPMA_TEST_ENV",
-			expected: "remote value",
-			remote:   true,
+			name:        "Fetch remote ENV variable missing from the master service environment",
+			host:        "remote.test.com",
+			env:         "CPMA_TEST_ENV",
+			remote:      true,
+			expectedErr: "Env variable CPMA_TEST_ENV isn't set in the environment of the master service on remote.test.com",
+		},
+		{
+			name:        "Fetch remote ENV variable with invalid name",
+			host:        "remote.test.com",
+			env:         "HOME; rm -rf /",
+			remote:      true,
+			expectedErr: `Invalid env variable name "HOME; rm -rf /"`,
 		},
 		{
 			name:     "Fetch local ENV variable",
@@ -94,14 +172,53 @@ func TestFetchEnv(t *testing.T) {
 
 			defer func() { remotehost.RunCMD = _RunCMD }()
 			remotehost.RunCMD = mockRunCMD
+			defer func() { remotehost.ReadRemoteFile = _ReadRemoteFile }()
+			remotehost.ReadRemoteFile = mockReadRemoteFile
+			ResetFetchedEnv()
+			defer ResetFetchedEnv()
 
 			env, err := FetchEnv(tc.host, tc.env)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
 			require.NoError(t, err)
 			assert.Equal(t, env, tc.expected)
 		})
 	}
 }
 
+func TestStringSourceFileChecksum(t *testing.T) {
+	ResetFetchedFiles()
+	defer ResetFetchedFiles()
+	env.Config().Set("FetchFromRemote", true)
+	defer env.Config().Set("FetchFromRemote", false)
+	defer func() { remotehost.ReadRemoteFile = _ReadRemoteFile }()
+	remotehost.ReadRemoteFile = mockReadRemoteFile
+	defer os.Remove("testdata/remote-file")
+	defer os.Remove("testdata/bindPassword")
+
+	_, err := FetchFile("testdata/remote-file")
+	require.NoError(t, err)
+
+	// The checksum of a secret would reveal a password with little entropy
+	stringSource := legacyconfigv1.StringSource{}
+	stringSource.File = "testdata/bindPassword"
+	value, err := FetchStringSource(stringSource)
+	require.NoError(t, err)
+	assert.Equal(t, "remote value", value)
+
+	assert.Equal(t, []FileRecord{
+		{Path: "testdata/bindPassword", State: FileFetched, Size: 12, Mode: 0640, GID: 994},
+		{Path: "testdata/remote-file", State: FileFetched, Size: 12, Mode: 0640, GID: 994, SHA256: "checksum"},
+	}, FetchedFiles())
+
+	// Fetching it again as a plain file doesn't record its checksum either
+	_, err = FetchFile("testdata/bindPassword")
+	require.NoError(t, err)
+	assert.Empty(t, FetchedFiles()[0].SHA256)
+}
+
 func TestStringSource(t *testing.T) {
 	testCases := []struct {
 		name     string
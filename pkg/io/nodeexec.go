@@ -0,0 +1,310 @@
+package io
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	goio "io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/konveyor/cpma/pkg/api"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io/remotehost"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// debugContainer is the container of debug pods, the host filesystem is mounted on /host
+const debugContainer = "debug"
+
+// debugPodLifetime is how long a debug pod runs, a pod left behind by an interrupted run stops by itself
+const debugPodLifetime = 3600
+
+// nodeReadScript prints size, permissions, owner and group IDs of file $1 on a line, followed by its content
+const nodeReadScript = `if [ -d "$1" ]; then echo "$1: Is a directory" >&2; exit 1; fi; stat -L -c '%s %a %u %g' -- "$1" && cat -- "$1"`
+
+// Debug pods are polled until they run, for debugPodTimeout at most
+var (
+	debugPodTimeout      = 2 * time.Minute
+	debugPodPollInterval = time.Second
+)
+
+// debugPods holds debug pods started to read files, by node
+var debugPods struct {
+	sync.Mutex
+	pods map[string]*nodeDebugPod
+}
+
+// nodeDebugPod is the debug pod of a node, its lock is held while the pod starts
+type nodeDebugPod struct {
+	sync.Mutex
+	name string
+}
+
+func init() {
+	// Debug pods are privileged, don't leave them behind when exiting on a fatal error
+	logrus.RegisterExitHandler(CloseDebugPods)
+}
+
+func fetchFromNode(src string) ([]byte, error) {
+	return fetchWith(readNodeFile, src)
+}
+
+// readNodeFile reads file on node through its debug pod, a missing file returns an error satisfying os.IsNotExist
+func readNodeFile(node, file string) (*remotehost.File, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := execOnNode(node, []string{"sh", "-c", nodeReadScript, "sh", file}, stdout, stderr); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		switch {
+		case strings.Contains(message, "No such file or directory"):
+			return nil, &os.PathError{Op: "stat", Path: file, Err: os.ErrNotExist}
+		case strings.Contains(message, "Is a directory"):
+			return nil, errors.Errorf("%s is a directory", file)
+		case strings.Contains(message, "Permission denied"):
+			return nil, &remotehost.PermissionError{Host: node, Reason: message}
+		case message != "":
+			return nil, errors.Errorf("Cannot read %s on node %s: %s", file, node, message)
+		}
+		return nil, errors.Wrapf(err, "Cannot read %s on node %s", file, node)
+	}
+
+	attributes, err := stdout.ReadString('\n')
+	if err != nil {
+		return nil, errors.Errorf("Cannot read attributes of %s on node %s", file, node)
+	}
+
+	var (
+		size     int64
+		perm     uint32
+		uid, gid uint32
+	)
+	if _, err := fmt.Sscanf(attributes, "%d %o %d %d", &size, &perm, &uid, &gid); err != nil {
+		return nil, errors.Wrapf(err, "Cannot parse attributes of %s on node %s", file, node)
+	}
+
+	content := stdout.Bytes()
+	checksum := sha256.Sum256(content)
+	return &remotehost.File{
+		Path:    file,
+		Content: content,
+		Size:    size,
+		Mode:    os.FileMode(perm) & os.ModePerm,
+		UID:     uid,
+		GID:     gid,
+		SHA256:  hex.EncodeToString(checksum[:]),
+	}, nil
+}
+
+// runOnNode runs cmd using a shell on node through its debug pod and returns its output
+func runOnNode(node, cmd string) (string, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := execOnNode(node, []string{"sh", "-c", cmd}, stdout, stderr); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.Errorf("%s failed on node %s: %s", cmd, node, message)
+		}
+		return "", errors.Wrapf(err, "%s failed on node %s", cmd, node)
+	}
+
+	return stdout.String(), nil
+}
+
+// execOnNode runs command on node, chrooted to the host filesystem
+func execOnNode(node string, command []string, stdout, stderr goio.Writer) error {
+	pod, err := debugPod(node)
+	if err != nil {
+		return err
+	}
+
+	return api.ExecInPod(debugNamespace(), pod, debugContainer, append([]string{"chroot", "/host"}, command...), stdout, stderr)
+}
+
+// debugPod returns the debug pod of node, started on first use. Pods of different nodes start concurrently.
+func debugPod(node string) (string, error) {
+	debugPods.Lock()
+	if debugPods.pods == nil {
+		debugPods.pods = make(map[string]*nodeDebugPod)
+	}
+	pod, ok := debugPods.pods[node]
+	if !ok {
+		pod = &nodeDebugPod{}
+		debugPods.pods[node] = pod
+	}
+	debugPods.Unlock()
+
+	pod.Lock()
+	defer pod.Unlock()
+
+	if pod.name != "" {
+		return pod.name, nil
+	}
+
+	name, err := startDebugPod(node)
+	if err != nil {
+		return "", err
+	}
+
+	pod.name = name
+	return name, nil
+}
+
+// startDebugPod starts a privileged pod on node, like oc debug node does, and waits for it to run
+func startDebugPod(node string) (string, error) {
+	if api.K8sClient == nil {
+		return "", errors.New("Kubernetes API client isn't initialized, node-exec needs a live cluster")
+	}
+
+	image := env.Config().GetString("DebugImage")
+	if image == "" {
+		image = env.DefaultDebugImage
+	}
+
+	pods := api.K8sClient.CoreV1().Pods(debugNamespace())
+	pod, err := pods.Create(debugPodSpec(node, image))
+	if err != nil {
+		return "", errors.Wrapf(err, "Cannot start debug pod on node %s", node)
+	}
+	logrus.Infof("Debug pod %s/%s started on node %s", pod.Namespace, pod.Name, node)
+	name := pod.Name
+
+	deadline := time.Now().Add(debugPodTimeout)
+	for {
+		pod, err = pods.Get(name, metav1.GetOptions{})
+		if err != nil {
+			deleteDebugPod(name)
+			return "", errors.Wrapf(err, "Cannot get debug pod on node %s", node)
+		}
+
+		switch pod.Status.Phase {
+		case corev1.PodRunning:
+			return pod.Name, nil
+		case corev1.PodSucceeded, corev1.PodFailed:
+			deleteDebugPod(pod.Name)
+			return "", errors.Errorf("Debug pod %s/%s on node %s stopped: %s", pod.Namespace, pod.Name, node, podWaitReason(pod))
+		}
+
+		if time.Now().After(deadline) {
+			deleteDebugPod(pod.Name)
+			return "", errors.Errorf("Debug pod %s/%s on node %s isn't running after %s: %s", pod.Namespace, pod.Name, node, debugPodTimeout, podWaitReason(pod))
+		}
+		time.Sleep(debugPodPollInterval)
+	}
+}
+
+// debugPodSpec returns a privileged pod sharing the host namespaces of node, the host filesystem is mounted on /host
+func debugPodSpec(node, image string) *corev1.Pod {
+	privileged := true
+	root := int64(0)
+	lifetime := int64(debugPodLifetime)
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "cpma-debug-",
+			Labels:       map[string]string{"app": "cpma-debug"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:              node,
+			HostPID:               true,
+			HostNetwork:           true,
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: &lifetime,
+			// Masters are usually tainted
+			Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			Containers: []corev1.Container{{
+				Name:    debugContainer,
+				Image:   image,
+				Command: []string{"sleep", fmt.Sprint(debugPodLifetime)},
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: &privileged,
+					RunAsUser:  &root,
+				},
+				VolumeMounts: []corev1.VolumeMount{{Name: "host", MountPath: "/host"}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name:         "host",
+				VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/"}},
+			}},
+		},
+	}
+}
+
+// podWaitReason explains why the container of pod isn't running
+func podWaitReason(pod *corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if waiting := status.State.Waiting; waiting != nil {
+			return strings.TrimSpace(waiting.Reason + " " + waiting.Message)
+		}
+		if terminated := status.State.Terminated; terminated != nil {
+			return strings.TrimSpace(terminated.Reason + " " + terminated.Message)
+		}
+	}
+
+	if pod.Status.Reason != "" || pod.Status.Message != "" {
+		return strings.TrimSpace(pod.Status.Reason + " " + pod.Status.Message)
+	}
+	return string(pod.Status.Phase)
+}
+
+// CloseDebugPods deletes debug pods started to read files, waiting for pods being started
+func CloseDebugPods() {
+	debugPods.Lock()
+	defer debugPods.Unlock()
+
+	for node, pod := range debugPods.pods {
+		pod.Lock()
+		if pod.name != "" {
+			deleteDebugPod(pod.name)
+		}
+		pod.Unlock()
+		delete(debugPods.pods, node)
+	}
+}
+
+// CloseDebugPodsOnInterrupt deletes debug pods and exits when interrupted, until the returned function is called
+func CloseDebugPodsOnInterrupt() func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			// Interrupting again exits right away
+			signal.Stop(signals)
+			logrus.Warnf("Received %s, deleting debug pods", sig)
+			CloseDebugPods()
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+func deleteDebugPod(name string) {
+	gracePeriod := int64(0)
+	err := api.K8sClient.CoreV1().Pods(debugNamespace()).Delete(name, &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+	if err != nil {
+		logrus.Warnf("Unable to delete debug pod %s/%s: %s", debugNamespace(), name, err)
+		return
+	}
+	logrus.Debugf("Debug pod %s/%s deleted", debugNamespace(), name)
+}
+
+func debugNamespace() string {
+	if namespace := env.Config().GetString("DebugNamespace"); namespace != "" {
+		return namespace
+	}
+	return env.DefaultDebugNamespace
+}
@@ -0,0 +1,246 @@
+package io
+
+import (
+	"encoding/json"
+	"fmt"
+	goio "io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/konveyor/cpma/pkg/api"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// testPodsAPI serves pods of a namespace, a pod runs once it was polled pendingPolls times.
+// Getting pods fails when getError is set, and getting the pod of a node in hold waits until its channel is closed.
+type testPodsAPI struct {
+	mu           sync.Mutex
+	pendingPolls int
+	waiting      *corev1.ContainerStateWaiting
+	getError     bool
+	hold         map[string]chan struct{}
+	created      []*corev1.Pod
+	polls        int
+	deleted      []string
+}
+
+func (a *testPodsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodPost:
+		pod := &corev1.Pod{}
+		json.NewDecoder(r.Body).Decode(pod)
+		pod.Name = pod.GenerateName + "abcde"
+		if len(a.created) > 0 {
+			pod.Name += fmt.Sprint(len(a.created))
+		}
+		pod.Namespace = "cpma"
+		pod.Status.Phase = corev1.PodPending
+		a.created = append(a.created, pod)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(pod)
+	case http.MethodGet:
+		if a.getError {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(metav1.Status{Status: metav1.StatusFailure, Message: "etcd is unavailable"})
+			return
+		}
+
+		var pod *corev1.Pod
+		for _, created := range a.created {
+			if created.Name == filepath.Base(r.URL.Path) {
+				pod = created.DeepCopy()
+			}
+		}
+		if hold, ok := a.hold[pod.Spec.NodeName]; ok {
+			a.mu.Unlock()
+			<-hold
+			a.mu.Lock()
+		}
+
+		a.polls++
+		if a.polls > a.pendingPolls {
+			pod.Status.Phase = corev1.PodRunning
+		} else if a.waiting != nil {
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{State: corev1.ContainerState{Waiting: a.waiting}}}
+		}
+		json.NewEncoder(w).Encode(pod)
+	case http.MethodDelete:
+		a.deleted = append(a.deleted, filepath.Base(r.URL.Path))
+		json.NewEncoder(w).Encode(metav1.Status{Status: metav1.StatusSuccess})
+	}
+}
+
+// setNodeExec serves pods using podsAPI and runs commands locally instead of in pods, the returned function
+// restores previous values
+func setNodeExec(t *testing.T, podsAPI *testPodsAPI) func() {
+	server := httptest.NewServer(podsAPI)
+
+	savedClient, savedExec := api.K8sClient, api.ExecInPod
+	savedTimeout, savedInterval := debugPodTimeout, debugPodPollInterval
+	api.K8sClient = kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+	api.ExecInPod = func(namespace, pod, container string, command []string, stdout, stderr goio.Writer) error {
+		assert.Equal(t, "cpma", namespace)
+		assert.Equal(t, "cpma-debug-abcde", pod)
+		assert.Equal(t, debugContainer, container)
+		require.Equal(t, []string{"chroot", "/host"}, command[:2])
+
+		cmd := exec.Command(command[2], command[3:]...)
+		cmd.Stdout, cmd.Stderr = stdout, stderr
+		return cmd.Run()
+	}
+	debugPodTimeout, debugPodPollInterval = 50*time.Millisecond, time.Millisecond
+
+	env.Config().Set("ConfigSource", "node-exec")
+	env.Config().Set("FetchFromRemote", false)
+	env.Config().Set("Hostname", "node1")
+	env.Config().Set("DebugNamespace", "cpma")
+
+	return func() {
+		CloseDebugPods()
+		server.Close()
+		api.K8sClient, api.ExecInPod = savedClient, savedExec
+		debugPodTimeout, debugPodPollInterval = savedTimeout, savedInterval
+		env.Config().Set("ConfigSource", "")
+		env.Config().Set("Hostname", "")
+		env.Config().Set("DebugNamespace", "")
+	}
+}
+
+func TestFetchFileFromNode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-node-exec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "master-config.yaml")
+	require.NoError(t, ioutil.WriteFile(file, []byte("kind: MasterConfig\n"), 0640))
+	require.NoError(t, os.Chmod(file, 0640))
+
+	podsAPI := &testPodsAPI{pendingPolls: 2}
+	defer setNodeExec(t, podsAPI)()
+	env.Config().Set("WorkDir", dir)
+	defer env.Config().Set("WorkDir", "")
+	ResetFetchedFiles()
+	defer ResetFetchedFiles()
+
+	content, err := FetchFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "kind: MasterConfig\n", string(content))
+	saved, err := ioutil.ReadFile(filepath.Join(dir, "node1", file))
+	require.NoError(t, err)
+	assert.Equal(t, content, saved)
+
+	_, err = FetchFile(filepath.Join(dir, "missing"))
+	assert.EqualError(t, err, "File "+filepath.Join("node1", dir, "missing")+" not found")
+
+	_, err = FetchFile(dir)
+	assert.EqualError(t, err, "Error accessing file "+filepath.Join("node1", dir)+": "+dir+" is a directory")
+
+	assert.Equal(t, []FileRecord{
+		{Host: "node1", Path: dir, State: FileFailed, Error: dir + " is a directory"},
+		{Host: "node1", Path: filepath.Join(dir, "master-config.yaml"), State: FileFetched, Size: 19, Mode: 0640,
+			UID: uint32(os.Getuid()), GID: uint32(os.Getgid()), SHA256: "7e2c0e1217a6d6d9e9e5ee9053a18d46547a4368292ec73bfe85957fff2a2909"},
+		{Host: "node1", Path: filepath.Join(dir, "missing"), State: FileMissing},
+	}, FetchedFiles())
+
+	// Commands are run through the debug pod as well
+	output, err := runOnHost("node1", "printf $HOME")
+	require.NoError(t, err)
+	assert.Equal(t, os.Getenv("HOME"), output)
+
+	// A single debug pod is started on the node, sharing its namespaces
+	require.Len(t, podsAPI.created, 1)
+	pod := podsAPI.created[0]
+	assert.Equal(t, "node1", pod.Spec.NodeName)
+	assert.True(t, pod.Spec.HostPID)
+	assert.Equal(t, env.DefaultDebugImage, pod.Spec.Containers[0].Image)
+	assert.True(t, *pod.Spec.Containers[0].SecurityContext.Privileged)
+	assert.Equal(t, "/", pod.Spec.Volumes[0].HostPath.Path)
+	assert.Equal(t, "/host", pod.Spec.Containers[0].VolumeMounts[0].MountPath)
+
+	CloseDebugPods()
+	assert.Equal(t, []string{"cpma-debug-abcde"}, podsAPI.deleted)
+}
+
+func TestDebugPodNotRunning(t *testing.T) {
+	podsAPI := &testPodsAPI{
+		pendingPolls: 1000,
+		waiting:      &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"},
+	}
+	defer setNodeExec(t, podsAPI)()
+	ResetFetchedFiles()
+	defer ResetFetchedFiles()
+
+	_, err := FetchFile("/etc/origin/master/master-config.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Debug pod cpma/cpma-debug-abcde on node node1 isn't running after 50ms: ImagePullBackOff Back-off pulling image")
+	assert.Equal(t, []string{"cpma-debug-abcde"}, podsAPI.deleted)
+}
+
+func TestDebugPodGetError(t *testing.T) {
+	podsAPI := &testPodsAPI{getError: true}
+	defer setNodeExec(t, podsAPI)()
+
+	_, err := debugPod("node1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Cannot get debug pod on node node1")
+	// The pod created isn't left behind
+	assert.Equal(t, []string{"cpma-debug-abcde"}, podsAPI.deleted)
+}
+
+func TestDebugPodsStartConcurrently(t *testing.T) {
+	podsAPI := &testPodsAPI{hold: map[string]chan struct{}{"node1": make(chan struct{})}}
+	defer setNodeExec(t, podsAPI)()
+
+	started := make(chan string)
+	go func() {
+		pod, err := debugPod("node1")
+		assert.NoError(t, err)
+		started <- pod
+	}()
+
+	// Wait for the pod of node1 to be created
+	for {
+		podsAPI.mu.Lock()
+		created := len(podsAPI.created)
+		podsAPI.mu.Unlock()
+		if created == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The pod of node2 starts while node1 still waits for its pod
+	pod, err := debugPod("node2")
+	require.NoError(t, err)
+	assert.Equal(t, "cpma-debug-abcde1", pod)
+
+	close(podsAPI.hold["node1"])
+	assert.Equal(t, "cpma-debug-abcde", <-started)
+
+	// Started pods are returned again rather than started twice
+	pod, err = debugPod("node1")
+	require.NoError(t, err)
+	assert.Equal(t, "cpma-debug-abcde", pod)
+	assert.Len(t, podsAPI.created, 2)
+
+	CloseDebugPods()
+	assert.ElementsMatch(t, []string{"cpma-debug-abcde", "cpma-debug-abcde1"}, podsAPI.deleted)
+}
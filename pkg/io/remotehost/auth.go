@@ -0,0 +1,244 @@
+package remotehost
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Number of times a passphrase or a password is prompted
+const (
+	passphraseAttempts = 3
+	passwordAttempts   = 3
+)
+
+// interactive tells if values can be prompted
+var interactive = func() bool {
+	return !env.NonInteractive()
+}
+
+// askPassword prompts for a secret value
+var askPassword = func(message string) (string, error) {
+	var value string
+	err := survey.AskOne(&survey.Password{Message: message}, &value)
+	return value, err
+}
+
+// askInput prompts for a value which is shown when typed
+var askInput = func(message string) (string, error) {
+	var value string
+	err := survey.AskOne(&survey.Input{Message: message}, &value)
+	return value, err
+}
+
+// promptLock serializes interactive prompts, several hosts may be connected to concurrently
+var promptLock sync.Mutex
+
+// authenticator authenticates to a hop, methods are tried in order: ssh-agent keys, private key file, password and
+// keyboard-interactive. What each method did is recorded to explain an authentication failure.
+type authenticator struct {
+	hop   hop
+	agent net.Conn
+	notes []string
+}
+
+func newAuthenticator(h hop) *authenticator {
+	return &authenticator{hop: h}
+}
+
+// methods returns authentication methods, only those which can be used are returned
+func (a *authenticator) methods() []ssh.AuthMethod {
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket == "" {
+		a.note("ssh-agent skipped: SSH_AUTH_SOCK isn't set")
+	} else if conn, err := net.Dial("unix", socket); err != nil {
+		a.note("ssh-agent skipped: cannot connect to %s: %s", socket, err)
+	} else {
+		a.agent = conn
+	}
+
+	// A method is tried once, ssh-agent keys and the key file are offered by the same one
+	methods := []ssh.AuthMethod{ssh.PublicKeysCallback(a.signers)}
+
+	password := env.Config().GetString("SSHPassword")
+	if password == "" && !interactive() {
+		a.note("password and keyboard-interactive skipped: SSHPassword isn't set in non-interactive mode")
+		return methods
+	}
+
+	passwordMethod := ssh.PasswordCallback(a.password)
+	if password == "" {
+		// Like OpenSSH, a mistyped password can be prompted again
+		passwordMethod = ssh.RetryableAuthMethod(passwordMethod, passwordAttempts)
+	}
+
+	return append(methods, passwordMethod, ssh.KeyboardInteractive(a.keyboardInteractive))
+}
+
+// close closes the connection to ssh-agent, keys aren't needed once authenticated
+func (a *authenticator) close() {
+	if a.agent != nil {
+		a.agent.Close()
+		a.agent = nil
+	}
+}
+
+func (a *authenticator) note(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	logrus.Debugf("SSH authentication to %s: %s", a.hop.addr(), message)
+	a.notes = append(a.notes, message)
+}
+
+// summary explains what was tried to authenticate
+func (a *authenticator) summary() string {
+	return fmt.Sprintf("authentication as %s: %s", a.hop.user, strings.Join(a.notes, "; "))
+}
+
+// signers returns ssh-agent keys followed by the key file one
+func (a *authenticator) signers() ([]ssh.Signer, error) {
+	signers := a.agentSigners()
+	if a.hop.keyFile == "" {
+		return signers, nil
+	}
+
+	keyFileSigners, err := a.keyFileSigners()
+	return append(signers, keyFileSigners...), err
+}
+
+func (a *authenticator) agentSigners() []ssh.Signer {
+	if a.agent == nil {
+		return nil
+	}
+
+	signers, err := agent.NewClient(a.agent).Signers()
+	if err != nil {
+		a.note("ssh-agent skipped: cannot list keys: %s", err)
+		return nil
+	}
+
+	a.note("ssh-agent offered %d key(s)", len(signers))
+	return signers
+}
+
+// keyFileSigners returns the signer of the private key file, an encrypted key is decrypted using SSHKeyPassphrase
+// or a prompted passphrase. A key which can't be used is skipped so that the next methods are tried.
+func (a *authenticator) keyFileSigners() ([]ssh.Signer, error) {
+	keyFile := a.hop.keyFile
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		a.note("key %s skipped: %s", keyFile, err)
+		return nil, nil
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	if err == nil {
+		a.note("key %s offered", keyFile)
+		return []ssh.Signer{signer}, nil
+	}
+
+	// Keys in PEM and OpenSSH format are both decrypted using their passphrase
+	if _, ok := err.(*ssh.PassphraseMissingError); !ok {
+		a.note("key %s skipped: unable to parse it: %s", keyFile, err)
+		return nil, nil
+	}
+
+	if passphrase := env.Config().GetString("SSHKeyPassphrase"); passphrase != "" {
+		signer, err := ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+		if err != nil {
+			a.note("key %s skipped: unable to decrypt it using SSHKeyPassphrase: %s", keyFile, err)
+			return nil, nil
+		}
+
+		a.note("key %s decrypted using SSHKeyPassphrase and offered", keyFile)
+		return []ssh.Signer{signer}, nil
+	}
+
+	if !interactive() {
+		a.note("key %s skipped: it is encrypted, set its passphrase using CPMA_SSHKEYPASSPHRASE in non-interactive mode", keyFile)
+		return nil, nil
+	}
+
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
+	for i := 0; i < passphraseAttempts; i++ {
+		passphrase, err := askPassword(fmt.Sprintf("Passphrase for key %s", keyFile))
+		if err != nil {
+			return nil, err
+		}
+
+		signer, err := ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+		if err == nil {
+			a.note("key %s decrypted using prompted passphrase and offered", keyFile)
+			return []ssh.Signer{signer}, nil
+		}
+		logrus.Warnf("Unable to decrypt key %s: %s", keyFile, err)
+	}
+
+	a.note("key %s skipped: unable to decrypt it after %d attempts", keyFile, passphraseAttempts)
+	return nil, nil
+}
+
+func (a *authenticator) password() (string, error) {
+	if password := env.Config().GetString("SSHPassword"); password != "" {
+		a.note("password from SSHPassword offered")
+		return password, nil
+	}
+
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
+	a.note("prompted password offered")
+	return askPassword(fmt.Sprintf("Password for %s@%s", a.hop.user, a.hop.host))
+}
+
+// keyboardInteractive answers questions asked by the server, a single secret question is answered using SSHPassword
+// when set, otherwise questions are prompted
+func (a *authenticator) keyboardInteractive(user, instruction string, questions []string, echos []bool) ([]string, error) {
+	if len(questions) == 0 {
+		return nil, nil
+	}
+
+	if password := env.Config().GetString("SSHPassword"); password != "" && len(questions) == 1 && !echos[0] {
+		a.note("keyboard-interactive answered using SSHPassword")
+		return []string{password}, nil
+	}
+
+	if !interactive() {
+		a.note("keyboard-interactive skipped: %d question(s) can't be prompted in non-interactive mode", len(questions))
+		return nil, errors.Errorf("keyboard-interactive authentication to %s needs prompting", a.hop.addr())
+	}
+
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
+	if instruction != "" {
+		fmt.Fprintln(os.Stderr, instruction)
+	}
+
+	answers := make([]string, len(questions))
+	for i, question := range questions {
+		ask := askPassword
+		if echos[i] {
+			ask = askInput
+		}
+
+		answer, err := ask(strings.TrimSpace(question))
+		if err != nil {
+			return nil, err
+		}
+		answers[i] = answer
+	}
+
+	a.note("keyboard-interactive answered using prompted values")
+	return answers, nil
+}
@@ -0,0 +1,298 @@
+package remotehost
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// passwordAuth accepts user authenticated with password, using either password or keyboard-interactive method
+func passwordAuth(user, password string) *ssh.ServerConfig {
+	return &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, p []byte) (*ssh.Permissions, error) {
+			if conn.User() == user && string(p) == password {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+		KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := challenge(user, "Enter your password", []string{"Password: "}, []bool{false})
+			if err != nil {
+				return nil, err
+			}
+			if conn.User() == user && len(answers) == 1 && answers[0] == password {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+	}
+}
+
+// keyboardInteractiveAuth accepts user answering password and a one-time code using keyboard-interactive method
+func keyboardInteractiveAuth(user, password, code string) *ssh.ServerConfig {
+	return &ssh.ServerConfig{
+		KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := challenge(user, "", []string{"Password: ", "Code: "}, []bool{false, true})
+			if err != nil {
+				return nil, err
+			}
+			if conn.User() == user && len(answers) == 2 && answers[0] == password && answers[1] == code {
+				return nil, nil
+			}
+			return nil, errors.New("wrong answers")
+		},
+	}
+}
+
+// serveAgent serves an ssh-agent holding key on socket
+func serveAgent(t *testing.T, socket string, key []byte) string {
+	rawKey, err := ssh.ParseRawPrivateKey(key)
+	require.NoError(t, err)
+
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: rawKey}))
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	return socket
+}
+
+// encryptedKey returns key in PEM format encrypted using passphrase
+func encryptedKey(t *testing.T, key []byte, passphrase string) []byte {
+	block, _ := pem.Decode(key)
+	encrypted, err := x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes, []byte(passphrase), x509.PEMCipherAES256)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(encrypted)
+}
+
+// openSSHKey returns the key of testdata/openssh_encrypted_key, in OpenSSH format encrypted using "passphrase"
+func openSSHKey(t *testing.T) (ssh.Signer, []byte) {
+	key, err := ioutil.ReadFile("testdata/openssh_encrypted_key")
+	require.NoError(t, err)
+
+	signer, err := ssh.ParsePrivateKeyWithPassphrase(key, []byte("passphrase"))
+	require.NoError(t, err)
+
+	return signer, key
+}
+
+func TestCreateConnectionAuthMethods(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	signer, key := newTestKey(t)
+	agentSocket := serveAgent(t, filepath.Join(dir, "agent.sock"), key)
+	_, otherKey := newTestKey(t)
+	openSSHSigner, openSSHKey := openSSHKey(t)
+
+	savedInteractive, savedAskPassword, savedAskInput := interactive, askPassword, askInput
+	defer func() { interactive, askPassword, askInput = savedInteractive, savedAskPassword, savedAskInput }()
+
+	testCases := []struct {
+		name         string
+		serverConfig *ssh.ServerConfig
+		agentSocket  string
+		keyFile      []byte
+		passphrase   string
+		password     string
+		interactive  bool
+		answers      []string
+		expectedErr  []string
+	}{
+		{
+			name:         "ssh-agent",
+			serverConfig: publicKeyAuth("root", signer.PublicKey()),
+			agentSocket:  agentSocket,
+		},
+		{
+			name:         "ssh-agent socket missing",
+			serverConfig: publicKeyAuth("root", signer.PublicKey()),
+			agentSocket:  filepath.Join(dir, "missing.sock"),
+			expectedErr:  []string{"ssh-agent skipped: cannot connect to " + filepath.Join(dir, "missing.sock"), "missing-key", "password and keyboard-interactive skipped"},
+		},
+		{
+			name:         "key file after ssh-agent keys",
+			serverConfig: publicKeyAuth("root", signer.PublicKey()),
+			agentSocket:  serveAgent(t, filepath.Join(dir, "other.sock"), otherKey),
+			keyFile:      key,
+		},
+		{
+			name:         "encrypted key using passphrase",
+			serverConfig: publicKeyAuth("root", signer.PublicKey()),
+			keyFile:      encryptedKey(t, key, "passphrase"),
+			passphrase:   "passphrase",
+		},
+		{
+			name:         "encrypted key using wrong passphrase",
+			serverConfig: publicKeyAuth("root", signer.PublicKey()),
+			keyFile:      encryptedKey(t, key, "passphrase"),
+			passphrase:   "wrong",
+			expectedErr:  []string{"unable to decrypt it using SSHKeyPassphrase"},
+		},
+		{
+			name:         "encrypted key in non-interactive mode",
+			serverConfig: publicKeyAuth("root", signer.PublicKey()),
+			keyFile:      encryptedKey(t, key, "passphrase"),
+			expectedErr:  []string{"SSH_AUTH_SOCK isn't set", "it is encrypted, set its passphrase using CPMA_SSHKEYPASSPHRASE in non-interactive mode"},
+		},
+		{
+			name:         "encrypted key using prompted passphrase",
+			serverConfig: publicKeyAuth("root", signer.PublicKey()),
+			keyFile:      encryptedKey(t, key, "passphrase"),
+			interactive:  true,
+			answers:      []string{"wrong", "passphrase"},
+		},
+		{
+			name:         "encrypted key in OpenSSH format",
+			serverConfig: publicKeyAuth("root", openSSHSigner.PublicKey()),
+			keyFile:      openSSHKey,
+			passphrase:   "passphrase",
+		},
+		{
+			name:         "encrypted key in OpenSSH format using prompted passphrase",
+			serverConfig: publicKeyAuth("root", openSSHSigner.PublicKey()),
+			keyFile:      openSSHKey,
+			interactive:  true,
+			answers:      []string{"passphrase"},
+		},
+		{
+			name:         "password",
+			serverConfig: passwordAuth("root", "secret"),
+			keyFile:      key,
+			password:     "secret",
+		},
+		{
+			name:         "prompted password",
+			serverConfig: passwordAuth("root", "secret"),
+			interactive:  true,
+			answers:      []string{"wrong", "secret"},
+		},
+		{
+			name:         "keyboard-interactive",
+			serverConfig: keyboardInteractiveAuth("root", "secret", "123456"),
+			interactive:  true,
+			answers:      []string{"secret", "123456"},
+		},
+		{
+			name:         "keyboard-interactive in non-interactive mode",
+			serverConfig: keyboardInteractiveAuth("root", "secret", "123456"),
+			password:     "secret",
+			expectedErr:  []string{"keyboard-interactive skipped: 2 question(s) can't be prompted in non-interactive mode"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestSSHServer(t, "master", tc.serverConfig)
+			defer server.listener.Close()
+
+			keyFile := filepath.Join(dir, "missing-key")
+			if tc.keyFile != nil {
+				keyFile = filepath.Join(dir, "key")
+				require.NoError(t, ioutil.WriteFile(keyFile, tc.keyFile, 0600))
+			}
+
+			defer setAuthSock(tc.agentSocket)()
+			defer setConfig(map[string]interface{}{
+				"home":             dir,
+				"SSHLogin":         "root",
+				"SSHPrivateKey":    keyFile,
+				"SSHPort":          server.port(),
+				"SSHKeyPassphrase": tc.passphrase,
+				"SSHPassword":      tc.password,
+				"InsecureHostKey":  true,
+				"JumpHosts":        nil,
+			})()
+
+			answers := tc.answers
+			interactive = func() bool { return tc.interactive }
+			askPassword = func(message string) (string, error) {
+				require.NotEmpty(t, answers, "unexpected prompt %s", message)
+				answer := answers[0]
+				answers = answers[1:]
+				return answer, nil
+			}
+			askInput = askPassword
+
+			client, err := CreateConnection("127.0.0.1")
+			if len(tc.expectedErr) > 0 {
+				require.Error(t, err)
+				for _, expected := range tc.expectedErr {
+					assert.Contains(t, err.Error(), expected)
+				}
+				assert.True(t, isFinal(err), "authentication failures are final")
+				return
+			}
+			require.NoError(t, err)
+			client.Close()
+			assert.Empty(t, answers)
+		})
+	}
+}
+
+func TestPromptsSerialized(t *testing.T) {
+	defer setConfig(map[string]interface{}{"SSHPassword": ""})()
+	defer func(i func() bool, ask func(string) (string, error)) { interactive, askPassword = i, ask }(interactive, askPassword)
+
+	var prompting, maxPrompting int32
+	interactive = func() bool { return true }
+	askPassword = func(message string) (string, error) {
+		current := atomic.AddInt32(&prompting, 1)
+		defer atomic.AddInt32(&prompting, -1)
+		for {
+			max := atomic.LoadInt32(&maxPrompting)
+			if current <= max || atomic.CompareAndSwapInt32(&maxPrompting, max, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return "password", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a := newAuthenticator(hop{host: fmt.Sprintf("master-%d", i), port: 22, user: "root"})
+			if i%2 == 0 {
+				_, err := a.password()
+				assert.NoError(t, err)
+				return
+			}
+			_, err := a.keyboardInteractive("root", "", []string{"Password: ", "Code: "}, []bool{false, false})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), maxPrompting, "prompts of concurrent connections must not overlap")
+}
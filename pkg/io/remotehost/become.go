@@ -0,0 +1,376 @@
+package remotehost
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// Privilege escalation methods, set as BecomeMethod
+const (
+	BecomeNone = "none"
+	BecomeSudo = "sudo"
+	BecomeSu   = "su"
+)
+
+// becomeReady is printed once privileges are escalated, output before it comes from sudo or su
+const becomeReady = "CPMA-BECOME-READY"
+
+// sudoPrompt is the password prompt of sudo, set so that it can be recognised
+const sudoPrompt = "CPMA-BECOME-PASSWORD:"
+
+// suPrompt is the password prompt of su in C locale
+const suPrompt = "Password:"
+
+// becomeTimeout bounds the wait for sudo or su to run a command
+const becomeTimeout = 30 * time.Second
+
+// sftpPermissionDenied is the SSH_FX_PERMISSION_DENIED status code of SFTP
+const sftpPermissionDenied = 3
+
+// deniedMessages are output by sudo and su when privileges can't be escalated
+var deniedMessages = []string{
+	"is not in the sudoers file",
+	"is not allowed to execute",
+	"a password is required",
+	"incorrect password",
+	"Sorry, try again",
+	"Authentication failure",
+	"Permission denied",
+}
+
+// becomeTerminal are modes of the terminal su reads the password from, typed input isn't echoed
+var becomeTerminal = ssh.TerminalModes{
+	ssh.ECHO:          0,
+	ssh.TTY_OP_ISPEED: 14400,
+	ssh.TTY_OP_OSPEED: 14400,
+}
+
+// PermissionError is returned when privileges don't allow an operation on a host
+type PermissionError struct {
+	Host   string
+	Reason string
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("permission denied on %s: %s", e.Host, e.Reason)
+}
+
+// IsPermissionDenied tells if err is caused by missing privileges, either privileges couldn't be escalated or the
+// become user isn't allowed to read a file
+func IsPermissionDenied(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case *PermissionError:
+		return true
+	case *sftp.StatusError:
+		return e.Code == sftpPermissionDenied
+	}
+
+	return os.IsPermission(errors.Cause(err))
+}
+
+// become tells how commands are run on a host: as user, using method
+type become struct {
+	host   string
+	login  string
+	method string
+	user   string
+}
+
+// becomeOn returns how commands are run on host, set by BecomeMethod and BecomeUser. Privileges aren't escalated when
+// logged in as the become user.
+func becomeOn(host string) (become, error) {
+	login, err := loginUser(host)
+	if err != nil {
+		return become{}, err
+	}
+
+	b := become{
+		host:   host,
+		login:  login,
+		method: env.Config().GetString("BecomeMethod"),
+		user:   env.Config().GetString("BecomeUser"),
+	}
+	if b.method == "" {
+		b.method = env.DefaultBecomeMethod
+	}
+	if b.user == "" {
+		b.user = env.DefaultBecomeUser
+	}
+	if b.method == BecomeNone || b.user == login {
+		b.method, b.user = BecomeNone, login
+	}
+
+	return b, nil
+}
+
+// command returns the shell command running command as the become user, becomeReady is printed first
+func (b become) command(command string) string {
+	switch b.method {
+	case BecomeSudo:
+		return fmt.Sprintf("sudo -S -p %s -u %s -- sh -c %s", shellQuote(sudoPrompt), shellQuote(b.user), shellQuote("printf "+becomeReady+"; "+command))
+	case BecomeSu:
+		// The locale is set so that the password prompt is known. The terminal su needs is switched to raw mode, its
+		// error output would be mixed with the output.
+		return fmt.Sprintf("LC_ALL=C su - %s -c %s", shellQuote(b.user), shellQuote("stty raw -echo; printf "+becomeReady+"; exec 2>/dev/null; "+command))
+	}
+
+	return command
+}
+
+// process is a command run on a host
+type process struct {
+	session *Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	output  *transcript
+}
+
+// openProcess opens a session on host whose input and output are connected to a process
+func openProcess(host string) (*process, error) {
+	session, err := NewSSHSession(host)
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, errors.Wrap(err, "Cannot start session")
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, errors.Wrap(err, "Cannot start session")
+	}
+	output := &transcript{prompts: make(chan struct{}, 1)}
+	session.Stderr = output
+
+	return &process{session: session, stdin: stdin, stdout: stdout, output: output}, nil
+}
+
+// start runs command as the become user, returning once privileges are escalated
+func (b become) start(command string) (*process, error) {
+	p, err := openProcess(b.host)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b.method {
+	case BecomeSudo:
+		p.output.isPrompt = isSudoPrompt
+	case BecomeSu:
+		p.output.isPrompt = isSuPrompt
+		// su reads the password from a terminal
+		if err := p.session.RequestPty("dumb", 24, 80, becomeTerminal); err != nil {
+			p.session.Close()
+			return nil, errors.Wrapf(err, "Cannot request a terminal on %s", b.host)
+		}
+	}
+
+	logrus.Debugf("Running %s on %s as %s using %s", command, b.host, b.user, b.method)
+	if err := p.session.Start(b.command(command)); err != nil {
+		p.session.Close()
+		return nil, errors.Wrapf(err, "Cannot run %s on %s", command, b.host)
+	}
+
+	if b.method == BecomeNone {
+		return p, nil
+	}
+
+	if err := b.escalate(p); err != nil {
+		p.session.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// escalate answers password prompts of sudo or su until becomeReady is printed
+func (b become) escalate(p *process) error {
+	ready := make(chan error, 1)
+	go func() {
+		ready <- readUntilReady(p.stdout, p.output)
+	}()
+
+	timeout := time.After(becomeTimeout)
+	answered := false
+	for {
+		select {
+		case err := <-ready:
+			if err != nil {
+				// Error output is recorded until the command exits
+				p.session.Wait()
+				return b.failure(p.output.String())
+			}
+			return nil
+		case <-p.output.prompts:
+			if answered {
+				forgetBecomePassword(b)
+				return &PermissionError{Host: b.host, Reason: fmt.Sprintf("%s rejected the password to become %s", b.method, b.user)}
+			}
+
+			password, err := becomePassword(b)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(p.stdin, password+"\n"); err != nil {
+				return errors.Wrapf(err, "Cannot send the %s password on %s", b.method, b.host)
+			}
+			answered = true
+		case <-timeout:
+			return errors.Errorf("Timed out becoming %s using %s on %s: %s", b.user, b.method, b.host, p.output)
+		}
+	}
+}
+
+// failure returns the error of sudo or su which exited before running the command
+func (b become) failure(output string) error {
+	for _, message := range deniedMessages {
+		if strings.Contains(output, message) {
+			return &PermissionError{Host: b.host, Reason: fmt.Sprintf("cannot become %s using %s: %s", b.user, b.method, output)}
+		}
+	}
+
+	return errors.Errorf("Cannot become %s using %s on %s: %s", b.user, b.method, b.host, output)
+}
+
+// readUntilReady reads stdout up to becomeReady, output before it is recorded to transcript. Bytes are read one at a
+// time so that output of the command isn't consumed.
+func readUntilReady(stdout io.Reader, t *transcript) error {
+	marker := []byte(becomeReady)
+	var pending []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(stdout, b); err != nil {
+			t.Write(pending)
+			return err
+		}
+
+		pending = append(pending, b[0])
+		if bytes.HasSuffix(pending, marker) {
+			t.Write(pending[:len(pending)-len(marker)])
+			return nil
+		}
+
+		// Output which can't be the start of the marker is recorded, a prompt can be recognised
+		held := len(pending)
+		for held > 0 && !bytes.HasPrefix(marker, pending[len(pending)-held:]) {
+			held--
+		}
+		if held < len(pending) {
+			t.Write(pending[:len(pending)-held])
+			pending = append([]byte(nil), pending[len(pending)-held:]...)
+		}
+	}
+}
+
+// transcript records output of sudo or su and signals password prompts
+type transcript struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	// isPrompt tells if output not checked yet ends with a password prompt, nil when no prompt is expected
+	isPrompt func(output string) bool
+	checked  int
+	prompts  chan struct{}
+}
+
+func (t *transcript) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf.Write(p)
+	if t.isPrompt != nil && t.isPrompt(t.buf.String()[t.checked:]) {
+		t.checked = t.buf.Len()
+		select {
+		case t.prompts <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// String returns the recorded output without prompts
+func (t *transcript) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	output := strings.NewReplacer(sudoPrompt, "", suPrompt, "").Replace(t.buf.String())
+	return strings.TrimSpace(output)
+}
+
+// isSudoPrompt tells if output holds the password prompt of sudo
+func isSudoPrompt(output string) bool {
+	return strings.Contains(output, sudoPrompt)
+}
+
+// isSuPrompt tells if output ends with the password prompt of su in C locale
+func isSuPrompt(output string) bool {
+	lastLine := output[strings.LastIndexAny(output, "\r\n")+1:]
+	return strings.TrimSpace(lastLine) == suPrompt
+}
+
+// becomePasswords holds prompted passwords by host and method, a password is prompted once
+var becomePasswords = struct {
+	sync.Mutex
+	values map[string]string
+}{}
+
+func becomePasswordKey(b become) string {
+	return b.method + ":" + b.login + ":" + b.user + "@" + b.host
+}
+
+// becomePassword returns the password asked by sudo or su, set as BecomePassword or prompted in interactive mode
+func becomePassword(b become) (string, error) {
+	if password := env.Config().GetString("BecomePassword"); password != "" {
+		return password, nil
+	}
+
+	becomePasswords.Lock()
+	defer becomePasswords.Unlock()
+
+	if password, ok := becomePasswords.values[becomePasswordKey(b)]; ok {
+		return password, nil
+	}
+
+	if !interactive() {
+		return "", &PermissionError{Host: b.host, Reason: fmt.Sprintf("%s asks for a password to become %s, set it using CPMA_BECOMEPASSWORD in non-interactive mode", b.method, b.user)}
+	}
+
+	message := fmt.Sprintf("sudo password for %s on %s", b.login, b.host)
+	if b.method == BecomeSu {
+		message = fmt.Sprintf("su password for %s on %s", b.user, b.host)
+	}
+	promptLock.Lock()
+	password, err := askPassword(message)
+	promptLock.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	if becomePasswords.values == nil {
+		becomePasswords.values = make(map[string]string)
+	}
+	becomePasswords.values[becomePasswordKey(b)] = password
+
+	return password, nil
+}
+
+// forgetBecomePassword forgets a rejected prompted password
+func forgetBecomePassword(b become) {
+	becomePasswords.Lock()
+	defer becomePasswords.Unlock()
+
+	delete(becomePasswords.values, becomePasswordKey(b))
+}
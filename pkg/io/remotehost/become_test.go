@@ -0,0 +1,169 @@
+package remotehost
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBecome(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-become")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	signer, key := newTestKey(t)
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, key, 0600))
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, []byte("content"), 0600))
+
+	savedInteractive, savedAskPassword := interactive, askPassword
+	defer func() { interactive, askPassword = savedInteractive, savedAskPassword }()
+
+	testCases := []struct {
+		name            string
+		method          string
+		user            string
+		serverPassword  string
+		password        string
+		interactive     bool
+		answers         []string
+		expectedRequest string
+		expectedErr     string
+	}{
+		{
+			name:            "sudo without password",
+			expectedRequest: "exec sudo -S -p 'CPMA-BECOME-PASSWORD:' -u 'root' -- sh -c",
+		},
+		{
+			name:            "sudo as another user",
+			method:          "sudo",
+			user:            "openshift",
+			expectedRequest: "exec sudo -S -p 'CPMA-BECOME-PASSWORD:' -u 'openshift' -- sh -c",
+		},
+		{
+			name:            "sudo using BecomePassword",
+			serverPassword:  "secret",
+			password:        "secret",
+			expectedRequest: "exec sudo -S",
+		},
+		{
+			name:            "sudo using prompted password",
+			serverPassword:  "secret",
+			interactive:     true,
+			answers:         []string{"secret"},
+			expectedRequest: "exec sudo -S",
+		},
+		{
+			name:           "sudo rejecting password",
+			serverPassword: "secret",
+			password:       "wrong",
+			expectedErr:    "permission denied on 127.0.0.1: sudo rejected the password to become root",
+		},
+		{
+			name:           "sudo asking for a password in non-interactive mode",
+			serverPassword: "secret",
+			expectedErr:    "permission denied on 127.0.0.1: sudo asks for a password to become root, set it using CPMA_BECOMEPASSWORD in non-interactive mode",
+		},
+		{
+			name:            "su using BecomePassword",
+			method:          "su",
+			serverPassword:  "secret",
+			password:        "secret",
+			expectedRequest: "exec LC_ALL=C su - 'root' -c 'stty raw -echo; printf CPMA-BECOME-READY; exec 2>/dev/null;",
+		},
+		{
+			name:           "su rejecting password",
+			method:         "su",
+			serverPassword: "secret",
+			password:       "wrong",
+			expectedErr:    "permission denied on 127.0.0.1: cannot become root using su: su: Authentication failure",
+		},
+		{
+			name:            "no privilege escalation",
+			method:          "none",
+			serverPassword:  "secret",
+			expectedRequest: "subsystem sftp",
+		},
+		{
+			name:            "logged in as the become user",
+			method:          "su",
+			user:            "admin",
+			serverPassword:  "secret",
+			expectedRequest: "subsystem sftp",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestSSHServer(t, "master", publicKeyAuth("admin", signer.PublicKey()))
+			defer server.listener.Close()
+			server.setBecomePassword(tc.serverPassword)
+
+			savedPool := DefaultPool()
+			defaultPool.pool = NewPool(CreateConnection, 0, DefaultMaxSessions)
+			defer func() {
+				defaultPool.pool.Close()
+				defaultPool.pool = savedPool
+			}()
+			defer func() { becomePasswords.values = nil }()
+
+			defer setAuthSock("")()
+			defer setConfig(map[string]interface{}{
+				"home":            dir,
+				"SSHLogin":        "admin",
+				"SSHPrivateKey":   keyFile,
+				"SSHPort":         server.port(),
+				"InsecureHostKey": true,
+				"JumpHosts":       nil,
+				"SFTPServer":      "",
+				"BecomeMethod":    tc.method,
+				"BecomeUser":      tc.user,
+				"BecomePassword":  tc.password,
+			})()
+
+			answers := tc.answers
+			interactive = func() bool { return tc.interactive }
+			askPassword = func(message string) (string, error) {
+				require.NotEmpty(t, answers, "unexpected prompt %s", message)
+				answer := answers[0]
+				answers = answers[1:]
+				return answer, nil
+			}
+
+			remoteFile, err := ReadRemoteFile("127.0.0.1", file)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.True(t, strings.HasSuffix(err.Error(), tc.expectedErr), err.Error())
+				assert.True(t, IsPermissionDenied(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []byte("content"), remoteFile.Content)
+			assert.True(t, strings.HasPrefix(server.lastRequest(), tc.expectedRequest), server.lastRequest())
+
+			// A prompted password is reused
+			output, err := RunCMD("127.0.0.1", "hostname")
+			require.NoError(t, err)
+			assert.Equal(t, "master", output)
+			assert.Empty(t, answers)
+		})
+	}
+}
+
+func TestIsPermissionDenied(t *testing.T) {
+	assert.True(t, IsPermissionDenied(&PermissionError{Host: "master0", Reason: "root can't read /etc/shadow"}))
+	assert.True(t, IsPermissionDenied(errors.Wrap(&sftp.StatusError{Code: sftpPermissionDenied}, "Cannot open")))
+	assert.True(t, IsPermissionDenied(os.ErrPermission))
+	assert.False(t, IsPermissionDenied(&sftp.StatusError{Code: 2}))
+	assert.False(t, IsPermissionDenied(os.ErrNotExist))
+	assert.False(t, IsPermissionDenied(errors.New("connection lost")))
+}
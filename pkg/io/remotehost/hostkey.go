@@ -0,0 +1,160 @@
+package remotehost
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	kh "golang.org/x/crypto/ssh/knownhosts"
+)
+
+// knownHostsFile is where host keys trusted on first use are recorded, relative to the home directory
+var knownHostsFile = filepath.Join(".cpma", "known_hosts")
+
+// askConfirm prompts for a yes or no answer
+var askConfirm = func(message string) (bool, error) {
+	var value bool
+	err := survey.AskOne(&survey.Confirm{Message: message}, &value)
+	return value, err
+}
+
+// trustLock serializes host key prompts and writes to knownHostsFile, hosts may be connected to concurrently
+var trustLock sync.Mutex
+
+// hostKeyChecker checks host keys against $HOME/.ssh/known_hosts and knownHostsFile. A key of an unknown host is
+// trusted on first use, once it matches a pinned fingerprint or is confirmed, and recorded in knownHostsFile.
+type hostKeyChecker struct {
+	home         string
+	fingerprints map[string]string
+}
+
+// hostKeyCallback returns the host key callback of connections, host keys aren't checked when InsecureHostKey is set
+func hostKeyCallback(home string) (ssh.HostKeyCallback, error) {
+	if env.Config().GetBool("InsecureHostKey") {
+		logrus.Warn("Host keys aren't checked, connections may be intercepted")
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	fingerprints, err := env.HostKeyFingerprints()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &hostKeyChecker{home: home, fingerprints: fingerprints}
+	return c.check, nil
+}
+
+func (c *hostKeyChecker) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+	pinned, isPinned := c.fingerprints[hostOf(hostname)]
+	if isPinned && pinned != fingerprint {
+		return errors.Errorf("Host key of %s doesn't match its pinned fingerprint: got %s, expected %s. The host key may have been replaced, or the connection intercepted",
+			hostname, fingerprint, pinned)
+	}
+
+	trustLock.Lock()
+	defer trustLock.Unlock()
+
+	err := c.known(hostname, remote, key)
+	keyErr, ok := err.(*kh.KeyError)
+	if !ok {
+		return err
+	}
+	if len(keyErr.Want) > 0 {
+		expected := make([]string, 0, len(keyErr.Want))
+		for _, want := range keyErr.Want {
+			expected = append(expected, fmt.Sprintf("%s at %s:%d", ssh.FingerprintSHA256(want.Key), want.Filename, want.Line))
+		}
+		return errors.Errorf("Host key of %s doesn't match the known one: got %s %s, expected %s. The host key may have been replaced, or the connection intercepted",
+			hostname, key.Type(), fingerprint, strings.Join(expected, ", "))
+	}
+
+	// Unknown host
+	if !isPinned {
+		if err := c.confirm(hostname, key); err != nil {
+			return err
+		}
+	}
+
+	return c.record(hostname, key)
+}
+
+// known checks key against known_hosts files, a *knownhosts.KeyError without wanted keys is returned for an unknown host
+func (c *hostKeyChecker) known(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	var files []string
+	for _, file := range []string{filepath.Join(c.home, ".ssh", "known_hosts"), filepath.Join(c.home, knownHostsFile)} {
+		if _, err := os.Stat(file); err == nil {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return &kh.KeyError{}
+	}
+
+	callback, err := kh.New(files...)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to read host keys from %s", strings.Join(files, ", "))
+	}
+
+	return callback(hostname, remote, key)
+}
+
+// confirm asks whether to trust the key of hostname, which can't be done in non-interactive mode
+func (c *hostKeyChecker) confirm(hostname string, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+	if !interactive() {
+		return errors.Errorf("Host key of %s is unknown, its %s fingerprint is %s. Check it and pin it using --host-key-fingerprint %s=%s in non-interactive mode",
+			hostname, key.Type(), fingerprint, hostOf(hostname), fingerprint)
+	}
+
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
+	trusted, err := askConfirm(fmt.Sprintf("The authenticity of host %s can't be established, its %s key fingerprint is %s. Trust it?",
+		hostname, key.Type(), fingerprint))
+	if err != nil {
+		return err
+	}
+	if !trusted {
+		return errors.Errorf("Host key %s of %s isn't trusted", fingerprint, hostname)
+	}
+
+	return nil
+}
+
+// record appends the key of hostname to knownHostsFile
+func (c *hostKeyChecker) record(hostname string, key ssh.PublicKey) error {
+	file := filepath.Join(c.home, knownHostsFile)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return errors.Wrapf(err, "Unable to create %s", filepath.Dir(file))
+	}
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to open %s", file)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, kh.Line([]string{kh.Normalize(hostname)}, key)); err != nil {
+		return errors.Wrapf(err, "Unable to write host key to %s", file)
+	}
+
+	logrus.Infof("Host key %s of %s added to %s", ssh.FingerprintSHA256(key), hostname, file)
+	return nil
+}
+
+// hostOf returns the host of a host:port address
+func hostOf(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
@@ -0,0 +1,175 @@
+package remotehost
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	kh "golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestHostKeyCallback(t *testing.T) {
+	signer, _ := newTestKey(t)
+	otherSigner, _ := newTestKey(t)
+	key, otherKey := signer.PublicKey(), otherSigner.PublicKey()
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	savedInteractive, savedAskConfirm := interactive, askConfirm
+	defer func() { interactive, askConfirm = savedInteractive, savedAskConfirm }()
+
+	testCases := []struct {
+		name         string
+		sshKnown     string
+		cpmaKnown    string
+		fingerprints []string
+		interactive  bool
+		answer       bool
+		expectedErr  string
+		recorded     bool
+	}{
+		{
+			name:     "known in SSH known_hosts",
+			sshKnown: kh.Line([]string{"master0.example.com"}, key),
+		},
+		{
+			name:      "known in CPMA known_hosts",
+			cpmaKnown: kh.Line([]string{"master0.example.com"}, key),
+		},
+		{
+			name:        "unknown in non-interactive mode",
+			expectedErr: "Host key of master0.example.com:22 is unknown, its ssh-rsa fingerprint is " + fingerprint + ". Check it and pin it using --host-key-fingerprint master0.example.com=" + fingerprint + " in non-interactive mode",
+		},
+		{
+			name:        "unknown and confirmed",
+			interactive: true,
+			answer:      true,
+			recorded:    true,
+		},
+		{
+			name:        "unknown and rejected",
+			interactive: true,
+			expectedErr: "Host key " + fingerprint + " of master0.example.com:22 isn't trusted",
+		},
+		{
+			name:         "unknown and pinned",
+			fingerprints: []string{"master0.example.com=" + fingerprint},
+			recorded:     true,
+		},
+		{
+			name:         "pinned to another key",
+			sshKnown:     kh.Line([]string{"master0.example.com"}, key),
+			fingerprints: []string{"master0.example.com=" + ssh.FingerprintSHA256(otherKey)},
+			expectedErr:  "Host key of master0.example.com:22 doesn't match its pinned fingerprint: got " + fingerprint + ", expected " + ssh.FingerprintSHA256(otherKey) + ". The host key may have been replaced, or the connection intercepted",
+		},
+		{
+			name:         "known with another key",
+			cpmaKnown:    kh.Line([]string{"master0.example.com"}, otherKey),
+			fingerprints: []string{"master0.example.com=" + fingerprint},
+			interactive:  true,
+			answer:       true,
+			expectedErr:  "Host key of master0.example.com:22 doesn't match the known one: got ssh-rsa " + fingerprint + ", expected " + ssh.FingerprintSHA256(otherKey) + " at ",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			home, err := ioutil.TempDir("", "cpma-hostkey")
+			require.NoError(t, err)
+			defer os.RemoveAll(home)
+
+			sshKnownHosts, cpmaKnownHosts := filepath.Join(home, ".ssh", "known_hosts"), filepath.Join(home, knownHostsFile)
+			for file, content := range map[string]string{sshKnownHosts: tc.sshKnown, cpmaKnownHosts: tc.cpmaKnown} {
+				if content != "" {
+					require.NoError(t, os.MkdirAll(filepath.Dir(file), 0700))
+					require.NoError(t, ioutil.WriteFile(file, []byte(content+"\n"), 0600))
+				}
+			}
+
+			defer setConfig(map[string]interface{}{
+				"InsecureHostKey":     false,
+				"HostKeyFingerprints": tc.fingerprints,
+			})()
+			interactive = func() bool { return tc.interactive }
+			askConfirm = func(message string) (bool, error) {
+				assert.Contains(t, message, fingerprint)
+				return tc.answer, nil
+			}
+
+			callback, err := hostKeyCallback(home)
+			require.NoError(t, err)
+			err = callback("master0.example.com:22", &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}, key)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			known, _ := ioutil.ReadFile(cpmaKnownHosts)
+			if tc.recorded {
+				assert.Equal(t, kh.Line([]string{"master0.example.com"}, key)+"\n", string(known))
+				// The recorded key is trusted from then on
+				require.NoError(t, callback("master0.example.com:22", &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}, key))
+				assert.Error(t, callback("master0.example.com:22", &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}, otherKey))
+			} else if tc.cpmaKnown == "" {
+				assert.Empty(t, known)
+			}
+		})
+	}
+}
+
+func TestCreateConnectionTrustsPinnedHostKey(t *testing.T) {
+	home, err := ioutil.TempDir("", "cpma-hostkey")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+
+	signer, key := newTestKey(t)
+	keyFile := filepath.Join(home, "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, key, 0600))
+
+	master := newTestSSHServer(t, "master", publicKeyAuth("root", signer.PublicKey()))
+	defer master.listener.Close()
+
+	savedInteractive := interactive
+	defer func() { interactive = savedInteractive }()
+	interactive = func() bool { return false }
+
+	defer setAuthSock("")()
+	defer setConfig(map[string]interface{}{
+		"home":                home,
+		"SSHLogin":            "root",
+		"SSHPrivateKey":       keyFile,
+		"SSHPort":             master.port(),
+		"InsecureHostKey":     false,
+		"JumpHosts":           nil,
+		"HostKeyFingerprints": []string{"127.0.0.1=" + ssh.FingerprintSHA256(master.hostKey)},
+	})()
+
+	client, err := CreateConnection("127.0.0.1")
+	require.NoError(t, err)
+	client.Close()
+
+	known, err := ioutil.ReadFile(filepath.Join(home, knownHostsFile))
+	require.NoError(t, err)
+	assert.Equal(t, kh.Line([]string{"[127.0.0.1]:" + strconv.Itoa(master.port())}, master.hostKey)+"\n", string(known))
+
+	// Once recorded, the host key is trusted without being pinned
+	defer setConfig(map[string]interface{}{"HostKeyFingerprints": nil})()
+	client, err = CreateConnection("127.0.0.1")
+	require.NoError(t, err)
+	client.Close()
+
+	// A host key which doesn't match is a final failure, dialing again wouldn't fix it
+	otherSigner, _ := newTestKey(t)
+	defer setConfig(map[string]interface{}{"HostKeyFingerprints": []string{"127.0.0.1=" + ssh.FingerprintSHA256(otherSigner.PublicKey())}})()
+	_, err = CreateConnection("127.0.0.1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "doesn't match its pinned fingerprint")
+	assert.True(t, isFinal(err))
+}
@@ -0,0 +1,249 @@
+package remotehost
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// Pool defaults, used when not configured
+const (
+	// DefaultMaxSessions matches the default MaxSessions of OpenSSH servers
+	DefaultMaxSessions = 10
+	// DefaultKeepAlive is the interval of keepalive requests
+	DefaultKeepAlive = 30 * time.Second
+)
+
+// keepAliveCountMax is the number of keepalive intervals a request is waited for
+const keepAliveCountMax = 3
+
+// Delays before a host which couldn't be reached is dialed again, the delay doubles on each failed dial
+var (
+	minRedialDelay = time.Second
+	maxRedialDelay = 30 * time.Second
+)
+
+// Pool holds SSH connections keyed by host. A connection is dialed on first use, checked using keepalive requests
+// and dialed again once broken. A host which couldn't be reached is dialed again after a delay, unless
+// authentication or the host key check failed.
+type Pool struct {
+	// Dial connects to a host
+	Dial func(host string) (*ssh.Client, error)
+	// KeepAlive is the interval of keepalive requests, 0 disables them
+	KeepAlive time.Duration
+	// MaxSessions limits the number of sessions open at the same time on a host
+	MaxSessions int
+
+	mu    sync.Mutex
+	hosts map[string]*hostConn
+}
+
+// hostConn is the connection to a host
+type hostConn struct {
+	// mu is held while dialing so that a host is dialed once
+	mu     sync.Mutex
+	client *ssh.Client
+	// closed is closed along with client
+	closed chan struct{}
+	// err is the error of the last dial. Authentication and host key failures are final, other errors are
+	// returned until retryAt
+	err     error
+	retryAt time.Time
+	// delay is the delay before dialing again after the last failed dial
+	delay time.Duration
+	// sessions holds a value per open session
+	sessions chan struct{}
+}
+
+// Session is an SSH session of a pooled connection, it must be closed to let other sessions open
+type Session struct {
+	*ssh.Session
+	release sync.Once
+	hc      *hostConn
+}
+
+// Close closes the session and frees its slot
+func (s *Session) Close() error {
+	err := s.Session.Close()
+	s.release.Do(func() { <-s.hc.sessions })
+
+	return err
+}
+
+// NewPool returns an empty pool connecting to hosts using dial
+func NewPool(dial func(host string) (*ssh.Client, error), keepAlive time.Duration, maxSessions int) *Pool {
+	return &Pool{Dial: dial, KeepAlive: keepAlive, MaxSessions: maxSessions}
+}
+
+// host returns the connection to host, creating it when missing
+func (p *Pool) host(host string) *hostConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.hosts == nil {
+		p.hosts = map[string]*hostConn{}
+	}
+
+	hc, ok := p.hosts[host]
+	if !ok {
+		maxSessions := p.MaxSessions
+		if maxSessions < 1 {
+			maxSessions = 1
+		}
+		hc = &hostConn{sessions: make(chan struct{}, maxSessions)}
+		p.hosts[host] = hc
+	}
+
+	return hc
+}
+
+// Client returns the connection to host, dialed when missing or broken
+func (p *Pool) Client(host string) (*ssh.Client, error) {
+	hc := p.host(host)
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if hc.err != nil && (isFinal(hc.err) || time.Now().Before(hc.retryAt)) {
+		return nil, hc.err
+	}
+
+	if hc.client != nil {
+		select {
+		case <-hc.closed:
+			logrus.Debugf("SSH connection to %s is broken, reconnecting", host)
+		default:
+			return hc.client, nil
+		}
+	}
+
+	client, err := p.Dial(host)
+	if err != nil {
+		hc.delay *= 2
+		if hc.delay < minRedialDelay {
+			hc.delay = minRedialDelay
+		}
+		if hc.delay > maxRedialDelay {
+			hc.delay = maxRedialDelay
+		}
+		hc.client, hc.err, hc.retryAt = nil, err, time.Now().Add(hc.delay)
+		return nil, err
+	}
+	hc.err, hc.delay = nil, 0
+
+	closed := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(closed)
+	}()
+	if p.KeepAlive > 0 {
+		go keepAlive(host, client, closed, p.KeepAlive)
+	}
+
+	hc.client, hc.closed = client, closed
+	return client, nil
+}
+
+// Session opens a session on host, waiting while MaxSessions sessions are open. A connection which broke since it
+// was last used is dialed again.
+func (p *Pool) Session(host string) (*Session, error) {
+	hc := p.host(host)
+	hc.sessions <- struct{}{}
+
+	session, err := p.newSession(hc, host)
+	if err != nil {
+		<-hc.sessions
+		return nil, err
+	}
+
+	return &Session{Session: session, hc: hc}, nil
+}
+
+func (p *Pool) newSession(hc *hostConn, host string) (*ssh.Session, error) {
+	client, err := p.Client(host)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := client.NewSession()
+	if err == nil {
+		return session, nil
+	}
+	if _, rejected := err.(*ssh.OpenChannelError); rejected {
+		return nil, errors.Wrap(err, "Cannot start session")
+	}
+
+	// The connection broke before it was noticed, closing it makes the next call dial again
+	logrus.Debugf("Cannot start session on %s, reconnecting: %s", host, err)
+	client.Close()
+	hc.mu.Lock()
+	if hc.client == client {
+		<-hc.closed
+	}
+	hc.mu.Unlock()
+
+	client, err = p.Client(host)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err = client.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot start session")
+	}
+
+	return session, nil
+}
+
+// Close closes every connection of the pool
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for host, hc := range p.hosts {
+		hc.mu.Lock()
+		if hc.client != nil {
+			hc.client.Close()
+		}
+		hc.mu.Unlock()
+		delete(p.hosts, host)
+	}
+}
+
+// keepAlive sends keepalive requests on client until it is closed. Like OpenSSH defaults, client is closed when a
+// request isn't answered within 3 intervals.
+func keepAlive(host string, client *ssh.Client, closed chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+
+		answered := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			answered <- err
+		}()
+
+		select {
+		case err := <-answered:
+			if err == nil {
+				continue
+			}
+			logrus.Debugf("SSH keepalive to %s failed: %s", host, err)
+		case <-time.After(keepAliveCountMax * interval):
+			logrus.Debugf("SSH keepalive to %s wasn't answered within %s", host, keepAliveCountMax*interval)
+		case <-closed:
+			return
+		}
+
+		client.Close()
+		return
+	}
+}
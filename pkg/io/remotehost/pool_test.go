@@ -0,0 +1,185 @@
+package remotehost
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// testDialer dials test servers by host name and counts dials
+type testDialer struct {
+	mu      sync.Mutex
+	servers map[string]*testSSHServer
+	signer  ssh.Signer
+	dials   map[string]int
+}
+
+func (d *testDialer) dial(host string) (*ssh.Client, error) {
+	d.mu.Lock()
+	d.dials[host]++
+	d.mu.Unlock()
+
+	server, ok := d.servers[host]
+	if !ok {
+		return nil, errors.New("no route to host " + host)
+	}
+
+	return ssh.Dial("tcp", "127.0.0.1:"+strconv.Itoa(server.port()), &ssh.ClientConfig{
+		User:            "root",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(d.signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+}
+
+func (d *testDialer) count(host string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.dials[host]
+}
+
+func newTestDialer(t *testing.T, hosts ...string) *testDialer {
+	signer, _ := newTestKey(t)
+	d := &testDialer{servers: map[string]*testSSHServer{}, signer: signer, dials: map[string]int{}}
+	for _, host := range hosts {
+		d.servers[host] = newTestSSHServer(t, host, publicKeyAuth("root", signer.PublicKey()))
+	}
+
+	return d
+}
+
+func (d *testDialer) close() {
+	for _, server := range d.servers {
+		server.listener.Close()
+	}
+}
+
+func runHostname(t *testing.T, pool *Pool, host string) string {
+	session, err := pool.Session(host)
+	require.NoError(t, err)
+	defer session.Close()
+
+	output, err := session.Output("hostname")
+	require.NoError(t, err)
+
+	return string(output)
+}
+
+func TestPoolHosts(t *testing.T) {
+	dialer := newTestDialer(t, "master0", "node0")
+	defer dialer.close()
+
+	pool := NewPool(dialer.dial, 20*time.Millisecond, DefaultMaxSessions)
+	defer pool.Close()
+
+	assert.Equal(t, "master0", runHostname(t, pool, "master0"))
+	assert.Equal(t, "node0", runHostname(t, pool, "node0"))
+	// Keepalive requests don't break connections
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "master0", runHostname(t, pool, "master0"))
+
+	client, err := pool.Client("master0")
+	require.NoError(t, err)
+	other, err := pool.Client("node0")
+	require.NoError(t, err)
+	assert.NotEqual(t, client, other)
+
+	assert.Equal(t, 1, dialer.count("master0"))
+	assert.Equal(t, 1, dialer.count("node0"))
+}
+
+func TestPoolReconnect(t *testing.T) {
+	dialer := newTestDialer(t, "master0")
+	defer dialer.close()
+
+	pool := NewPool(dialer.dial, 0, DefaultMaxSessions)
+	defer pool.Close()
+
+	client, err := pool.Client("master0")
+	require.NoError(t, err)
+	client.Close()
+
+	assert.Equal(t, "master0", runHostname(t, pool, "master0"))
+	assert.Equal(t, 2, dialer.count("master0"))
+}
+
+func TestPoolMaxSessions(t *testing.T) {
+	dialer := newTestDialer(t, "master0")
+	defer dialer.close()
+
+	pool := NewPool(dialer.dial, 0, 1)
+	defer pool.Close()
+
+	first, err := pool.Session("master0")
+	require.NoError(t, err)
+
+	opened := make(chan *Session)
+	go func() {
+		second, err := pool.Session("master0")
+		assert.NoError(t, err)
+		opened <- second
+	}()
+
+	select {
+	case <-opened:
+		t.Fatal("second session opened while the first one is open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Close()
+	second := <-opened
+	require.NotNil(t, second)
+	second.Close()
+}
+
+func TestPoolDialError(t *testing.T) {
+	dialer := newTestDialer(t)
+
+	pool := NewPool(dialer.dial, 0, DefaultMaxSessions)
+	defer pool.Close()
+
+	savedMinRedialDelay := minRedialDelay
+	defer func() { minRedialDelay = savedMinRedialDelay }()
+	minRedialDelay = 100 * time.Millisecond
+
+	_, err := pool.Session("master0")
+	assert.EqualError(t, err, "no route to host master0")
+	// The host isn't dialed again before the delay
+	_, err = pool.Session("master0")
+	assert.EqualError(t, err, "no route to host master0")
+	assert.Equal(t, 1, dialer.count("master0"))
+
+	// Once the delay is over, a host which became reachable is dialed again
+	dialer.mu.Lock()
+	dialer.servers["master0"] = newTestSSHServer(t, "master0", publicKeyAuth("root", dialer.signer.PublicKey()))
+	dialer.mu.Unlock()
+	defer dialer.close()
+	time.Sleep(minRedialDelay)
+	assert.Equal(t, "master0", runHostname(t, pool, "master0"))
+	assert.Equal(t, 2, dialer.count("master0"))
+}
+
+func TestPoolFinalDialError(t *testing.T) {
+	dials := 0
+	pool := NewPool(func(host string) (*ssh.Client, error) {
+		dials++
+		return nil, finalError{errors.New("ssh: unable to authenticate")}
+	}, 0, DefaultMaxSessions)
+	defer pool.Close()
+
+	savedMinRedialDelay := minRedialDelay
+	defer func() { minRedialDelay = savedMinRedialDelay }()
+	minRedialDelay = 0
+
+	for i := 0; i < 2; i++ {
+		_, err := pool.Session("master0")
+		assert.EqualError(t, err, "ssh: unable to authenticate")
+	}
+	assert.Equal(t, 1, dials)
+}
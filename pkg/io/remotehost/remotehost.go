@@ -1,121 +1,264 @@
 package remotehost
 
 import (
-	"fmt"
 	"io/ioutil"
-	"path/filepath"
+	"net"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/env/sshconfig"
 	"github.com/pkg/errors"
-	"github.com/pkg/sftp"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
-	kh "golang.org/x/crypto/ssh/knownhosts"
 )
 
-var instances struct {
-	client   *ssh.Client
-	sshError error
+var defaultPool struct {
+	once sync.Once
+	pool *Pool
 }
 
-var once struct {
-	client sync.Once
+// hop is an SSH host connected to, either a jump host or the master
+type hop struct {
+	host    string
+	port    int
+	user    string
+	keyFile string
 }
 
-// Client Wrapper around sftp.Client
-type Client struct {
-	*sftp.Client
+func (h hop) addr() string {
+	return net.JoinHostPort(h.host, strconv.Itoa(h.port))
 }
 
-// CreateConnection create ssh connection
+// finalError is a connection failure which dialing again wouldn't fix
+type finalError struct {
+	error
+}
+
+// isFinal tells if err is a connection failure which dialing again wouldn't fix
+func isFinal(err error) bool {
+	_, ok := errors.Cause(err).(finalError)
+	return ok
+}
+
+// CreateConnection create ssh connection, through jump hosts when there are
 func CreateConnection(source string) (*ssh.Client, error) {
-	key, err := ioutil.ReadFile(env.Config().GetString("SSHPrivateKey"))
+	home := env.Config().GetString("home")
+	config, err := sshconfig.ReadUser(home)
+	if err != nil {
+		return nil, err
+	}
+
+	hops, err := connectionHops(source, config, home)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Unable to read private key: %s\n", key)
+		return nil, err
 	}
 
-	// Create the Signer for this private key.
-	signer, err := ssh.ParsePrivateKey(key)
+	callback, err := hostKeyCallback(home)
 	if err != nil {
-		return nil, errors.Wrap(err, "Unable to parse private key")
+		return nil, err
 	}
 
-	knownHostsFile := filepath.Join(env.Config().GetString("home"), ".ssh", "known_hosts")
+	// Each hop is reached through a channel forwarded by the previous one
+	var clients []*ssh.Client
+	for _, h := range hops {
+		var jump *ssh.Client
+		if len(clients) > 0 {
+			jump = clients[len(clients)-1]
+		}
+
+		auth := newAuthenticator(h)
+		// Once the host key is checked, the host is reached and only the host key check or authentication can fail
+		reached := false
+		sshConfig := &ssh.ClientConfig{
+			User: h.user,
+			Auth: auth.methods(),
+			HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+				reached = true
+				return callback(hostname, remote, key)
+			},
+
+			Timeout: 10 * time.Second,
+		}
 
-	var hostKeyCallback ssh.HostKeyCallback
-	if env.Config().GetBool("InsecureHostKey") {
-		hostKeyCallback = ssh.InsecureIgnoreHostKey()
-	} else {
-		hostKeyCallback, err = kh.New(knownHostsFile)
+		client, err := connect(jump, h, sshConfig)
+		auth.close()
 		if err != nil {
-			return nil, errors.Wrapf(err, "Unable to get hostkey in %s\n", knownHostsFile)
+			closeClients(clients)
+			err = errors.Wrapf(err, "Cannot connect to %s, %s\n", h.addr(), auth.summary())
+			// Dialing again wouldn't fix a rejected host key or a failed authentication
+			if reached {
+				return nil, finalError{err}
+			}
+			return nil, err
 		}
+		clients = append(clients, client)
 	}
 
-	sshConfig := &ssh.ClientConfig{
-		User: env.Config().GetString("SSHLogin"),
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: hostKeyCallback,
+	client, jumps := clients[len(clients)-1], clients[:len(clients)-1]
+	if len(jumps) > 0 {
+		// Jump host connections only carry the master one, they are closed along with it
+		go func() {
+			client.Wait()
+			closeClients(jumps)
+		}()
+	}
+
+	return client, nil
+}
 
-		Timeout: 10 * time.Second,
+// closeClients closes connections to hops, the last one first as it goes through the previous ones
+func closeClients(clients []*ssh.Client) {
+	for i := len(clients) - 1; i >= 0; i-- {
+		clients[i].Close()
+	}
+}
+
+// connectionHops returns jump hosts followed by the master. Jump hosts are read from JumpHosts, or from ProxyJump
+// of the master entry of SSH config. Values which aren't set are read from SSH config, then from master values.
+func connectionHops(source string, config sshconfig.Config, home string) ([]hop, error) {
+	master := hop{
+		host:    source,
+		user:    env.Config().GetString("SSHLogin"),
+		keyFile: env.Config().GetString("SSHPrivateKey"),
 	}
 
-	port := 22
-	if p := env.Config().GetString("SSHPort"); p != "" {
-		port, err = strconv.Atoi(p)
+	if p := env.Config().GetString("SSHPort"); p != "" && p != "0" {
+		port, err := strconv.Atoi(p)
 		if err != nil || port < 1 || port > 65535 {
-			return nil, errors.Wrapf(err, "Port number %s is wrong\n", p)
+			return nil, errors.Errorf("Port number %s is wrong\n", p)
 		}
+		master.port = port
 	}
+	master = resolve(master, config, hop{user: env.DefaultSSHLogin, port: env.DefaultSSHPort}, home)
 
-	addr := fmt.Sprintf("%s:%d", source, port)
-	logrus.Debugf("Connecting to %s", addr)
-
-	connection, err := ssh.Dial("tcp", addr, sshConfig)
+	jumpHosts, err := env.JumpHosts()
 	if err != nil {
-		return nil, errors.Wrapf(err, "Cannot connect to %s\n", addr)
+		return nil, err
+	}
+
+	if proxyJump := config.Get(source, "ProxyJump"); len(jumpHosts) == 0 && proxyJump != "" && proxyJump != "none" {
+		for _, spec := range strings.Split(proxyJump, ",") {
+			jumpHost, err := env.ParseJumpHost(spec)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid ProxyJump for %s in SSH config", source)
+			}
+			jumpHosts = append(jumpHosts, jumpHost)
+		}
 	}
 
-	return connection, nil
+	hops := make([]hop, 0, len(jumpHosts)+1)
+	for _, j := range jumpHosts {
+		jump := hop{host: j.Host, port: j.Port, user: j.User, keyFile: j.KeyFile}
+		hops = append(hops, resolve(jump, config, hop{user: master.user, port: env.DefaultSSHPort, keyFile: master.keyFile}, home))
+	}
+
+	return append(hops, master), nil
 }
 
-// NewSSHSession Start new ssh session
-func NewSSHSession(source string) (*ssh.Session, error) {
-	// Init client only on first call
-	once.client.Do(func() {
-		connection, err := CreateConnection(source)
-		instances.client = connection
-		instances.sshError = err
-	})
+// resolve sets the hostname of h from SSH config, along with its values which aren't set, from SSH config or defaults
+func resolve(h hop, c sshconfig.Config, defaults hop, home string) hop {
+	alias := h.host
+	if hostName := c.Get(alias, "HostName"); hostName != "" {
+		h.host = strings.Replace(hostName, "%h", alias, -1)
+	}
+
+	if h.user == "" {
+		h.user = c.Get(alias, "User")
+	}
+	if h.user == "" {
+		h.user = defaults.user
+	}
+
+	if h.port == 0 {
+		h.port, _ = strconv.Atoi(c.Get(alias, "Port"))
+	}
+	if h.port == 0 {
+		h.port = defaults.port
+	}
+
+	if h.keyFile == "" {
+		h.keyFile = c.IdentityFile(alias, home)
+	}
+	if h.keyFile == "" {
+		h.keyFile = defaults.keyFile
+	}
 
-	if instances.sshError != nil {
-		return nil, instances.sshError
+	return h
+}
+
+// connect connects to h, through jump client when it isn't nil
+func connect(jump *ssh.Client, h hop, sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	if jump == nil {
+		logrus.Debugf("Connecting to %s", h.addr())
+		return ssh.Dial("tcp", h.addr(), sshConfig)
 	}
 
-	session, err := instances.client.NewSession()
+	logrus.Debugf("Connecting to %s through %s", h.addr(), jump.RemoteAddr())
+	conn, err := jump.Dial("tcp", h.addr())
 	if err != nil {
-		return nil, errors.Wrap(err, "Cannot start session")
+		return nil, errors.Wrapf(err, "Cannot reach %s from jump host %s", h.addr(), jump.RemoteAddr())
 	}
 
-	return session, nil
+	c, chans, reqs, err := ssh.NewClientConn(conn, h.addr(), sshConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(c, chans, reqs), nil
 }
 
-// RunCMD execute cmd on remote host
+// DefaultPool returns the pool of connections used to run commands, configured by SSHKeepAlive and SSHMaxSessions
+func DefaultPool() *Pool {
+	defaultPool.once.Do(func() {
+		keepAlive := DefaultKeepAlive
+		if env.Config().IsSet("SSHKeepAlive") {
+			keepAlive = time.Duration(env.Config().GetInt("SSHKeepAlive")) * time.Second
+		}
+
+		maxSessions := DefaultMaxSessions
+		if env.Config().IsSet("SSHMaxSessions") {
+			maxSessions = env.Config().GetInt("SSHMaxSessions")
+		}
+
+		defaultPool.pool = NewPool(CreateConnection, keepAlive, maxSessions)
+	})
+
+	return defaultPool.pool
+}
+
+// NewSSHSession Start new ssh session on source, it must be closed once done
+func NewSSHSession(source string) (*Session, error) {
+	return DefaultPool().Session(source)
+}
+
+// RunCMD execute cmd on remote host as the become user
 var RunCMD = func(hostname, cmd string) (string, error) {
-	session, err := NewSSHSession(hostname)
+	b, err := becomeOn(hostname)
 	if err != nil {
 		return "", err
 	}
 
-	output, err := session.Output(cmd)
+	p, err := b.start(cmd)
 	if err != nil {
 		return "", err
 	}
+	defer p.session.Close()
+
+	p.stdin.Close()
+	output, err := ioutil.ReadAll(p.stdout)
+	if err != nil {
+		return "", errors.Wrapf(err, "Cannot read output of %s on %s", cmd, hostname)
+	}
+	if err := p.session.Wait(); err != nil {
+		if message := p.output.String(); message != "" {
+			return "", errors.Errorf("%s failed on %s: %s", cmd, hostname, message)
+		}
+		return "", errors.Wrapf(err, "%s failed on %s", cmd, hostname)
+	}
 
 	return string(output), nil
 }
@@ -0,0 +1,432 @@
+package remotehost
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/env/sshconfig"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHServer accepts connections authenticated by its config, forwards direct-tcpip channels and answers exec
+// requests with its name. sudo and su are emulated, serving SFTP when running sftp-server.
+type testSSHServer struct {
+	name     string
+	listener net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.PublicKey
+
+	// conns is the number of open connections
+	conns int32
+
+	mu sync.Mutex
+	// requests holds session requests accepted, as type followed by payload
+	requests []string
+	// becomePassword is asked by sudo and su when set
+	becomePassword string
+}
+
+func newTestSSHServer(t *testing.T, name string, config *ssh.ServerConfig) *testSSHServer {
+	hostKey, _ := newTestKey(t)
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &testSSHServer{name: name, listener: listener, config: config, hostKey: hostKey.PublicKey()}
+	go s.serve()
+
+	return s
+}
+
+// publicKeyAuth accepts user authenticated with key
+func publicKeyAuth(user string, key ssh.PublicKey) *ssh.ServerConfig {
+	return &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, k ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == user && string(k.Marshal()) == string(key.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+}
+
+// waitConnections waits for the number of open connections to be n
+func (s *testSSHServer) waitConnections(t *testing.T, n int32) {
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt32(&s.conns) != n; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s has %d open connections instead of %d", s.name, atomic.LoadInt32(&s.conns), n)
+		}
+	}
+}
+
+func (s *testSSHServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSSHServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testSSHServer) handle(conn net.Conn) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	atomic.AddInt32(&s.conns, 1)
+	defer atomic.AddInt32(&s.conns, -1)
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "direct-tcpip":
+			go s.forward(newChannel)
+		case "session":
+			go s.session(newChannel)
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+func (s *testSSHServer) forward(newChannel ssh.NewChannel) {
+	var payload struct {
+		Host     string
+		Port     uint32
+		OrigHost string
+		OrigPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		target.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	go func() {
+		io.Copy(target, channel)
+		target.Close()
+	}()
+	io.Copy(channel, target)
+	channel.Close()
+}
+
+func (s *testSSHServer) session(newChannel ssh.NewChannel) {
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+
+	for req := range reqs {
+		var payload struct{ Value string }
+		ssh.Unmarshal(req.Payload, &payload)
+		s.mu.Lock()
+		s.requests = append(s.requests, req.Type+" "+payload.Value)
+		s.mu.Unlock()
+
+		switch {
+		case req.Type == "subsystem" && payload.Value == "sftp":
+			req.Reply(true, nil)
+			go s.sftp(channel)
+		case req.Type == "pty-req":
+			req.Reply(true, nil)
+		case req.Type == "exec" && (strings.HasPrefix(payload.Value, "sudo ") || strings.HasPrefix(payload.Value, "LC_ALL=C su ")):
+			req.Reply(true, nil)
+			go s.become(channel, payload.Value)
+		case req.Type == "exec":
+			req.Reply(true, nil)
+			io.WriteString(channel, s.name)
+			exit(channel, 0)
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// sftp serves local files on channel
+func (s *testSSHServer) sftp(channel ssh.Channel) {
+	server, err := sftp.NewServer(channel)
+	if err != nil {
+		channel.Close()
+		return
+	}
+
+	server.Serve()
+	exit(channel, 0)
+}
+
+// become emulates sudo and su running command, the password is asked when set
+func (s *testSSHServer) become(channel ssh.Channel, command string) {
+	// sudo asks the password again while su exits
+	prompt, output, rejected, retry := sudoPrompt, channel.Stderr(), "Sorry, try again.\n"+sudoPrompt, true
+	if strings.HasPrefix(command, "LC_ALL=C su ") {
+		prompt, output, rejected, retry = "Password: ", channel, "su: Authentication failure\r\n", false
+	}
+
+	s.mu.Lock()
+	password := s.becomePassword
+	s.mu.Unlock()
+
+	if password != "" {
+		io.WriteString(output, prompt)
+		if readLine(channel) != password {
+			io.WriteString(output, rejected)
+			if retry {
+				readLine(channel)
+			}
+			exit(channel, 1)
+			return
+		}
+	}
+
+	io.WriteString(channel, becomeReady)
+	if strings.Contains(command, "sftp-server") {
+		s.sftp(channel)
+		return
+	}
+
+	io.WriteString(channel, s.name)
+	exit(channel, 0)
+}
+
+func (s *testSSHServer) setBecomePassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.becomePassword = password
+}
+
+// readLine reads a line one byte at a time, leaving what follows unread
+func readLine(r io.Reader) string {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := r.Read(b); err != nil || b[0] == '\n' {
+			return string(line)
+		}
+		line = append(line, b[0])
+	}
+}
+
+func exit(channel ssh.Channel, status uint32) {
+	channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+	channel.Close()
+}
+
+func (s *testSSHServer) lastRequest() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.requests) == 0 {
+		return ""
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// newTestKey returns a new RSA key signer and its PEM encoding
+func newTestKey(t *testing.T) (ssh.Signer, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	return signer, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+// setConfig sets configuration values, the returned function restores previous ones
+func setConfig(values map[string]interface{}) func() {
+	saved := map[string]interface{}{}
+	for key, value := range values {
+		saved[key] = env.Config().Get(key)
+		env.Config().Set(key, value)
+	}
+
+	return func() {
+		for key, value := range saved {
+			env.Config().Set(key, value)
+		}
+	}
+}
+
+// setAuthSock sets SSH_AUTH_SOCK, the returned function restores it
+func setAuthSock(socket string) func() {
+	saved, ok := os.LookupEnv("SSH_AUTH_SOCK")
+	os.Setenv("SSH_AUTH_SOCK", socket)
+
+	return func() {
+		if ok {
+			os.Setenv("SSH_AUTH_SOCK", saved)
+		} else {
+			os.Unsetenv("SSH_AUTH_SOCK")
+		}
+	}
+}
+
+func TestCreateConnectionThroughJumpHosts(t *testing.T) {
+	home, err := ioutil.TempDir("", "cpma-remotehost")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+
+	masterSigner, masterKey := newTestKey(t)
+	jumpSigner, jumpKey := newTestKey(t)
+	masterKeyFile := filepath.Join(home, "master")
+	jumpKeyFile := filepath.Join(home, "jump")
+	require.NoError(t, ioutil.WriteFile(masterKeyFile, masterKey, 0600))
+	require.NoError(t, ioutil.WriteFile(jumpKeyFile, jumpKey, 0600))
+
+	// Jump hosts use their own user and key, the second one defaults to master values
+	bastion := newTestSSHServer(t, "bastion", publicKeyAuth("jumper", jumpSigner.PublicKey()))
+	inner := newTestSSHServer(t, "inner", publicKeyAuth("root", masterSigner.PublicKey()))
+	master := newTestSSHServer(t, "master", publicKeyAuth("root", masterSigner.PublicKey()))
+	defer bastion.listener.Close()
+	defer inner.listener.Close()
+	defer master.listener.Close()
+
+	defer setAuthSock("")()
+	defer setConfig(map[string]interface{}{
+		"home":            home,
+		"SSHLogin":        "root",
+		"SSHPrivateKey":   masterKeyFile,
+		"SSHPort":         master.port(),
+		"InsecureHostKey": true,
+		"JumpHosts": []string{
+			"jumper@127.0.0.1:" + strconv.Itoa(bastion.port()) + "=" + jumpKeyFile,
+			"127.0.0.1:" + strconv.Itoa(inner.port()),
+		},
+	})()
+
+	client, err := CreateConnection("127.0.0.1")
+	require.NoError(t, err)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	require.NoError(t, err)
+	output, err := session.Output("hostname")
+	require.NoError(t, err)
+	assert.Equal(t, "master", string(output))
+
+	// Jump host connections are closed along with the master one
+	bastion.waitConnections(t, 1)
+	inner.waitConnections(t, 1)
+	client.Close()
+	master.waitConnections(t, 0)
+	inner.waitConnections(t, 0)
+	bastion.waitConnections(t, 0)
+
+	// Jump host connections are closed when a later hop fails
+	master.listener.Close()
+	_, err = CreateConnection("127.0.0.1")
+	assert.Error(t, err)
+	inner.waitConnections(t, 0)
+	bastion.waitConnections(t, 0)
+
+	// A jump host refusing the key fails the connection
+	env.Config().Set("JumpHosts", []string{"127.0.0.1:" + strconv.Itoa(bastion.port())})
+	_, err = CreateConnection("127.0.0.1")
+	assert.Error(t, err)
+}
+
+func TestConnectionHops(t *testing.T) {
+	config, err := sshconfig.Read("../../env/sshconfig/testdata/ssh_config")
+	require.NoError(t, err)
+
+	defer func() {
+		env.Config().Set("SSHLogin", "")
+		env.Config().Set("SSHPort", "")
+		env.Config().Set("SSHPrivateKey", "")
+		env.Config().Set("JumpHosts", nil)
+	}()
+	env.Config().Set("SSHLogin", "root")
+	env.Config().Set("SSHPort", "")
+	env.Config().Set("SSHPrivateKey", "/home/user/.ssh/master")
+
+	t.Run("ProxyJump from SSH config", func(t *testing.T) {
+		env.Config().Set("JumpHosts", nil)
+
+		hops, err := connectionHops("master0", config, "/home/user")
+		require.NoError(t, err)
+		assert.Equal(t, []hop{
+			{host: "bastion.example.com", port: 2222, user: "jumper", keyFile: "/home/user/.ssh/bastion"},
+			{host: "inner.example.com", port: 2200, user: "admin", keyFile: "/home/user/.ssh/example"},
+			{host: "master0.cluster1.example.com", port: 22, user: "root", keyFile: "/home/user/.ssh/master"},
+		}, hops)
+	})
+
+	t.Run("JumpHosts take precedence over ProxyJump", func(t *testing.T) {
+		env.Config().Set("JumpHosts", []string{"legacy.example.com=/home/user/.ssh/legacy"})
+
+		hops, err := connectionHops("master0", config, "/home/user")
+		require.NoError(t, err)
+		assert.Equal(t, []hop{
+			{host: "legacy.example.com", port: 22, user: "default", keyFile: "/home/user/.ssh/legacy"},
+			{host: "master0.cluster1.example.com", port: 22, user: "root", keyFile: "/home/user/.ssh/master"},
+		}, hops)
+	})
+
+	t.Run("no SSH config", func(t *testing.T) {
+		env.Config().Set("JumpHosts", []string{"bastion"})
+		env.Config().Set("SSHPort", "2022")
+
+		hops, err := connectionHops("master0", nil, "/home/user")
+		require.NoError(t, err)
+		assert.Equal(t, []hop{
+			{host: "bastion", port: 22, user: "root", keyFile: "/home/user/.ssh/master"},
+			{host: "master0", port: 2022, user: "root", keyFile: "/home/user/.ssh/master"},
+		}, hops)
+	})
+
+	t.Run("master values from SSH config", func(t *testing.T) {
+		env.Config().Set("JumpHosts", nil)
+		env.Config().Set("SSHLogin", "")
+		env.Config().Set("SSHPort", "")
+		env.Config().Set("SSHPrivateKey", "")
+
+		hops, err := connectionHops("bastion", config, "/home/user")
+		require.NoError(t, err)
+		assert.Equal(t, []hop{
+			{host: "bastion.example.com", port: 2222, user: "jumper", keyFile: "/home/user/.ssh/bastion"},
+		}, hops)
+
+		// Defaults apply when neither settings nor SSH config set values
+		hops, err = connectionHops("master0", nil, "/home/user")
+		require.NoError(t, err)
+		assert.Equal(t, []hop{{host: "master0", port: 22, user: "root"}}, hops)
+	})
+}
@@ -0,0 +1,169 @@
+package remotehost
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/env/sshconfig"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+)
+
+// Client Wrapper around sftp.Client
+type Client struct {
+	*sftp.Client
+	session *Session
+	host    string
+	// user is the user files are read as
+	user string
+}
+
+// File is a file read from a remote host along with its attributes
+type File struct {
+	Path    string
+	Content []byte
+	Size    int64
+	Mode    os.FileMode
+	UID     uint32
+	GID     uint32
+	// SHA256 is the hex encoded checksum of Content
+	SHA256 string
+}
+
+// NewSFTPClient starts an SFTP client on host reading files as the become user. The sftp subsystem is used when
+// privileges aren't escalated, otherwise sftp-server set as SFTPServer is run using BecomeMethod.
+func NewSFTPClient(host string) (*Client, error) {
+	b, err := becomeOn(host)
+	if err != nil {
+		return nil, err
+	}
+
+	var p *process
+	if b.method == BecomeNone {
+		p, err = startSubsystem(host, "sftp")
+	} else {
+		server := env.Config().GetString("SFTPServer")
+		if server == "" {
+			server = env.DefaultSFTPServer
+		}
+		p, err = b.start(shellQuote(server))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Cannot start SFTP on %s", host)
+	}
+
+	client, err := sftp.NewClientPipe(p.stdout, p.stdin)
+	if err != nil {
+		p.session.Close()
+		if message := p.output.String(); message != "" {
+			return nil, errors.Errorf("Cannot start SFTP on %s: %s", host, message)
+		}
+		return nil, errors.Wrapf(err, "Cannot start SFTP on %s", host)
+	}
+
+	return &Client{Client: client, session: p.session, host: host, user: b.user}, nil
+}
+
+// startSubsystem starts subsystem on host as the SSH login
+func startSubsystem(host, subsystem string) (*process, error) {
+	p, err := openProcess(host)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.session.RequestSubsystem(subsystem); err != nil {
+		p.session.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Close closes the SFTP client along with its session
+func (c *Client) Close() error {
+	c.Client.Close()
+	return c.session.Close()
+}
+
+// ReadFile reads file along with its attributes, a missing file returns an error satisfying os.IsNotExist and an
+// unreadable one a PermissionError
+func (c *Client) ReadFile(file string) (*File, error) {
+	info, err := c.Stat(file)
+	if err != nil {
+		return nil, c.fileError(file, err)
+	}
+	if info.IsDir() {
+		return nil, errors.Errorf("%s is a directory", file)
+	}
+
+	f, err := c.Open(file)
+	if err != nil {
+		return nil, c.fileError(file, err)
+	}
+	defer f.Close()
+
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(content)
+	remoteFile := &File{
+		Path:    file,
+		Content: content,
+		Size:    info.Size(),
+		Mode:    info.Mode(),
+		SHA256:  hex.EncodeToString(checksum[:]),
+	}
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		remoteFile.UID, remoteFile.GID = stat.UID, stat.GID
+	}
+
+	return remoteFile, nil
+}
+
+// fileError returns a PermissionError when err is caused by missing privileges
+func (c *Client) fileError(file string, err error) error {
+	if IsPermissionDenied(err) {
+		return &PermissionError{Host: c.host, Reason: fmt.Sprintf("%s can't read %s", c.user, file)}
+	}
+
+	return err
+}
+
+// ReadRemoteFile reads file on host using SFTP, a missing file returns an error satisfying os.IsNotExist
+var ReadRemoteFile = func(host, file string) (*File, error) {
+	client, err := NewSFTPClient(host)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	return client.ReadFile(file)
+}
+
+// loginUser returns the user logged in as on host
+func loginUser(host string) (string, error) {
+	home := env.Config().GetString("home")
+	config, err := sshconfig.ReadUser(home)
+	if err != nil {
+		return "", err
+	}
+
+	hops, err := connectionHops(host, config, home)
+	if err != nil {
+		return "", err
+	}
+
+	return hops[len(hops)-1].user, nil
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,92 @@
+package remotehost
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRemoteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-sftp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	signer, key := newTestKey(t)
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, key, 0600))
+
+	binaryFile := filepath.Join(dir, "binary")
+	require.NoError(t, ioutil.WriteFile(binaryFile, []byte{0x00, 0xff, 0xfe, '\n', 0x00}, 0640))
+	emptyFile := filepath.Join(dir, "empty")
+	require.NoError(t, ioutil.WriteFile(emptyFile, nil, 0600))
+
+	testCases := []struct {
+		name            string
+		login           string
+		expectedRequest string
+	}{
+		{
+			name:            "root uses sftp subsystem",
+			login:           "root",
+			expectedRequest: "subsystem sftp",
+		},
+		{
+			name:            "other users run sftp-server using sudo",
+			login:           "admin",
+			expectedRequest: `exec sudo -S -p 'CPMA-BECOME-PASSWORD:' -u 'root' -- sh -c 'printf CPMA-BECOME-READY; '\''/usr/libexec/openssh/sftp-server'\'''`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestSSHServer(t, "master", publicKeyAuth(tc.login, signer.PublicKey()))
+			defer server.listener.Close()
+
+			savedPool := DefaultPool()
+			defaultPool.pool = NewPool(CreateConnection, 0, DefaultMaxSessions)
+			defer func() {
+				defaultPool.pool.Close()
+				defaultPool.pool = savedPool
+			}()
+
+			defer setAuthSock("")()
+			defer setConfig(map[string]interface{}{
+				"home":            dir,
+				"SSHLogin":        tc.login,
+				"SSHPrivateKey":   keyFile,
+				"SSHPort":         server.port(),
+				"InsecureHostKey": true,
+				"JumpHosts":       nil,
+				"SFTPServer":      "",
+			})()
+
+			file, err := ReadRemoteFile("127.0.0.1", binaryFile)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRequest, server.lastRequest())
+			assert.Equal(t, &File{
+				Path:    binaryFile,
+				Content: []byte{0x00, 0xff, 0xfe, '\n', 0x00},
+				Size:    5,
+				Mode:    0640,
+				UID:     uint32(os.Getuid()),
+				GID:     uint32(os.Getgid()),
+				SHA256:  "105849fa91bdfcdf1b7ccac5e7dc0fa08c02856deea786b98a14afcf8788ccba",
+			}, file)
+
+			file, err = ReadRemoteFile("127.0.0.1", emptyFile)
+			require.NoError(t, err)
+			assert.Empty(t, file.Content)
+			assert.Equal(t, int64(0), file.Size)
+
+			_, err = ReadRemoteFile("127.0.0.1", filepath.Join(dir, "missing"))
+			assert.True(t, os.IsNotExist(err))
+
+			_, err = ReadRemoteFile("127.0.0.1", dir)
+			assert.EqualError(t, err, dir+" is a directory")
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package io
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io/remotehost"
+	"github.com/sirupsen/logrus"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// masterUnits are systemd units of the master API, from 3.9 HA and single master installs
+var masterUnits = []string{
+	"atomic-openshift-master-api.service",
+	"origin-master-api.service",
+	"atomic-openshift-master.service",
+	"origin-master.service",
+}
+
+// masterEnvFile is sourced by the master API static pod on 3.10 and later
+const masterEnvFile = "/etc/origin/master/master.env"
+
+// masterPodManifests are static pod manifests of the master on 3.10 and later
+var masterPodManifests = []string{
+	"/etc/origin/node/pods/master-api.yaml",
+	"/etc/origin/node/pods/master-controllers.yaml",
+}
+
+// masterSysconfigFiles are environment files of master units, looked up when files can't be listed on the master
+var masterSysconfigFiles = []string{
+	"/etc/sysconfig/atomic-openshift-master-api",
+	"/etc/sysconfig/origin-master-api",
+	"/etc/sysconfig/atomic-openshift-master",
+	"/etc/sysconfig/origin-master",
+	"/etc/sysconfig/atomic-openshift-master-controllers",
+	"/etc/sysconfig/origin-master-controllers",
+}
+
+// listMasterEnvFiles lists files of the master service environment which exist
+const listMasterEnvFiles = "ls -1d /etc/sysconfig/atomic-openshift-master* /etc/sysconfig/origin-master* " +
+	masterEnvFile + " /etc/origin/node/pods/master-*.yaml 2>/dev/null; true"
+
+// envSource holds variables set by a source of the master service environment, such as an environment file
+type envSource struct {
+	name string
+	vars map[string]string
+}
+
+// serviceEnv is the environment of the master service, sources are listed by precedence
+type serviceEnv []envSource
+
+// lookup returns the value of variable name along with the source setting it
+func (e serviceEnv) lookup(name string) (string, string, bool) {
+	for _, source := range e {
+		if value, ok := source.vars[name]; ok {
+			return value, source.name, true
+		}
+	}
+
+	return "", "", false
+}
+
+// serviceEnvs caches the master service environment by host, it is read once
+var serviceEnvs struct {
+	sync.Mutex
+	hosts map[string]serviceEnv
+}
+
+// masterServiceEnv returns the environment of the master service on host
+func masterServiceEnv(host string) serviceEnv {
+	serviceEnvs.Lock()
+	defer serviceEnvs.Unlock()
+
+	if e, ok := serviceEnvs.hosts[host]; ok {
+		return e
+	}
+
+	e := readServiceEnv(host)
+	if serviceEnvs.hosts == nil {
+		serviceEnvs.hosts = make(map[string]serviceEnv)
+	}
+	serviceEnvs.hosts[host] = e
+	return e
+}
+
+// readServiceEnv reads the master service environment on host. Files are listed and units inspected when commands
+// can be run on host, otherwise known files are looked up. By precedence, the environment is set by:
+//   - master.env, sourced by the master API static pod on 3.10 and later
+//   - env of containers of master static pods
+//   - EnvironmentFile then Environment of master units, the last environment file first
+//   - other environment files in /etc/sysconfig
+func readServiceEnv(host string) serviceEnv {
+	files := orderEnvFiles(nil)
+	var units []unitEnv
+
+	if canRunCommands() {
+		output, err := runOnHost(host, listMasterEnvFiles)
+		if err != nil {
+			logrus.Warnf("Unable to list environment files of the master service on %s: %s", host, err)
+		}
+		files = orderEnvFiles(strings.Fields(output))
+
+		units, err = masterUnitsEnv(host)
+		if err != nil {
+			logrus.Warnf("Unable to read environment of master units on %s: %s", host, err)
+		}
+	}
+
+	var e serviceEnv
+	read := map[string]bool{}
+	readFile := func(file string, parse func(file string, content []byte) []envSource) {
+		if read[file] {
+			return
+		}
+		read[file] = true
+
+		content, err := FetchFile(file)
+		if err != nil {
+			logrus.Debugf("Environment file %s skipped: %s", file, err)
+			return
+		}
+		e = append(e, parse(file, content)...)
+	}
+
+	// master.env is ordered first, it is sourced when containers start
+	for _, file := range files {
+		switch {
+		case file == masterEnvFile:
+			readFile(file, envFileSource)
+		case strings.HasSuffix(file, ".yaml"):
+			readFile(file, podEnv)
+		}
+	}
+
+	for _, unit := range units {
+		for i := len(unit.files) - 1; i >= 0; i-- {
+			readFile(unit.files[i], envFileSource)
+		}
+		e = append(e, envSource{name: unit.name + " Environment", vars: unit.vars})
+	}
+
+	for _, file := range files {
+		readFile(file, envFileSource)
+	}
+
+	return e
+}
+
+// canRunCommands tells if commands can be run on the master
+func canRunCommands() bool {
+	return env.Config().GetBool("FetchFromRemote") || env.Config().GetString("ConfigSource") == "node-exec"
+}
+
+// runOnHost runs cmd on host, over SSH or through a debug pod when config source is node-exec
+func runOnHost(host, cmd string) (string, error) {
+	if env.Config().GetString("ConfigSource") == "node-exec" {
+		return runOnNode(host, cmd)
+	}
+	return remotehost.RunCMD(host, cmd)
+}
+
+// orderEnvFiles sorts listed files following known ones, others are placed last in lexical order. Known files are
+// returned when none are listed.
+func orderEnvFiles(files []string) []string {
+	known := append([]string{masterEnvFile}, masterPodManifests...)
+	known = append(known, masterSysconfigFiles...)
+	if files == nil {
+		return known
+	}
+	rank := func(file string) int {
+		for i, k := range known {
+			if k == file {
+				return i
+			}
+		}
+		return len(known)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if rank(files[i]) != rank(files[j]) {
+			return rank(files[i]) < rank(files[j])
+		}
+		return files[i] < files[j]
+	})
+
+	return files
+}
+
+// unitEnv is the environment of a systemd unit
+type unitEnv struct {
+	name  string
+	files []string
+	vars  map[string]string
+}
+
+// masterUnitsEnv returns the environment of master units which are installed on host
+func masterUnitsEnv(host string) ([]unitEnv, error) {
+	cmd := "systemctl show -p Id -p LoadState -p Environment -p EnvironmentFiles " + strings.Join(masterUnits, " ")
+	output, err := runOnHost(host, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseUnitsEnv(output), nil
+}
+
+// parseUnitsEnv parses the output of systemctl show, units are separated by an empty line
+func parseUnitsEnv(output string) []unitEnv {
+	var units []unitEnv
+	unit := unitEnv{vars: map[string]string{}}
+	loaded := false
+
+	flush := func() {
+		if unit.name != "" && loaded {
+			units = append(units, unit)
+		}
+		unit, loaded = unitEnv{vars: map[string]string{}}, false
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		switch key, value := line[:i], line[i+1:]; key {
+		case "Id":
+			unit.name = value
+		case "LoadState":
+			loaded = value == "loaded"
+		case "Environment":
+			for _, assignment := range splitEnvironment(value) {
+				if j := strings.Index(assignment, "="); j > 0 {
+					unit.vars[assignment[:j]] = assignment[j+1:]
+				}
+			}
+		case "EnvironmentFiles":
+			// Files are shown as path (ignore_errors=yes|no)
+			if j := strings.Index(value, " (ignore_errors="); j >= 0 {
+				value = value[:j]
+			}
+			unit.files = append(unit.files, value)
+		}
+	}
+	flush()
+
+	return units
+}
+
+// splitEnvironment splits the Environment property shown by systemctl, assignments holding spaces are quoted
+func splitEnvironment(value string) []string {
+	var (
+		assignments []string
+		current     strings.Builder
+		quoted      bool
+		escaped     bool
+	)
+
+	for _, r := range value {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if current.Len() > 0 {
+				assignments = append(assignments, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		assignments = append(assignments, current.String())
+	}
+
+	return assignments
+}
+
+// envFileSource parses an environment file as read by systemd or sourced by a shell
+func envFileSource(file string, content []byte) []envSource {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		i := strings.Index(line, "=")
+		if i <= 0 || !envVarName.MatchString(line[:i]) {
+			continue
+		}
+		vars[line[:i]] = unquote(line[i+1:])
+	}
+
+	return []envSource{{name: file, vars: vars}}
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// podEnv returns env values set on containers of a static pod manifest, one source per container
+func podEnv(file string, content []byte) []envSource {
+	pod := &corev1.Pod{}
+	if err := yaml.Unmarshal(content, pod); err != nil {
+		logrus.Warnf("Unable to parse static pod manifest %s: %s", file, err)
+		return nil
+	}
+
+	var sources []envSource
+	for _, container := range pod.Spec.Containers {
+		vars := map[string]string{}
+		for _, v := range container.Env {
+			// Values from secrets or config maps of the cluster can't be read from the master
+			if v.ValueFrom == nil {
+				vars[v.Name] = v.Value
+			}
+		}
+		if len(vars) > 0 {
+			sources = append(sources, envSource{name: fmt.Sprintf("%s (container %s)", filepath.Clean(file), container.Name), vars: vars})
+		}
+	}
+
+	return sources
+}
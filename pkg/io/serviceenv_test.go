@@ -0,0 +1,208 @@
+package io
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io/remotehost"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMaster serves files and the output of systemctl show, as a master would
+type fakeMaster struct {
+	files map[string]string
+	units string
+}
+
+func (m fakeMaster) run(host, cmd string) (string, error) {
+	switch {
+	case strings.HasPrefix(cmd, "ls "):
+		var files []string
+		for file := range m.files {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		return strings.Join(files, "\n") + "\n", nil
+	case strings.HasPrefix(cmd, "systemctl show "):
+		return m.units, nil
+	}
+
+	return "", nil
+}
+
+func (m fakeMaster) read(host, file string) (*remotehost.File, error) {
+	content, ok := m.files[file]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: file, Err: os.ErrNotExist}
+	}
+
+	return &remotehost.File{Path: file, Content: []byte(content), Size: int64(len(content))}, nil
+}
+
+// notFoundUnits is the output of systemctl show for master units which aren't installed
+const notFoundUnits = `Id=origin-master-api.service
+LoadState=not-found
+Environment=
+`
+
+func TestFetchEnvFromServiceEnv(t *testing.T) {
+	testCases := []struct {
+		name            string
+		master          fakeMaster
+		expectedValues  map[string]string
+		expectedRecords []EnvRecord
+	}{
+		{
+			name: "3.9 master units",
+			master: fakeMaster{
+				files: map[string]string{
+					"/etc/sysconfig/atomic-openshift-master-api":          "# Options\nOPTIONS=--loglevel=2\nGITHUB_SECRET=\"github secret\"\nexport LDAP_PASSWORD='api password'\n",
+					"/etc/sysconfig/atomic-openshift-master-api-override": "GITHUB_SECRET=override\n",
+					"/etc/sysconfig/atomic-openshift-master-controllers":  "LDAP_PASSWORD=controllers password\nGITLAB_SECRET=gitlab\n",
+				},
+				units: `Id=atomic-openshift-master-api.service
+LoadState=loaded
+Environment=UNIT_SECRET=unit "QUOTED=a b"
+EnvironmentFiles=/etc/sysconfig/atomic-openshift-master-api (ignore_errors=no)
+EnvironmentFiles=/etc/sysconfig/atomic-openshift-master-api-override (ignore_errors=yes)
+
+` + notFoundUnits,
+			},
+			expectedValues: map[string]string{
+				"GITHUB_SECRET": "override",
+				"LDAP_PASSWORD": "api password",
+				"GITLAB_SECRET": "gitlab",
+				"UNIT_SECRET":   "unit",
+				"QUOTED":        "a b",
+			},
+			expectedRecords: []EnvRecord{
+				{Host: "master0", Name: "GITHUB_SECRET", State: EnvResolved, Source: "/etc/sysconfig/atomic-openshift-master-api-override"},
+				{Host: "master0", Name: "GITLAB_SECRET", State: EnvResolved, Source: "/etc/sysconfig/atomic-openshift-master-controllers"},
+				{Host: "master0", Name: "LDAP_PASSWORD", State: EnvResolved, Source: "/etc/sysconfig/atomic-openshift-master-api"},
+				{Host: "master0", Name: "MISSING", State: EnvUnresolved},
+				{Host: "master0", Name: "QUOTED", State: EnvResolved, Source: "atomic-openshift-master-api.service Environment"},
+				{Host: "master0", Name: "UNIT_SECRET", State: EnvResolved, Source: "atomic-openshift-master-api.service Environment"},
+			},
+		},
+		{
+			name: "3.11 static pods",
+			master: fakeMaster{
+				files: map[string]string{
+					"/etc/origin/master/master.env": "GOOGLE_SECRET=from master.env\n",
+					"/etc/origin/node/pods/master-api.yaml": `apiVersion: v1
+kind: Pod
+metadata:
+  name: master-api
+spec:
+  containers:
+  - name: api
+    env:
+    - name: GOOGLE_SECRET
+      value: from pod
+    - name: KEYSTONE_PASSWORD
+      value: keystone
+    - name: FROM_SECRET
+      valueFrom:
+        secretKeyRef:
+          name: master
+          key: password
+`,
+					"/etc/sysconfig/atomic-openshift-master-api": "KEYSTONE_PASSWORD=stale\n",
+				},
+				units: notFoundUnits,
+			},
+			expectedValues: map[string]string{
+				"GOOGLE_SECRET":     "from master.env",
+				"KEYSTONE_PASSWORD": "keystone",
+			},
+			expectedRecords: []EnvRecord{
+				{Host: "master0", Name: "FROM_SECRET", State: EnvUnresolved},
+				{Host: "master0", Name: "GOOGLE_SECRET", State: EnvResolved, Source: "/etc/origin/master/master.env"},
+				{Host: "master0", Name: "KEYSTONE_PASSWORD", State: EnvResolved, Source: "/etc/origin/node/pods/master-api.yaml (container api)"},
+				{Host: "master0", Name: "MISSING", State: EnvUnresolved},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			workDir, err := ioutil.TempDir("", "cpma-serviceenv")
+			require.NoError(t, err)
+			defer os.RemoveAll(workDir)
+
+			env.Config().Set("FetchFromRemote", true)
+			env.Config().Set("Hostname", "master0")
+			env.Config().Set("WorkDir", workDir)
+			defer func() {
+				env.Config().Set("FetchFromRemote", false)
+				env.Config().Set("Hostname", "")
+				env.Config().Set("WorkDir", "")
+			}()
+
+			defer func() { remotehost.RunCMD, remotehost.ReadRemoteFile = _RunCMD, _ReadRemoteFile }()
+			remotehost.RunCMD, remotehost.ReadRemoteFile = tc.master.run, tc.master.read
+			ResetFetchedEnv()
+			defer ResetFetchedEnv()
+			ResetFetchedFiles()
+			defer ResetFetchedFiles()
+
+			for name, expected := range tc.expectedValues {
+				value, err := FetchEnv("master0", name)
+				require.NoError(t, err)
+				assert.Equal(t, expected, value, name)
+			}
+			for _, record := range tc.expectedRecords {
+				if record.State == EnvUnresolved {
+					_, err := FetchEnv("master0", record.Name)
+					assert.EqualError(t, err, "Env variable "+record.Name+" isn't set in the environment of the master service on master0")
+				}
+			}
+
+			assert.Equal(t, tc.expectedRecords, FetchedEnv())
+		})
+	}
+}
+
+func TestFetchEnvFromLocalCopies(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "cpma-serviceenv")
+	require.NoError(t, err)
+	defer os.RemoveAll(workDir)
+
+	sysconfig := filepath.Join(workDir, "master0", "etc", "sysconfig", "atomic-openshift-master")
+	require.NoError(t, os.MkdirAll(filepath.Dir(sysconfig), 0750))
+	require.NoError(t, ioutil.WriteFile(sysconfig, []byte("OPENID_SECRET=openid\n"), 0640))
+
+	env.Config().Set("ConfigSource", "local")
+	env.Config().Set("FetchFromRemote", false)
+	env.Config().Set("Hostname", "master0")
+	env.Config().Set("WorkDir", workDir)
+	defer func() {
+		env.Config().Set("ConfigSource", "")
+		env.Config().Set("Hostname", "")
+		env.Config().Set("WorkDir", "")
+	}()
+	os.Setenv("CPMA_TEST_LOCAL_SECRET", "local")
+	defer os.Unsetenv("CPMA_TEST_LOCAL_SECRET")
+	ResetFetchedEnv()
+	defer ResetFetchedEnv()
+
+	value, err := FetchEnv("master0", "OPENID_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "openid", value)
+
+	// Variables missing from copies are read from the local environment
+	value, err = FetchEnv("master0", "CPMA_TEST_LOCAL_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "local", value)
+
+	assert.Equal(t, []EnvRecord{
+		{Host: "master0", Name: "CPMA_TEST_LOCAL_SECRET", State: EnvResolved, Source: "local environment"},
+		{Host: "master0", Name: "OPENID_SECRET", State: EnvResolved, Source: "/etc/sysconfig/atomic-openshift-master"},
+	}, FetchedEnv())
+}
@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/konveyor/cpma/pkg/decode"
 	"github.com/konveyor/cpma/pkg/env"
-	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/apicert"
 	"github.com/konveyor/cpma/pkg/transform/reportoutput"
 	"github.com/sirupsen/logrus"
@@ -115,19 +113,14 @@ func (e APIExtraction) buildReportOutput() {
 			})
 	}
 
-	FinalReportOutput.Report.ComponentReports = append(FinalReportOutput.Report.ComponentReports, componentReport)
+	FinalReportOutput.AddComponentReport(componentReport)
 
 }
 
 // Extract collects API configuration from an OCP3 cluster
 func (e APITransform) Extract() (Extraction, error) {
 	logrus.Info("APITransform::Extract")
-	content, err := io.FetchFile(env.Config().GetString("MasterConfigFile"))
-	if err != nil {
-		return nil, err
-	}
-
-	masterConfig, err := decode.MasterConfig(content)
+	masterConfig, err := SourceSnapshot.MasterConfig()
 	if err != nil {
 		return nil, err
 	}
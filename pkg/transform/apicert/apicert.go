@@ -41,7 +41,7 @@ func getCert(certName, keyName string) ([]byte, []byte) {
 		return nil, nil
 	}
 
-	keyContent, err := io.FetchFile(keyFile)
+	keyContent, err := io.FetchSecretFile(keyFile)
 	if err != nil {
 		return nil, nil
 	}
@@ -58,7 +58,7 @@ func (e ClusterExtraction) Transform() ([]Output, error) {
 			StorageClassList:     e.StorageClassList,
 		})
 
-		FinalReportOutput.Report.ClusterReport = clusterReport
+		FinalReportOutput.SetClusterReport(clusterReport)
 	}
 
 	return outputs, nil
@@ -99,8 +99,12 @@ func (e ClusterExtraction) buildManifestOutput() (Output, error) {
 // Validate no need to validate it, data is exctracted from API
 func (e ClusterExtraction) Validate() (err error) { return }
 
-// Extract collects data for cluster report
+// Extract collects data for cluster report, from the cluster dump when one is set
 func (e ClusterTransform) Extract() (Extraction, error) {
+	if env.Config().GetString("ClusterDump") != "" {
+		return e.extractFromDump()
+	}
+
 	chanNodes := make(chan *k8sapicore.NodeList)
 	chanClusterQuotas := make(chan *o7tapiquota.ClusterResourceQuotaList)
 	chanNamespaces := make(chan *k8sapicore.NamespaceList)
@@ -113,7 +117,12 @@ func (e ClusterTransform) Extract() (Extraction, error) {
 	chanSecurityContextConstraints := make(chan *o7tapisecurity.SecurityContextConstraintsList)
 
 	go api.ListNamespaces(api.K8sClient, chanNamespaces)
-	go api.ListNodes(api.K8sClient, chanNodes)
+	var nodesErr error
+	go func() {
+		nodeList, err := api.ListNodes(api.K8sClient)
+		nodesErr = err
+		chanNodes <- nodeList
+	}()
 	go api.ListQuotas(api.O7tClient, chanClusterQuotas)
 	go api.ListPVs(api.K8sClient, chanPVs)
 	go api.ListUsers(api.O7tClient, chanUsers)
@@ -168,10 +177,28 @@ func (e ClusterTransform) Extract() (Extraction, error) {
 	extraction.RBACResources.ClusterRolesBindingsList = <-chanClusterRolesListBindings
 	extraction.RBACResources.SecurityContextConstraintsList = <-chanSecurityContextConstraints
 	extraction.StorageClassList = <-chanStorageClassList
+	if nodesErr != nil {
+		return nil, nodesErr
+	}
 
 	return *extraction, nil
 }
 
+// extractFromDump collects data for cluster report from API resources dumped to files
+func (e ClusterTransform) extractFromDump() (Extraction, error) {
+	dump, err := SourceSnapshot.ClusterDump()
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := dump.Resources()
+	if err != nil {
+		return nil, err
+	}
+
+	return ClusterExtraction{Resources: *resources}, nil
+}
+
 // Name returns a human readable name for the transform
 func (e ClusterTransform) Name() string {
 	return ClusterTransformName
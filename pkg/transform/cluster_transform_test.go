@@ -57,3 +57,58 @@ func TestClusterExtractionTransform(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, expectedClusterReportJSON, actualClusterReportJSON)
 }
+
+func TestClusterTransformExtractFromDump(t *testing.T) {
+	// Same resources as a live cluster would return, namespaced ones belong to namespacetest1
+	namespaceResources := cpmatest.CreateTestNameSpaceList()[0]
+	namespaceResources.NamespaceName = "namespacetest1"
+	liveExtraction := transform.ClusterExtraction{api.Resources{
+		QuotaList:            cpmatest.CreateTestClusterQuotaList(),
+		PersistentVolumeList: cpmatest.CreateTestPVList(),
+		NodeList:             cpmatest.CreateTestNodeList(),
+		StorageClassList:     cpmatest.CreateStorageClassList(),
+		NamespaceList:        []api.NamespaceResources{namespaceResources},
+		RBACResources: api.RBACResources{
+			UsersList:                      cpmatest.CreateUserList(),
+			GroupList:                      cpmatest.CreateGroupList(),
+			ClusterRolesList:               cpmatest.CreateClusterRoleList(),
+			ClusterRolesBindingsList:       cpmatest.CreateClusterRoleBindingsList(),
+			SecurityContextConstraintsList: cpmatest.CreateSCCList(),
+		},
+	}}
+
+	env.Config().Set("Reporting", true)
+	env.Config().Set("Manifests", true)
+	env.Config().Set("ClusterDump", "../api/testdata/cluster-dump")
+	defer env.Config().Set("ClusterDump", "")
+	transform.SourceSnapshot = transform.NewSnapshot()
+
+	dumpExtraction, err := transform.ClusterTransform{}.Extract()
+	require.NoError(t, err)
+
+	transform.FinalReportOutput = transform.Report{}
+	dumpOutput, err := dumpExtraction.Transform()
+	require.NoError(t, err)
+	dumpReport := transform.FinalReportOutput.Report.ClusterReport
+
+	transform.FinalReportOutput = transform.Report{}
+	liveOutput, err := liveExtraction.Transform()
+	require.NoError(t, err)
+	liveReport := transform.FinalReportOutput.Report.ClusterReport
+
+	manifests := dumpOutput[0].(transform.ManifestOutput).Manifests
+	assert.Equal(t, liveOutput[0].(transform.ManifestOutput).Manifests, manifests)
+	expectedClusterQuotaCRD, err := ioutil.ReadFile("testdata/expected-CR-cluster-quota.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "100_CPMA-cluster-quota-resource-test-quota1.yaml", manifests[0].Name)
+	assert.Equal(t, expectedClusterQuotaCRD, manifests[0].CRD)
+
+	require.Len(t, dumpReport.Namespaces, 1)
+	assert.Len(t, dumpReport.Namespaces[0].Pods, 2)
+	assert.Len(t, dumpReport.Nodes, 1)
+	dumpReportJSON, err := json.MarshalIndent(dumpReport, "", " ")
+	require.NoError(t, err)
+	liveReportJSON, err := json.MarshalIndent(liveReport, "", " ")
+	require.NoError(t, err)
+	assert.Equal(t, string(liveReportJSON), string(dumpReportJSON))
+}
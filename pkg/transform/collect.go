@@ -0,0 +1,226 @@
+package transform
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/konveyor/cpma/pkg/api"
+	"github.com/konveyor/cpma/pkg/bundle"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io"
+	"github.com/konveyor/cpma/pkg/transform/node"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// BundleConfigKeys are configuration values saved in a bundle, needed to analyze it
+var BundleConfigKeys = []string{
+	"ClusterName",
+	"CrioConfigFile",
+	"ETCDConfigFile",
+	"Hostname",
+	"MasterConfigFile",
+	"NodeConfigFile",
+	"RegistriesConfigFile",
+}
+
+// Collect runs every registered transform to gather the source files they fetch,
+// along with API resources, into a bundle. Outputs are discarded.
+func Collect(cpmaVersion string) (*bundle.Bundle, error) {
+	logrus.Info("Starting collection")
+
+	config := make(map[string]string)
+	for _, key := range BundleConfigKeys {
+		config[key] = env.Config().GetString(key)
+	}
+	b := bundle.New(cpmaVersion, config)
+
+	// Record every fetched file
+	fetchFile := io.FetchFile
+	defer func() { io.FetchFile = fetchFile }()
+	io.FetchFile = func(src string) ([]byte, error) {
+		content, err := fetchFile(src)
+		if err == nil {
+			b.AddSourceFile(src, content)
+		}
+		return content, err
+	}
+
+	SourceSnapshot = NewSnapshot()
+	FinalReportOutput = Report{}
+	defer func() { FinalReportOutput = Report{} }()
+
+	var resources *api.Resources
+	for _, transform := range transformRegistry {
+		logrus.Infof("Collect:Starting for - %s", transform.Name())
+
+		extraction, err := transform.Extract()
+		if err != nil {
+			logrus.Warnf("Collect:%s: %s", transform.Name(), err)
+			continue
+		}
+
+		if clusterExtraction, ok := extraction.(ClusterExtraction); ok {
+			resources = &clusterExtraction.Resources
+		}
+
+		// Some files are only fetched while transforming
+		if err := extraction.Validate(); err != nil {
+			logrus.Warnf("Collect:%s: %s", transform.Name(), err)
+			continue
+		}
+		if _, err := extraction.Transform(); err != nil {
+			logrus.Warnf("Collect:%s: %s", transform.Name(), err)
+		}
+	}
+
+	if resources == nil {
+		return nil, errors.New("API resources could not be collected")
+	}
+
+	if err := collectResources(b, resources); err != nil {
+		return nil, err
+	}
+
+	logrus.Info("Succesfully finished collection")
+	return b, nil
+}
+
+// resourceList is an API resources list saved to the bundle under name
+type resourceList struct {
+	name       string
+	apiVersion string
+	kind       string
+	list       interface{}
+}
+
+// collectResources adds API resources lists to the bundle, in the cluster dump layout
+func collectResources(b *bundle.Bundle, resources *api.Resources) error {
+	configMaps, _, err := NodeTransform{}.listNodeResources()
+	if err != nil {
+		return errors.Wrap(err, "Failed to collect node groups")
+	}
+
+	lists := []resourceList{
+		{name: "nodes.yaml", apiVersion: "v1", kind: "NodeList", list: resources.NodeList},
+		{name: "clusterresourcequotas.yaml", apiVersion: "quota.openshift.io/v1", kind: "ClusterResourceQuotaList", list: resources.QuotaList},
+		{name: "persistentvolumes.yaml", apiVersion: "v1", kind: "PersistentVolumeList", list: resources.PersistentVolumeList},
+		{name: "storageclasses.yaml", apiVersion: "storage.k8s.io/v1", kind: "StorageClassList", list: resources.StorageClassList},
+		{name: "users.yaml", apiVersion: "user.openshift.io/v1", kind: "UserList", list: resources.RBACResources.UsersList},
+		{name: "groups.yaml", apiVersion: "user.openshift.io/v1", kind: "GroupList", list: resources.RBACResources.GroupList},
+		{name: "clusterroles.yaml", apiVersion: "authorization.openshift.io/v1", kind: "ClusterRoleList", list: resources.RBACResources.ClusterRolesList},
+		{name: "clusterrolebindings.yaml", apiVersion: "authorization.openshift.io/v1", kind: "ClusterRoleBindingList", list: resources.RBACResources.ClusterRolesBindingsList},
+		{name: "securitycontextconstraints.yaml", apiVersion: "security.openshift.io/v1", kind: "SecurityContextConstraintsList", list: resources.RBACResources.SecurityContextConstraintsList},
+		{name: path.Join("namespaces", node.GroupNamespace, "configmaps.yaml"), apiVersion: "v1", kind: "ConfigMapList", list: configMaps},
+	}
+
+	var namespaces []map[string]interface{}
+	for _, namespace := range resources.NamespaceList {
+		namespaces = append(namespaces, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]string{"name": namespace.NamespaceName},
+		})
+
+		dir := path.Join("namespaces", namespace.NamespaceName)
+		lists = append(lists, []resourceList{
+			{name: path.Join(dir, "daemonsets.yaml"), apiVersion: "extensions/v1beta1", kind: "DaemonSetList", list: namespace.DaemonSetList},
+			{name: path.Join(dir, "deployments.yaml"), apiVersion: "apps/v1beta1", kind: "DeploymentList", list: namespace.DeploymentList},
+			{name: path.Join(dir, "pods.yaml"), apiVersion: "v1", kind: "PodList", list: namespace.PodList},
+			{name: path.Join(dir, "resourcequotas.yaml"), apiVersion: "v1", kind: "ResourceQuotaList", list: namespace.ResourceQuotaList},
+			{name: path.Join(dir, "roles.yaml"), apiVersion: "authorization.openshift.io/v1", kind: "RoleList", list: namespace.RolesList},
+			{name: path.Join(dir, "routes.yaml"), apiVersion: "route.openshift.io/v1", kind: "RouteList", list: namespace.RouteList},
+			{name: path.Join(dir, "persistentvolumeclaims.yaml"), apiVersion: "v1", kind: "PersistentVolumeClaimList", list: namespace.PVCList},
+		}...)
+	}
+
+	namespacesContent, err := yaml.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": namespaces})
+	if err != nil {
+		return err
+	}
+	b.AddResources("namespaces.yaml", namespacesContent)
+
+	for _, list := range lists {
+		content, err := marshalList(list.apiVersion, list.kind, list.list)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to collect %s", list.kind)
+		}
+		b.AddResources(list.name, content)
+	}
+
+	return nil
+}
+
+// marshalList marshals a typed list, setting its kind which API clients leave empty
+func marshalList(apiVersion, kind string, list interface{}) ([]byte, error) {
+	content, err := yaml.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(content, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields["apiVersion"] = apiVersion
+	fields["kind"] = kind
+
+	return yaml.Marshal(fields)
+}
+
+// ConfigureBundleAnalysis unpacks a bundle into the work directory, the same way files are laid out in local mode,
+// and configures a local and offline run against it
+func ConfigureBundleAnalysis(b *bundle.Bundle) error {
+	// The manifest comes from the bundle, only known keys are trusted
+	for _, key := range BundleConfigKeys {
+		if value, ok := b.Config[key]; ok {
+			env.Config().Set(key, value)
+		}
+	}
+
+	hostname := env.Config().GetString("Hostname")
+	if err := validateBundleHostname(hostname); err != nil {
+		return err
+	}
+
+	workDir := env.Config().GetString("WorkDir")
+	if workDir == "" {
+		workDir = "."
+		env.Config().Set("WorkDir", workDir)
+	}
+
+	sourceDir := filepath.Join(workDir, hostname)
+	clusterDumpDir := filepath.Join(workDir, "cluster-dump", hostname)
+
+	// Resources of a previous analysis must not mix with the bundle ones
+	if err := os.RemoveAll(clusterDumpDir); err != nil {
+		return err
+	}
+	if err := b.Unpack(sourceDir, clusterDumpDir); err != nil {
+		return errors.Wrap(err, "Failed to unpack bundle")
+	}
+
+	env.Config().Set("ConfigSource", "local")
+	env.Config().Set("FetchFromRemote", false)
+	env.Config().Set("ClusterDump", clusterDumpDir)
+
+	logrus.Infof("Bundle collected on %s by CPMA %s unpacked to %s", b.Created.Format("2006-01-02 15:04:05"), b.CPMAVersion, workDir)
+	return nil
+}
+
+// validateBundleHostname checks the bundle host name is a single path element,
+// it names directories of the work directory that are removed and written to
+func validateBundleHostname(hostname string) error {
+	if hostname == "" || hostname == "." || hostname == ".." ||
+		filepath.Base(hostname) != hostname || strings.ContainsAny(hostname, `/\`) {
+		return errors.Errorf("Bundle Hostname %q is not a valid host name", hostname)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package transform_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/bundle"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/transform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigureBundleAnalysis(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      map[string]string
+		expectedErr string
+	}{
+		{
+			name:   "unpack bundle",
+			config: map[string]string{"Hostname": "master-0.example.com", "MasterConfigFile": "/etc/origin/master/master-config.yaml"},
+		},
+		{
+			name:        "hostname traversal",
+			config:      map[string]string{"Hostname": "../../x"},
+			expectedErr: `Bundle Hostname "../../x" is not a valid host name`,
+		},
+		{
+			name:        "hostname parent directory",
+			config:      map[string]string{"Hostname": ".."},
+			expectedErr: `Bundle Hostname ".." is not a valid host name`,
+		},
+		{
+			name:        "missing hostname",
+			config:      map[string]string{},
+			expectedErr: `Bundle Hostname "" is not a valid host name`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parentDir, err := ioutil.TempDir("", "cpma-bundle")
+			require.NoError(t, err)
+			defer os.RemoveAll(parentDir)
+			workDir := filepath.Join(parentDir, "a", "work")
+			require.NoError(t, os.MkdirAll(workDir, 0750))
+
+			// Analysis settings must not leak into other tests
+			for _, key := range []string{"WorkDir", "Hostname", "MasterConfigFile", "ConfigSource", "FetchFromRemote", "ClusterDump"} {
+				defer env.Config().Set(key, env.Config().Get(key))
+			}
+			env.Config().Set("WorkDir", workDir)
+			env.Config().Set("Hostname", "")
+
+			// Keys which are not bundle configuration keys are ignored
+			config := map[string]string{"WorkDir": parentDir}
+			for key, value := range tc.config {
+				config[key] = value
+			}
+			b := bundle.New("test", config)
+			b.AddSourceFile("/etc/origin/master/master-config.yaml", []byte("kind: MasterConfig\n"))
+			b.AddResources("nodes.yaml", []byte("kind: NodeList\n"))
+
+			err = transform.ConfigureBundleAnalysis(b)
+			assert.Equal(t, workDir, env.Config().GetString("WorkDir"))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+
+				// Nothing is written
+				entries, err := ioutil.ReadDir(parentDir)
+				require.NoError(t, err)
+				require.Len(t, entries, 1)
+				entries, err = ioutil.ReadDir(workDir)
+				require.NoError(t, err)
+				assert.Empty(t, entries)
+				return
+			}
+			require.NoError(t, err)
+
+			hostname := tc.config["Hostname"]
+			assert.FileExists(t, filepath.Join(workDir, hostname, "etc", "origin", "master", "master-config.yaml"))
+			assert.FileExists(t, filepath.Join(workDir, "cluster-dump", hostname, "nodes.yaml"))
+			assert.Equal(t, filepath.Join(workDir, "cluster-dump", hostname), env.Config().GetString("ClusterDump"))
+		})
+	}
+}
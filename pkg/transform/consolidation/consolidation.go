@@ -0,0 +1,566 @@
+package consolidation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/konveyor/cpma/pkg/transform"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
+	"github.com/konveyor/cpma/pkg/transform/scheduler"
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/pkg/errors"
+)
+
+// Cluster-scoped manifests merged into a single one
+const (
+	oauthManifest     = "100_CPMA-cluster-config-oauth.yaml"
+	imageManifest     = "100_CPMA-cluster-config-image.yaml"
+	projectManifest   = "100_CPMA-cluster-config-project.yaml"
+	schedulerManifest = "100_CPMA-cluster-config-scheduler.yaml"
+	networkManifest   = "100_CPMA-cluster-config-sdn.yaml"
+)
+
+// Prefixes of openshift-config secrets and configmaps referenced by merged manifests
+const (
+	secretManifestPrefix    = "100_CPMA-cluster-config-secret-"
+	configMapManifestPrefix = "100_CPMA-cluster-config-configmap-"
+)
+
+// policyConfigMapManifest is the only openshift-config resource which isn't generated by OAuth
+var policyConfigMapManifest = configMapManifestPrefix + scheduler.PolicyConfigMapName + ".yaml"
+
+// Source is a cluster whose generated manifests are consolidated
+type Source struct {
+	Cluster      string
+	ManifestsDir string
+	// FailedComponents are components which failed on the cluster, their manifests are left out
+	FailedComponents []string
+	// SkippedComponents are components which didn't run on the cluster, their manifests are left out
+	SkippedComponents []string
+}
+
+// excludes tells whether manifest name was generated by a component which failed or didn't run on the source cluster
+func (s Source) excludes(name string) bool {
+	component := manifestComponent(name)
+	for _, excluded := range append(append([]string{}, s.FailedComponents...), s.SkippedComponents...) {
+		if excluded == component {
+			return true
+		}
+	}
+
+	return false
+}
+
+// manifestComponent returns the component generating manifest name, empty when it isn't known
+func manifestComponent(name string) string {
+	switch {
+	case name == imageManifest:
+		return transform.ImageComponentName
+	case name == projectManifest:
+		return transform.ProjectComponentName
+	case name == schedulerManifest, name == policyConfigMapManifest:
+		return transform.SchedulerComponentName
+	case name == networkManifest:
+		return transform.SDNComponentName
+	case name == oauthManifest, strings.HasPrefix(name, secretManifestPrefix), strings.HasPrefix(name, configMapManifestPrefix):
+		return transform.OAuthComponentName
+	}
+
+	return ""
+}
+
+// sourceValue is the value of a field in the manifest of a cluster
+type sourceValue struct {
+	cluster string
+	value   interface{}
+}
+
+// consolidation holds merged manifests along with conflicts found while merging
+type consolidation struct {
+	sources   []Source
+	manifests []transform.Manifest
+	conflicts []reportoutput.FleetConflict
+}
+
+// Consolidate merges cluster-scoped manifests of several clusters into manifests for a single OCP4 cluster.
+// When clusters have different values for the same setting, a conflict holding every value is reported
+// and the value of the first source is used.
+func Consolidate(sources []Source) ([]transform.Manifest, []reportoutput.FleetConflict, error) {
+	c := &consolidation{sources: sources}
+
+	for _, merge := range []func() error{
+		c.mergeOAuth,
+		c.mergeConfigResources,
+		c.mergeImage,
+		c.mergeProject,
+		c.mergeScheduler,
+		c.mergeNetwork,
+	} {
+		if err := merge(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return c.manifests, c.conflicts, nil
+}
+
+// mergeOAuth merges identity providers of every cluster, providers with the same name must be identical
+func (c *consolidation) mergeOAuth() error {
+	var merged *configv1.OAuth
+	var tokenConfigs, templates []sourceValue
+	providers := make(map[string][]sourceValue)
+	var providerNames []string
+
+	for _, source := range c.sources {
+		oauth := &configv1.OAuth{}
+		found, err := readManifest(source, oauthManifest, oauth)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		if merged == nil {
+			merged = oauth.DeepCopy()
+			merged.Spec.IdentityProviders = nil
+		}
+
+		tokenConfigs = append(tokenConfigs, sourceValue{source.Cluster, oauth.Spec.TokenConfig})
+		templates = append(templates, sourceValue{source.Cluster, oauth.Spec.Templates})
+
+		for _, provider := range oauth.Spec.IdentityProviders {
+			if _, ok := providers[provider.Name]; !ok {
+				providerNames = append(providerNames, provider.Name)
+				merged.Spec.IdentityProviders = append(merged.Spec.IdentityProviders, provider)
+			}
+			providers[provider.Name] = append(providers[provider.Name], sourceValue{source.Cluster, provider})
+		}
+	}
+
+	if merged == nil {
+		return nil
+	}
+
+	for _, name := range providerNames {
+		c.compare("OAuth", fmt.Sprintf("spec.identityProviders[name=%s]", name),
+			"Identity provider name is used by several clusters with different settings", providers[name])
+	}
+	c.compare("OAuth", "spec.tokenConfig", "", tokenConfigs)
+	c.compare("OAuth", "spec.templates", "", templates)
+
+	return c.addManifest(oauthManifest, merged)
+}
+
+// mergeConfigResources gathers openshift-config secrets and configmaps, those with the same name must be identical
+func (c *consolidation) mergeConfigResources() error {
+	contents := make(map[string][]sourceValue)
+	manifests := make(map[string][]byte)
+	// versions holds distinct contents of each resource, in the order they are found
+	versions := make(map[string][]string)
+	var names []string
+
+	for _, source := range c.sources {
+		files, err := ioutil.ReadDir(source.ManifestsDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Failed to read manifests of %s", source.Cluster)
+		}
+
+		for _, file := range files {
+			name := file.Name()
+			if !strings.HasPrefix(name, secretManifestPrefix) && !strings.HasPrefix(name, configMapManifestPrefix) {
+				continue
+			}
+			if source.excludes(name) {
+				continue
+			}
+
+			content, err := ioutil.ReadFile(filepath.Join(source.ManifestsDir, name))
+			if err != nil {
+				return errors.Wrapf(err, "Failed to read manifest %s of %s", name, source.Cluster)
+			}
+
+			if _, ok := manifests[name]; !ok {
+				names = append(names, name)
+				manifests[name] = content
+			}
+			// Secret values mustn't end up in reports, not even as checksums which could reveal short passwords.
+			// Contents are numbered instead, clusters with the same number have the same contents.
+			version := indexOf(versions[name], string(content))
+			if version < 0 {
+				versions[name] = append(versions[name], string(content))
+				version = len(versions[name]) - 1
+			}
+			contents[name] = append(contents[name], sourceValue{source.Cluster, fmt.Sprintf("contents #%d", version+1)})
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		kind := "ConfigMap"
+		resource := strings.TrimSuffix(strings.TrimPrefix(name, configMapManifestPrefix), ".yaml")
+		if strings.HasPrefix(name, secretManifestPrefix) {
+			kind = "Secret"
+			resource = strings.TrimSuffix(strings.TrimPrefix(name, secretManifestPrefix), ".yaml")
+		}
+
+		c.compare(kind, "openshift-config/"+resource, "Resource is generated by several clusters with different contents", contents[name])
+		c.manifests = append(c.manifests, transform.Manifest{Name: name, CRD: manifests[name]})
+	}
+
+	return nil
+}
+
+// mergeImage merges registry lists, allowed and blocked registries are mutually exclusive
+func (c *consolidation) mergeImage() error {
+	var merged *configv1.Image
+	var trustedCAs, registrySources []sourceValue
+	importLocations := make(map[string][]sourceValue)
+	var importDomains []string
+
+	for _, source := range c.sources {
+		image := &configv1.Image{}
+		found, err := readManifest(source, imageManifest, image)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		if merged == nil {
+			merged = image.DeepCopy()
+			merged.Spec.AllowedRegistriesForImport = nil
+		}
+
+		for _, location := range image.Spec.AllowedRegistriesForImport {
+			if _, ok := importLocations[location.DomainName]; !ok {
+				importDomains = append(importDomains, location.DomainName)
+				merged.Spec.AllowedRegistriesForImport = append(merged.Spec.AllowedRegistriesForImport, location)
+			}
+			importLocations[location.DomainName] = append(importLocations[location.DomainName], sourceValue{source.Cluster, location})
+		}
+
+		spec := &merged.Spec
+		spec.ExternalRegistryHostnames = union(spec.ExternalRegistryHostnames, image.Spec.ExternalRegistryHostnames)
+		spec.RegistrySources.InsecureRegistries = union(spec.RegistrySources.InsecureRegistries, image.Spec.RegistrySources.InsecureRegistries)
+		spec.RegistrySources.BlockedRegistries = union(spec.RegistrySources.BlockedRegistries, image.Spec.RegistrySources.BlockedRegistries)
+		spec.RegistrySources.AllowedRegistries = union(spec.RegistrySources.AllowedRegistries, image.Spec.RegistrySources.AllowedRegistries)
+
+		trustedCAs = append(trustedCAs, sourceValue{source.Cluster, image.Spec.AdditionalTrustedCA})
+		registrySources = append(registrySources, sourceValue{source.Cluster, image.Spec.RegistrySources})
+	}
+
+	if merged == nil {
+		return nil
+	}
+
+	for _, domain := range importDomains {
+		c.compare("Image", fmt.Sprintf("spec.allowedRegistriesForImport[domainName=%s]", domain), "", importLocations[domain])
+	}
+	c.compare("Image", "spec.additionalTrustedCA", "", trustedCAs)
+
+	// Merged blocked registries would be ignored by clusters only allowing some registries
+	if sources := merged.Spec.RegistrySources; len(sources.AllowedRegistries) > 0 && len(sources.BlockedRegistries) > 0 {
+		c.addConflict("Image", "spec.registrySources",
+			"allowedRegistries and blockedRegistries can't be both set, they are merged from different clusters", registrySources)
+	}
+
+	return c.addManifest(imageManifest, merged)
+}
+
+// mergeProject uses project request settings shared by every cluster
+func (c *consolidation) mergeProject() error {
+	var merged *configv1.Project
+	var messages, templates []sourceValue
+
+	for _, source := range c.sources {
+		project := &configv1.Project{}
+		found, err := readManifest(source, projectManifest, project)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		if merged == nil {
+			merged = project
+		}
+		messages = append(messages, sourceValue{source.Cluster, project.Spec.ProjectRequestMessage})
+		templates = append(templates, sourceValue{source.Cluster, project.Spec.ProjectRequestTemplate})
+	}
+
+	if merged == nil {
+		return nil
+	}
+
+	c.compare("Project", "spec.projectRequestMessage", "", messages)
+	c.compare("Project", "spec.projectRequestTemplate", "", templates)
+
+	return c.addManifest(projectManifest, merged)
+}
+
+// mergeScheduler uses scheduler settings shared by every cluster
+func (c *consolidation) mergeScheduler() error {
+	var merged *configv1.Scheduler
+	var nodeSelectors, policies []sourceValue
+
+	for _, source := range c.sources {
+		scheduler := &configv1.Scheduler{}
+		found, err := readManifest(source, schedulerManifest, scheduler)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		if merged == nil {
+			merged = scheduler
+		}
+		nodeSelectors = append(nodeSelectors, sourceValue{source.Cluster, scheduler.Spec.DefaultNodeSelector})
+		policies = append(policies, sourceValue{source.Cluster, scheduler.Spec.Policy})
+	}
+
+	if merged == nil {
+		return nil
+	}
+
+	c.compare("Scheduler", "spec.defaultNodeSelector", "", nodeSelectors)
+	c.compare("Scheduler", "spec.policy", "", policies)
+
+	return c.addManifest(schedulerManifest, merged)
+}
+
+// mergeNetwork uses network settings shared by every cluster, a single OCP4 cluster has a single set of networks
+func (c *consolidation) mergeNetwork() error {
+	var merged *operatorv1.Network
+	var clusterNetworks, serviceNetworks []networkValue
+	var defaultNetworks []sourceValue
+
+	for _, source := range c.sources {
+		network := &operatorv1.Network{}
+		found, err := readManifest(source, networkManifest, network)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		if merged == nil {
+			merged = network
+		}
+		var clusterCIDRs, clusterKeys []string
+		for _, entry := range network.Spec.ClusterNetwork {
+			clusterCIDRs = append(clusterCIDRs, entry.CIDR)
+			clusterKeys = append(clusterKeys, fmt.Sprintf("hostPrefix=%d", entry.HostPrefix))
+		}
+		clusterNetwork, err := newNetworkValue(source.Cluster, network.Spec.ClusterNetwork, clusterCIDRs, clusterKeys)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid cluster network in manifest %s of %s", networkManifest, source.Cluster)
+		}
+		serviceNetwork, err := newNetworkValue(source.Cluster, network.Spec.ServiceNetwork, network.Spec.ServiceNetwork, nil)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid service network in manifest %s of %s", networkManifest, source.Cluster)
+		}
+
+		clusterNetworks = append(clusterNetworks, clusterNetwork)
+		serviceNetworks = append(serviceNetworks, serviceNetwork)
+		defaultNetworks = append(defaultNetworks, sourceValue{source.Cluster, network.Spec.DefaultNetwork})
+	}
+
+	if merged == nil {
+		return nil
+	}
+
+	c.compareNetworks("spec.clusterNetwork", "Cluster network", clusterNetworks)
+	c.compareNetworks("spec.serviceNetwork", "Service network", serviceNetworks)
+	c.compare("Network", "spec.defaultNetwork", "", defaultNetworks)
+
+	return c.addManifest(networkManifest, merged)
+}
+
+// networkValue holds networks of a cluster, as written in its manifest and parsed
+type networkValue struct {
+	sourceValue
+	cidrs []*net.IPNet
+	// key is the same for networks covering the same ranges, however their CIDRs are written
+	key string
+}
+
+// newNetworkValue parses CIDRs of value, extra are settings of each network compared along with its range
+func newNetworkValue(cluster string, value interface{}, cidrs, extra []string) (networkValue, error) {
+	v := networkValue{sourceValue: sourceValue{cluster, value}}
+
+	var keys []string
+	for i, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return v, err
+		}
+		v.cidrs = append(v.cidrs, ipNet)
+
+		key := ipNet.String()
+		if i < len(extra) {
+			key += " " + extra[i]
+		}
+		keys = append(keys, key)
+	}
+	v.key = strings.Join(keys, ",")
+
+	return v, nil
+}
+
+// compareNetworks reports a conflict when clusters don't have the same networks,
+// the conflict tells whether ranges of different clusters overlap
+func (c *consolidation) compareNetworks(field, name string, values []networkValue) {
+	same, overlap := true, false
+	for i, v := range values {
+		for _, other := range values[i+1:] {
+			if v.key == other.key {
+				continue
+			}
+			same = false
+			overlap = overlap || cidrsOverlap(v.cidrs, other.cidrs)
+		}
+	}
+	if same {
+		return
+	}
+
+	comment := name + " CIDRs differ"
+	if overlap {
+		comment = name + " CIDRs of different clusters overlap"
+	}
+
+	sourceValues := make([]sourceValue, 0, len(values))
+	for _, v := range values {
+		sourceValues = append(sourceValues, v.sourceValue)
+	}
+	c.addConflict("Network", field, comment, sourceValues)
+}
+
+// cidrsOverlap tells whether a range of a overlaps a range of b
+func cidrsOverlap(a, b []*net.IPNet) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.Contains(y.IP) || y.Contains(x.IP) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compare reports a conflict when values aren't the same on every cluster
+func (c *consolidation) compare(kind, field, comment string, values []sourceValue) {
+	for _, v := range values[1:] {
+		if formatValue(v.value) != formatValue(values[0].value) {
+			c.addConflict(kind, field, comment, values)
+			return
+		}
+	}
+}
+
+func (c *consolidation) addConflict(kind, field, comment string, values []sourceValue) {
+	if comment == "" {
+		comment = "Clusters have different values"
+	}
+
+	conflict := reportoutput.FleetConflict{
+		Kind:    kind,
+		Field:   field,
+		Comment: fmt.Sprintf("%s, value of %s is used", comment, values[0].cluster),
+	}
+	for _, v := range values {
+		conflict.Values = append(conflict.Values, reportoutput.FleetConflictValue{Cluster: v.cluster, Value: formatValue(v.value)})
+	}
+
+	c.conflicts = append(c.conflicts, conflict)
+}
+
+func (c *consolidation) addManifest(name string, cr interface{}) error {
+	content, err := transform.GenYAML(cr)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to generate consolidated manifest %s", name)
+	}
+
+	c.manifests = append(c.manifests, transform.Manifest{Name: name, CRD: content})
+
+	return nil
+}
+
+// readManifest decodes a manifest of source into cr, it tells whether the manifest was found.
+// Manifests of components which failed or didn't run on the source cluster are not found.
+func readManifest(source Source, name string, cr interface{}) (bool, error) {
+	if source.excludes(name) {
+		return false, nil
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(source.ManifestsDir, name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed to read manifest %s of %s", name, source.Cluster)
+	}
+
+	if err := yaml.Unmarshal(content, cr); err != nil {
+		return false, errors.Wrapf(err, "Failed to parse manifest %s of %s", name, source.Cluster)
+	}
+
+	return true, nil
+}
+
+// formatValue returns value as shown in reports, strings as they are and other values as JSON
+func formatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	content, _ := json.Marshal(value)
+	return string(content)
+}
+
+// indexOf returns the index of value in values, -1 when it is missing
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// union appends values missing from merged
+func union(merged, values []string) []string {
+	for _, value := range values {
+		found := false
+		for _, m := range merged {
+			if m == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, value)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,259 @@
+package consolidation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/konveyor/cpma/pkg/transform"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsolidate(t *testing.T) {
+	sources := []Source{
+		{Cluster: "cluster1", ManifestsDir: "testdata/cluster1/manifests"},
+		{Cluster: "cluster2", ManifestsDir: "testdata/cluster2/manifests"},
+		{Cluster: "missing", ManifestsDir: "testdata/missing/manifests"},
+	}
+
+	manifests, conflicts, err := Consolidate(sources)
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, m := range manifests {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{
+		oauthManifest,
+		"100_CPMA-cluster-config-secret-github-secret.yaml",
+		"100_CPMA-cluster-config-secret-htpasswd_auth-secret.yaml",
+		imageManifest,
+		projectManifest,
+		schedulerManifest,
+		networkManifest,
+	}, names)
+
+	oauth := &configv1.OAuth{}
+	require.NoError(t, yaml.Unmarshal(findManifest(t, manifests, oauthManifest), oauth))
+	providers := []string{}
+	for _, p := range oauth.Spec.IdentityProviders {
+		providers = append(providers, p.Name)
+	}
+	assert.Equal(t, []string{"htpasswd_auth", "github", "ldap"}, providers)
+	assert.Equal(t, "2d85ea3f45d6777bffd7", oauth.Spec.IdentityProviders[1].GitHub.ClientID)
+
+	image := &configv1.Image{}
+	require.NoError(t, yaml.Unmarshal(findManifest(t, manifests, imageManifest), image))
+	assert.Equal(t, []string{"bad.test.com", "worse.test.com"}, image.Spec.RegistrySources.BlockedRegistries)
+	assert.Equal(t, []string{"insecure.test.com", "insecure2.test.com"}, image.Spec.RegistrySources.InsecureRegistries)
+	assert.Equal(t, []string{"external-registry.cluster1.example.com", "external-registry.cluster2.example.com"}, image.Spec.ExternalRegistryHostnames)
+	assert.Equal(t, []configv1.RegistryLocation{
+		{DomainName: "registry1.test.com", Insecure: true},
+		{DomainName: "registry2.test.com"},
+	}, image.Spec.AllowedRegistriesForImport)
+
+	network := &operatorv1.Network{}
+	require.NoError(t, yaml.Unmarshal(findManifest(t, manifests, networkManifest), network))
+	assert.Equal(t, "10.128.0.0/14", network.Spec.ClusterNetwork[0].CIDR)
+
+	expectedConflicts := []reportoutput.FleetConflict{
+		{
+			Kind:    "OAuth",
+			Field:   "spec.identityProviders[name=github]",
+			Comment: "Identity provider name is used by several clusters with different settings, value of cluster1 is used",
+			Values: []reportoutput.FleetConflictValue{
+				{Cluster: "cluster1", Value: `{"name":"github","mappingMethod":"claim","type":"GitHub","github":{"clientID":"2d85ea3f45d6777bffd7","clientSecret":{"name":"github-secret"},"hostname":"","ca":{"name":""}}}`},
+				{Cluster: "cluster2", Value: `{"name":"github","mappingMethod":"claim","type":"GitHub","github":{"clientID":"7d3c0b8a45d6777bffd7","clientSecret":{"name":"github-secret"},"hostname":"","ca":{"name":""}}}`},
+			},
+		},
+		{
+			Kind:    "Secret",
+			Field:   "openshift-config/htpasswd_auth-secret",
+			Comment: "Resource is generated by several clusters with different contents, value of cluster1 is used",
+			Values: []reportoutput.FleetConflictValue{
+				{Cluster: "cluster1", Value: "contents #1"},
+				{Cluster: "cluster2", Value: "contents #2"},
+			},
+		},
+		{
+			Kind:    "Image",
+			Field:   "spec.allowedRegistriesForImport[domainName=registry1.test.com]",
+			Comment: "Clusters have different values, value of cluster1 is used",
+			Values: []reportoutput.FleetConflictValue{
+				{Cluster: "cluster1", Value: `{"domainName":"registry1.test.com","insecure":true}`},
+				{Cluster: "cluster2", Value: `{"domainName":"registry1.test.com"}`},
+			},
+		},
+		{
+			Kind:    "Scheduler",
+			Field:   "spec.defaultNodeSelector",
+			Comment: "Clusters have different values, value of cluster1 is used",
+			Values: []reportoutput.FleetConflictValue{
+				{Cluster: "cluster1", Value: "node-role.kubernetes.io/compute=true"},
+				{Cluster: "cluster2", Value: "region=primary"},
+			},
+		},
+		{
+			Kind:    "Network",
+			Field:   "spec.clusterNetwork",
+			Comment: "Cluster network CIDRs differ, value of cluster1 is used",
+			Values: []reportoutput.FleetConflictValue{
+				{Cluster: "cluster1", Value: `[{"cidr":"10.128.0.0/14","hostPrefix":23}]`},
+				{Cluster: "cluster2", Value: `[{"cidr":"10.132.0.0/14","hostPrefix":23}]`},
+			},
+		},
+	}
+	assert.Equal(t, expectedConflicts, conflicts)
+}
+
+func TestConsolidateFailedComponents(t *testing.T) {
+	sources := []Source{
+		{Cluster: "cluster1", ManifestsDir: "testdata/cluster1/manifests"},
+		{Cluster: "cluster2", ManifestsDir: "testdata/cluster2/manifests", FailedComponents: []string{"OAuth"}, SkippedComponents: []string{"SDN"}},
+	}
+
+	manifests, conflicts, err := Consolidate(sources)
+	require.NoError(t, err)
+
+	// Manifests of components which failed or were skipped on cluster2 are those of cluster1
+	oauth := &configv1.OAuth{}
+	require.NoError(t, yaml.Unmarshal(findManifest(t, manifests, oauthManifest), oauth))
+	assert.Equal(t, "2d85ea3f45d6777bffd7", oauth.Spec.IdentityProviders[1].GitHub.ClientID)
+
+	fields := []string{}
+	for _, c := range conflicts {
+		fields = append(fields, c.Kind+" "+c.Field)
+	}
+	assert.Equal(t, []string{
+		"Image spec.allowedRegistriesForImport[domainName=registry1.test.com]",
+		"Scheduler spec.defaultNodeSelector",
+	}, fields)
+
+	// Components which succeeded on cluster2 are still merged
+	image := &configv1.Image{}
+	require.NoError(t, yaml.Unmarshal(findManifest(t, manifests, imageManifest), image))
+	assert.Contains(t, image.Spec.ExternalRegistryHostnames, "external-registry.cluster2.example.com")
+}
+
+func TestConsolidateRegistrySources(t *testing.T) {
+	sources := []Source{
+		{Cluster: "cluster1", ManifestsDir: "testdata/cluster1/manifests"},
+		{Cluster: "cluster3", ManifestsDir: "testdata/cluster3/manifests"},
+	}
+
+	_, conflicts, err := Consolidate(sources)
+	require.NoError(t, err)
+
+	fields := []string{}
+	for _, c := range conflicts {
+		fields = append(fields, c.Kind+" "+c.Field)
+	}
+	assert.Contains(t, fields, "Image spec.registrySources")
+}
+
+func TestConsolidateNetworks(t *testing.T) {
+	networkManifestContent := `apiVersion: operator.openshift.io/v1
+kind: Network
+metadata:
+  name: cluster
+spec:
+  clusterNetwork:
+  - cidr: %s
+    hostPrefix: 23
+  serviceNetwork:
+  - %s
+`
+
+	testCases := []struct {
+		name             string
+		clusterNetworks  []string
+		serviceNetworks  []string
+		expectedComments []string
+		expectedErr      string
+	}{
+		{
+			name:            "same ranges written differently",
+			clusterNetworks: []string{"10.128.0.0/14", "10.128.0.0/014"},
+			serviceNetworks: []string{"172.30.0.0/16", "172.30.1.0/16"},
+		},
+		{
+			name:             "overlapping ranges",
+			clusterNetworks:  []string{"10.128.0.0/14", "10.128.0.0/16"},
+			serviceNetworks:  []string{"172.30.0.0/16", "172.30.0.0/16"},
+			expectedComments: []string{"Cluster network CIDRs of different clusters overlap, value of cluster1 is used"},
+		},
+		{
+			name:             "distinct ranges",
+			clusterNetworks:  []string{"10.128.0.0/14", "10.128.0.0/14"},
+			serviceNetworks:  []string{"172.30.0.0/16", "172.31.0.0/16"},
+			expectedComments: []string{"Service network CIDRs differ, value of cluster1 is used"},
+		},
+		{
+			name:            "invalid range",
+			clusterNetworks: []string{"10.128.0.0/14", "10.128.0.0"},
+			serviceNetworks: []string{"172.30.0.0/16", "172.30.0.0/16"},
+			expectedErr:     "Invalid cluster network in manifest 100_CPMA-cluster-config-sdn.yaml of cluster2: invalid CIDR address: 10.128.0.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cpma-consolidation")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			var sources []Source
+			for i := range tc.clusterNetworks {
+				cluster := fmt.Sprintf("cluster%d", i+1)
+				manifestsDir := filepath.Join(dir, cluster)
+				require.NoError(t, os.MkdirAll(manifestsDir, 0750))
+				content := fmt.Sprintf(networkManifestContent, tc.clusterNetworks[i], tc.serviceNetworks[i])
+				require.NoError(t, ioutil.WriteFile(filepath.Join(manifestsDir, networkManifest), []byte(content), 0640))
+				sources = append(sources, Source{Cluster: cluster, ManifestsDir: manifestsDir})
+			}
+
+			_, conflicts, err := Consolidate(sources)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			comments := []string{}
+			for _, c := range conflicts {
+				comments = append(comments, c.Comment)
+			}
+			if tc.expectedComments == nil {
+				tc.expectedComments = []string{}
+			}
+			assert.Equal(t, tc.expectedComments, comments)
+		})
+	}
+}
+
+func TestManifestComponent(t *testing.T) {
+	assert.Equal(t, "OAuth", manifestComponent(oauthManifest))
+	assert.Equal(t, "OAuth", manifestComponent(secretManifestPrefix+"github-secret.yaml"))
+	assert.Equal(t, "Scheduler", manifestComponent(policyConfigMapManifest))
+	assert.Equal(t, "SDN", manifestComponent(networkManifest))
+	// Unknown manifests aren't filed under any component
+	assert.Equal(t, "", manifestComponent("100_CPMA-kubelet-config-worker.yaml"))
+}
+
+func findManifest(t *testing.T, manifests []transform.Manifest, name string) []byte {
+	for _, m := range manifests {
+		if m.Name == name {
+			return m.CRD
+		}
+	}
+
+	t.Fatalf("manifest %s not found", name)
+	return nil
+}
@@ -1,10 +1,8 @@
 package transform
 
 import (
-	"github.com/BurntSushi/toml"
 	"github.com/ghodss/yaml"
 	"github.com/konveyor/cpma/pkg/env"
-	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/reportoutput"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -164,20 +162,15 @@ func (e CrioExtraction) buildReportOutput() {
 			})
 	}
 
-	FinalReportOutput.Report.ComponentReports = append(FinalReportOutput.Report.ComponentReports, componentReport)
+	FinalReportOutput.AddComponentReport(componentReport)
 }
 
 // Extract collects Crio configuration from an OCP3 cluster
 func (e CrioTransform) Extract() (Extraction, error) {
 	logrus.Info("CrioTransform::Extract")
-	content, err := io.FetchFile(env.Config().GetString("CrioConfigFile"))
+	config, err := SourceSnapshot.CrioConfig()
 	if err != nil {
-		return nil, errors.New("No configuration file available")
-	}
-
-	var config Crios
-	if _, err := toml.Decode(string(content), &config); err != nil {
-		return nil, errors.Wrap(err, "Failed to decode crio, see error")
+		return nil, err
 	}
 
 	var extraction CrioExtraction
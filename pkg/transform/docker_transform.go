@@ -41,7 +41,7 @@ func (e DockerExtraction) buildReportOutput() {
 			Comment:    "The Docker runtime has been replaced with CRI-O",
 		})
 
-	FinalReportOutput.Report.ComponentReports = append(FinalReportOutput.Report.ComponentReports, componentReport)
+	FinalReportOutput.AddComponentReport(componentReport)
 }
 
 // Extract collects Docker configuration from an OCP3 cluster
@@ -49,10 +49,11 @@ func (e DockerTransform) Extract() (Extraction, error) {
 	logrus.Info("DockerTransform::Extract")
 	// Testing remote connection
 	if env.Config().GetBool("FetchFromRemote") {
-		_, err := remotehost.NewSSHSession(env.Config().GetString("Hostname"))
+		session, err := remotehost.NewSSHSession(env.Config().GetString("Hostname"))
 		if err != nil {
 			return nil, err
 		}
+		session.Close()
 	}
 	var extraction DockerExtraction
 	return extraction, nil
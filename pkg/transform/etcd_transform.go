@@ -5,10 +5,7 @@ import (
 	"fmt"
 	"strings"
 
-	"gopkg.in/go-ini/ini.v1"
-
 	"github.com/konveyor/cpma/pkg/env"
-	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/reportoutput"
 	"github.com/sirupsen/logrus"
 )
@@ -71,18 +68,13 @@ func (e ETCDExtraction) buildReportOutput() {
 			Comment:    TLSMessage,
 		})
 
-	FinalReportOutput.Report.ComponentReports = append(FinalReportOutput.Report.ComponentReports, componentReport)
+	FinalReportOutput.AddComponentReport(componentReport)
 }
 
 // Extract collects ETCD configuration from an OCP3 cluster
 func (e ETCDTransform) Extract() (Extraction, error) {
 	logrus.Info("ETCDTransform::Extract")
-	content, err := io.FetchFile(env.Config().GetString("ETCDConfigFile"))
-	if err != nil {
-		return nil, err
-	}
-
-	ETCDConfig, err := ini.Load(content)
+	ETCDConfig, err := SourceSnapshot.ETCDConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,98 @@
+package transform
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Dependent is implemented by transforms that have to wait for other
+// transforms to complete before they can run
+type Dependent interface {
+	// Dependencies returns names of the transforms this transform depends on
+	Dependencies() []string
+}
+
+// transformGraph holds transforms and their dependencies as indexes
+type transformGraph struct {
+	transforms   []Transform
+	dependencies [][]int
+}
+
+// dependenciesOf returns the dependencies declared by a transform, if any
+func dependenciesOf(transform Transform) []string {
+	if dependent, ok := transform.(Dependent); ok {
+		return dependent.Dependencies()
+	}
+	return nil
+}
+
+// newTransformGraph builds the dependency graph of transforms,
+// it fails on duplicate names, unknown dependencies and cycles
+func newTransformGraph(transforms []Transform) (*transformGraph, error) {
+	index := make(map[string]int, len(transforms))
+	for i, transform := range transforms {
+		if _, ok := index[transform.Name()]; ok {
+			return nil, errors.Errorf("transform %s is declared more than once", transform.Name())
+		}
+		index[transform.Name()] = i
+	}
+
+	graph := &transformGraph{
+		transforms:   transforms,
+		dependencies: make([][]int, len(transforms)),
+	}
+
+	for i, transform := range transforms {
+		for _, dependency := range dependenciesOf(transform) {
+			j, ok := index[dependency]
+			if !ok {
+				return nil, errors.Errorf("transform %s depends on unknown transform %s", transform.Name(), dependency)
+			}
+			graph.dependencies[i] = append(graph.dependencies[i], j)
+		}
+	}
+
+	if err := graph.checkCycles(); err != nil {
+		return nil, err
+	}
+
+	return graph, nil
+}
+
+// checkCycles makes sure transforms can be ordered, otherwise the runner would wait forever
+func (g *transformGraph) checkCycles() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(g.transforms))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return errors.Errorf("dependency cycle detected at transform %s", g.transforms[i].Name())
+		case visited:
+			return nil
+		}
+
+		state[i] = visiting
+		for _, j := range g.dependencies[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+
+		return nil
+	}
+
+	for i := range g.transforms {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
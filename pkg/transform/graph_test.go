@@ -0,0 +1,214 @@
+package transform
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTransform struct {
+	name         string
+	dependencies []string
+	delay        time.Duration
+	err          error
+	run          func(name string)
+}
+
+type fakeExtraction struct {
+	transform fakeTransform
+}
+
+func (t fakeTransform) Extract() (Extraction, error) {
+	if t.run != nil {
+		t.run(t.name)
+	}
+	time.Sleep(t.delay)
+	if t.err != nil {
+		return nil, t.err
+	}
+	return fakeExtraction{transform: t}, nil
+}
+
+func (t fakeTransform) Name() string {
+	return t.name
+}
+
+func (t fakeTransform) Dependencies() []string {
+	return t.dependencies
+}
+
+func (e fakeExtraction) Validate() error {
+	return nil
+}
+
+func (e fakeExtraction) Transform() ([]Output, error) {
+	FinalReportOutput.AddComponentReport(reportoutput.ComponentReport{Component: e.transform.name})
+	return []Output{ManifestOutput{Manifests: []Manifest{{Name: e.transform.name}}}}, nil
+}
+
+func TestNewTransformGraph(t *testing.T) {
+	testCases := []struct {
+		name        string
+		transforms  []Transform
+		expectedErr string
+	}{
+		{
+			name: "independent transforms",
+			transforms: []Transform{
+				fakeTransform{name: "A"},
+				fakeTransform{name: "B"},
+			},
+		},
+		{
+			name: "chained dependencies",
+			transforms: []Transform{
+				fakeTransform{name: "A", dependencies: []string{"B"}},
+				fakeTransform{name: "B", dependencies: []string{"C"}},
+				fakeTransform{name: "C"},
+			},
+		},
+		{
+			name: "unknown dependency",
+			transforms: []Transform{
+				fakeTransform{name: "A", dependencies: []string{"B"}},
+			},
+			expectedErr: "transform A depends on unknown transform B",
+		},
+		{
+			name: "duplicate transform",
+			transforms: []Transform{
+				fakeTransform{name: "A"},
+				fakeTransform{name: "A"},
+			},
+			expectedErr: "transform A is declared more than once",
+		},
+		{
+			name: "dependency cycle",
+			transforms: []Transform{
+				fakeTransform{name: "A", dependencies: []string{"B"}},
+				fakeTransform{name: "B", dependencies: []string{"A"}},
+			},
+			expectedErr: "dependency cycle detected at transform A",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newTransformGraph(tc.transforms)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRunnerTransform(t *testing.T) {
+	var (
+		mutex    sync.Mutex
+		started  []string
+		running  int32
+		maxAlive int32
+	)
+
+	track := func(name string) {
+		mutex.Lock()
+		started = append(started, name)
+		mutex.Unlock()
+
+		alive := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxAlive)
+			if alive <= max || atomic.CompareAndSwapInt32(&maxAlive, max, alive) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}
+
+	var flushed []string
+	var report reportoutput.ReportOutput
+	ManifestOutputFlush = func(manifests []Manifest) error {
+		for _, manifest := range manifests {
+			flushed = append(flushed, manifest.Name)
+		}
+		return nil
+	}
+	ReportOutputFlush = func(r Report) error {
+		report = r.Report
+		return nil
+	}
+	FinalReportOutput = Report{}
+
+	transforms := []Transform{
+		fakeTransform{name: "A", run: track, dependencies: []string{"C"}},
+		fakeTransform{name: "B", run: track, delay: 20 * time.Millisecond},
+		fakeTransform{name: "C", run: track, delay: 10 * time.Millisecond},
+		fakeTransform{name: "D", run: track},
+		fakeTransform{name: "E", run: track, err: errors.New("extract failed")},
+		fakeTransform{name: "F", run: track, dependencies: []string{"E"}},
+	}
+
+	err := Runner{Parallelism: 2}.Transform(transforms)
+	require.EqualError(t, err, "transformations failed for: E")
+
+	// F is never started because E failed
+	assert.NotContains(t, started, "F")
+	// A waits for C
+	indexOf := func(name string) int {
+		for i, s := range started {
+			if s == name {
+				return i
+			}
+		}
+		return -1
+	}
+	assert.True(t, indexOf("C") < indexOf("A"))
+	assert.True(t, atomic.LoadInt32(&maxAlive) <= 2)
+
+	// Outputs and reports follow declaration order
+	assert.Equal(t, []string{"A", "B", "C", "D"}, flushed)
+	var components []string
+	for _, componentReport := range report.ComponentReports {
+		components = append(components, componentReport.Component)
+	}
+	assert.Equal(t, []string{"A", "B", "C", "D"}, components)
+
+	assert.Equal(t, []reportoutput.ComponentStatus{
+		{Component: "A", Status: reportoutput.StatusSucceeded},
+		{Component: "B", Status: reportoutput.StatusSucceeded},
+		{Component: "C", Status: reportoutput.StatusSucceeded},
+		{Component: "D", Status: reportoutput.StatusSucceeded},
+		{Component: "E", Status: reportoutput.StatusFailed, Errors: []string{"extract failed"}},
+		{Component: "F", Status: reportoutput.StatusSkipped, Comment: "Dependency E did not succeed"},
+	}, report.RunStatus)
+}
+
+func TestRegistryGraph(t *testing.T) {
+	graph, err := newTransformGraph(transformRegistry)
+	require.NoError(t, err)
+
+	dependencies := make(map[string][]string)
+	for i, transform := range graph.transforms {
+		for _, j := range graph.dependencies[i] {
+			dependencies[transform.Name()] = append(dependencies[transform.Name()], graph.transforms[j].Name())
+		}
+	}
+
+	// Transforms reading the master configuration wait for API
+	assert.Equal(t, map[string][]string{
+		OAuthComponentName:     {APIComponentName},
+		SDNComponentName:       {APIComponentName},
+		ImageComponentName:     {APIComponentName},
+		ProjectComponentName:   {APIComponentName},
+		SchedulerComponentName: {APIComponentName},
+	}, dependencies)
+}
@@ -3,10 +3,7 @@ package transform
 import (
 	"errors"
 
-	"github.com/BurntSushi/toml"
-	"github.com/konveyor/cpma/pkg/decode"
 	"github.com/konveyor/cpma/pkg/env"
-	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/image"
 	"github.com/konveyor/cpma/pkg/transform/registries"
 	"github.com/konveyor/cpma/pkg/transform/reportoutput"
@@ -209,7 +206,7 @@ func (e ImageExtraction) buildReportOutput() {
 			Comment:    "Not supported by OCP4",
 		})
 
-	FinalReportOutput.Report.ComponentReports = append(FinalReportOutput.Report.ComponentReports, componentReport)
+	FinalReportOutput.AddComponentReport(componentReport)
 }
 
 // Extract collects image configuration information from an OCP3 cluster
@@ -217,19 +214,13 @@ func (e ImageTransform) Extract() (Extraction, error) {
 	logrus.Info("ImageTransform::Extract")
 	var extraction ImageExtraction
 
-	content, err := io.FetchFile(env.Config().GetString("RegistriesConfigFile"))
+	registriesConfig, err := SourceSnapshot.RegistriesConfig()
 	if err != nil {
 		return nil, err
 	}
-	if _, err := toml.Decode(string(content), &extraction.RegistriesConfig); err != nil {
-		return nil, err
-	}
+	extraction.RegistriesConfig = registriesConfig
 
-	content, err = io.FetchFile(env.Config().GetString("MasterConfigFile"))
-	if err != nil {
-		return nil, err
-	}
-	masterConfig, err := decode.MasterConfig(content)
+	masterConfig, err := SourceSnapshot.MasterConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -254,3 +245,8 @@ func (e ImageExtraction) Validate() error {
 func (e ImageTransform) Name() string {
 	return "Image"
 }
+
+// Dependencies returns API, which reads the master configuration first
+func (e ImageTransform) Dependencies() []string {
+	return []string{APIComponentName}
+}
@@ -3,11 +3,14 @@ package test
 import (
 	"encoding/json"
 	"io/ioutil"
+	"path/filepath"
+	"strings"
 
 	"github.com/konveyor/cpma/pkg/decode"
 	"github.com/konveyor/cpma/pkg/transform"
 	"github.com/konveyor/cpma/pkg/transform/oauth"
 	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
+	k8sapicore "k8s.io/api/core/v1"
 	k8sjson "k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/client-go/kubernetes/scheme"
 )
@@ -27,6 +30,39 @@ func LoadMasterConfig(file string) (*legacyconfigv1.MasterConfig, error) {
 	return masterConfig, nil
 }
 
+// LoadNodeConfig loads NodeConfig
+func LoadNodeConfig(file string) (*legacyconfigv1.NodeConfig, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeConfig, err := decode.NodeConfig(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodeConfig, nil
+}
+
+// LoadNodeGroupConfigMaps loads openshift-node config maps, each one named after its node configuration file
+func LoadNodeGroupConfigMaps(files ...string) ([]k8sapicore.ConfigMap, error) {
+	var configMaps []k8sapicore.ConfigMap
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		configMap := k8sapicore.ConfigMap{Data: map[string]string{"node-config.yaml": string(content)}}
+		configMap.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		configMap.Namespace = "openshift-node"
+		configMaps = append(configMaps, configMap)
+	}
+
+	return configMaps, nil
+}
+
 // LoadIPTestData load identity providers from file
 func LoadIPTestData(file string) ([]oauth.IdentityProvider, *legacyconfigv1.OAuthTemplates, error) {
 	content, err := ioutil.ReadFile(file)
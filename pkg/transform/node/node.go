@@ -0,0 +1,226 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	apiVersion = "machineconfiguration.openshift.io/v1"
+	kind       = "KubeletConfig"
+	// KubeletConfigName is the name of the KubeletConfig CR built from node-config.yaml
+	KubeletConfigName = "set-kubelet-config"
+	// PoolSelectorLabel is the label a MachineConfigPool must carry to get the KubeletConfig applied
+	PoolSelectorLabel = "custom-kubelet"
+)
+
+// KubeletConfigCR is a KubeletConfig Cluster Resource
+type KubeletConfigCR struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        KubeletConfigMetadata `json:"metadata"`
+	Spec            KubeletConfigSpec     `json:"spec"`
+}
+
+// KubeletConfigMetadata is the Metadata for a KubeletConfig CR
+type KubeletConfigMetadata struct {
+	Name string `json:"name"`
+}
+
+// KubeletConfigSpec is the Spec for a KubeletConfig CR
+type KubeletConfigSpec struct {
+	MachineConfigPoolSelector metav1.LabelSelector `json:"machineConfigPoolSelector"`
+	KubeletConfig             KubeletConfiguration `json:"kubeletConfig"`
+}
+
+// KubeletConfiguration holds kubelet settings supported by the KubeletConfig CR
+type KubeletConfiguration struct {
+	MaxPods                          int32             `json:"maxPods,omitempty"`
+	PodsPerCore                      int32             `json:"podsPerCore,omitempty"`
+	KubeReserved                     map[string]string `json:"kubeReserved,omitempty"`
+	SystemReserved                   map[string]string `json:"systemReserved,omitempty"`
+	EvictionHard                     map[string]string `json:"evictionHard,omitempty"`
+	EvictionSoft                     map[string]string `json:"evictionSoft,omitempty"`
+	EvictionSoftGracePeriod          map[string]string `json:"evictionSoftGracePeriod,omitempty"`
+	EvictionPressureTransitionPeriod string            `json:"evictionPressureTransitionPeriod,omitempty"`
+	EvictionMaxPodGracePeriod        int32             `json:"evictionMaxPodGracePeriod,omitempty"`
+	ImageGCHighThresholdPercent      *int32            `json:"imageGCHighThresholdPercent,omitempty"`
+	ImageGCLowThresholdPercent       *int32            `json:"imageGCLowThresholdPercent,omitempty"`
+}
+
+// ArgumentResult is the outcome of translating one kubelet argument
+type ArgumentResult struct {
+	Name      string
+	Value     string
+	Supported bool
+	Comment   string
+}
+
+// kubeletArgumentFields maps supported kubelet arguments to the KubeletConfiguration field they set
+var kubeletArgumentFields = map[string]string{
+	"max-pods":                            "maxPods",
+	"pods-per-core":                       "podsPerCore",
+	"kube-reserved":                       "kubeReserved",
+	"system-reserved":                     "systemReserved",
+	"eviction-hard":                       "evictionHard",
+	"eviction-soft":                       "evictionSoft",
+	"eviction-soft-grace-period":          "evictionSoftGracePeriod",
+	"eviction-pressure-transition-period": "evictionPressureTransitionPeriod",
+	"eviction-max-pod-grace-period":       "evictionMaxPodGracePeriod",
+	"image-gc-high-threshold":             "imageGCHighThresholdPercent",
+	"image-gc-low-threshold":              "imageGCLowThresholdPercent",
+}
+
+// Translate converts kubelet arguments into a KubeletConfig CR applied to pools labelled with poolSelector.
+// It returns the CR, nil when no argument could be carried over, and the result for each argument.
+func Translate(name string, poolSelector map[string]string, kubeletArguments legacyconfigv1.ExtendedArguments) (*KubeletConfigCR, []ArgumentResult) {
+	var kubeletConfigCR KubeletConfigCR
+	kubeletConfigCR.APIVersion = apiVersion
+	kubeletConfigCR.Kind = kind
+	kubeletConfigCR.Metadata.Name = name
+	kubeletConfigCR.Spec.MachineConfigPoolSelector.MatchLabels = poolSelector
+
+	argumentNames := make([]string, 0, len(kubeletArguments))
+	for argumentName := range kubeletArguments {
+		argumentNames = append(argumentNames, argumentName)
+	}
+	sort.Strings(argumentNames)
+
+	var results []ArgumentResult
+	translated := false
+	for _, argumentName := range argumentNames {
+		value := strings.Join(kubeletArguments[argumentName], ",")
+		result := ArgumentResult{Name: argumentName, Value: value}
+
+		field, ok := kubeletArgumentFields[argumentName]
+		if !ok {
+			result.Comment = "Argument can't be set through a KubeletConfig CR, it must be reviewed manually"
+			results = append(results, result)
+			continue
+		}
+
+		if err := setKubeletArgument(&kubeletConfigCR.Spec.KubeletConfig, argumentName, kubeletArguments[argumentName]); err != nil {
+			result.Comment = fmt.Sprintf("Unable to translate value %q: %s", value, err)
+			results = append(results, result)
+			continue
+		}
+
+		result.Supported = true
+		result.Comment = fmt.Sprintf("Set as kubeletConfig.%s", field)
+		results = append(results, result)
+		translated = true
+	}
+
+	if !translated {
+		return nil, results
+	}
+
+	return &kubeletConfigCR, results
+}
+
+func setKubeletArgument(config *KubeletConfiguration, argumentName string, values []string) error {
+	var err error
+
+	switch argumentName {
+	case "max-pods":
+		config.MaxPods, err = parseInt32(values)
+	case "pods-per-core":
+		config.PodsPerCore, err = parseInt32(values)
+	case "kube-reserved":
+		config.KubeReserved, err = parsePairs(values, "=")
+	case "system-reserved":
+		config.SystemReserved, err = parsePairs(values, "=")
+	case "eviction-hard":
+		config.EvictionHard, err = parsePairs(values, "<")
+	case "eviction-soft":
+		config.EvictionSoft, err = parsePairs(values, "<")
+	case "eviction-soft-grace-period":
+		config.EvictionSoftGracePeriod, err = parsePairs(values, "=")
+	case "eviction-pressure-transition-period":
+		config.EvictionPressureTransitionPeriod, err = parseString(values)
+	case "eviction-max-pod-grace-period":
+		config.EvictionMaxPodGracePeriod, err = parseInt32(values)
+	case "image-gc-high-threshold":
+		config.ImageGCHighThresholdPercent, err = parsePercent(values)
+	case "image-gc-low-threshold":
+		config.ImageGCLowThresholdPercent, err = parsePercent(values)
+	default:
+		err = errors.Errorf("unsupported kubelet argument %s", argumentName)
+	}
+
+	return err
+}
+
+func parseString(values []string) (string, error) {
+	if len(values) != 1 {
+		return "", errors.New("exactly one value is expected")
+	}
+
+	return values[0], nil
+}
+
+func parseInt32(values []string) (int32, error) {
+	value, err := parseString(values)
+	if err != nil {
+		return 0, err
+	}
+
+	number, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("%s is not a number", value)
+	}
+
+	return int32(number), nil
+}
+
+func parsePercent(values []string) (*int32, error) {
+	percent, err := parseInt32(values)
+	if err != nil {
+		return nil, err
+	}
+
+	if percent < 0 || percent > 100 {
+		return nil, errors.Errorf("%d is not a percentage", percent)
+	}
+
+	return &percent, nil
+}
+
+// parsePairs reads comma separated key/value pairs such as "cpu=500m,memory=1Gi"
+// or eviction thresholds such as "memory.available<100Mi"
+func parsePairs(values []string, separator string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, value := range values {
+		for _, pair := range strings.Split(value, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+
+			keyValue := strings.SplitN(pair, separator, 2)
+			if len(keyValue) != 2 || keyValue[0] == "" || keyValue[1] == "" {
+				return nil, errors.Errorf("%s is not of the form key%svalue", pair, separator)
+			}
+			pairs[keyValue[0]] = keyValue[1]
+		}
+	}
+
+	if len(pairs) == 0 {
+		return nil, errors.New("no value found")
+	}
+
+	return pairs, nil
+}
+
+// Validate checks node configuration holds kubelet arguments
+func Validate(nodeConfig legacyconfigv1.NodeConfig) error {
+	if len(nodeConfig.KubeletArguments) == 0 {
+		return errors.New("no kubelet arguments found in node configuration")
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package node_test
+
+import (
+	"testing"
+
+	cpmatest "github.com/konveyor/cpma/pkg/transform/internal/test"
+	"github.com/konveyor/cpma/pkg/transform/node"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestTranslate(t *testing.T) {
+	t.Parallel()
+	nodeConfig, err := cpmatest.LoadNodeConfig("testdata/node-config.yaml")
+	require.NoError(t, err)
+
+	kubeletConfigCR, results := node.Translate("workers", map[string]string{"custom-kubelet": "workers"}, nodeConfig.KubeletArguments)
+	require.NotNil(t, kubeletConfigCR)
+
+	assert.Equal(t, "machineconfiguration.openshift.io/v1", kubeletConfigCR.APIVersion)
+	assert.Equal(t, "KubeletConfig", kubeletConfigCR.Kind)
+	assert.Equal(t, "workers", kubeletConfigCR.Metadata.Name)
+	assert.Equal(t, map[string]string{"custom-kubelet": "workers"}, kubeletConfigCR.Spec.MachineConfigPoolSelector.MatchLabels)
+	assert.Equal(t, node.KubeletConfiguration{
+		MaxPods:                     250,
+		KubeReserved:                map[string]string{"cpu": "500m", "memory": "1Gi"},
+		SystemReserved:              map[string]string{"cpu": "500m", "memory": "512Mi"},
+		EvictionHard:                map[string]string{"memory.available": "100Mi", "nodefs.available": "10%"},
+		EvictionSoft:                map[string]string{"memory.available": "500Mi"},
+		EvictionSoftGracePeriod:     map[string]string{"memory.available": "1m30s"},
+		ImageGCHighThresholdPercent: int32Ptr(85),
+		ImageGCLowThresholdPercent:  int32Ptr(80),
+	}, kubeletConfigCR.Spec.KubeletConfig)
+
+	assert.Equal(t, []node.ArgumentResult{
+		{Name: "cloud-provider", Value: "aws", Comment: "Argument can't be set through a KubeletConfig CR, it must be reviewed manually"},
+		{Name: "eviction-hard", Value: "memory.available<100Mi,nodefs.available<10%", Supported: true, Comment: "Set as kubeletConfig.evictionHard"},
+		{Name: "eviction-soft", Value: "memory.available<500Mi", Supported: true, Comment: "Set as kubeletConfig.evictionSoft"},
+		{Name: "eviction-soft-grace-period", Value: "memory.available=1m30s", Supported: true, Comment: "Set as kubeletConfig.evictionSoftGracePeriod"},
+		{Name: "image-gc-high-threshold", Value: "85", Supported: true, Comment: "Set as kubeletConfig.imageGCHighThresholdPercent"},
+		{Name: "image-gc-low-threshold", Value: "80", Supported: true, Comment: "Set as kubeletConfig.imageGCLowThresholdPercent"},
+		{Name: "kube-reserved", Value: "cpu=500m,memory=1Gi", Supported: true, Comment: "Set as kubeletConfig.kubeReserved"},
+		{Name: "max-pods", Value: "250", Supported: true, Comment: "Set as kubeletConfig.maxPods"},
+		{Name: "node-labels", Value: "node-role.kubernetes.io/compute=true", Comment: "Argument can't be set through a KubeletConfig CR, it must be reviewed manually"},
+		{Name: "pods-per-core", Value: "many", Comment: `Unable to translate value "many": many is not a number`},
+		{Name: "system-reserved", Value: "cpu=500m,memory=512Mi", Supported: true, Comment: "Set as kubeletConfig.systemReserved"},
+	}, results)
+}
+
+func TestTranslateUnsupportedArguments(t *testing.T) {
+	t.Parallel()
+	nodeConfig, err := cpmatest.LoadNodeConfig("testdata/node-config-unsupported.yaml")
+	require.NoError(t, err)
+
+	kubeletConfigCR, results := node.Translate(node.KubeletConfigName, nil, nodeConfig.KubeletArguments)
+	assert.Nil(t, kubeletConfigCR)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Supported)
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name        string
+		inputFile   string
+		expectedErr string
+	}{
+		{
+			name:      "node config with kubelet arguments",
+			inputFile: "testdata/node-config.yaml",
+		},
+		{
+			name:        "fail without kubelet arguments",
+			inputFile:   "testdata/node-config-empty.yaml",
+			expectedErr: "no kubelet arguments found in node configuration",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nodeConfig, err := cpmatest.LoadNodeConfig(tc.inputFile)
+			require.NoError(t, err)
+
+			err = node.Validate(*nodeConfig)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package node
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/konveyor/cpma/pkg/decode"
+	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// GroupNamespace is the namespace holding node group configurations on OCP 3.10+
+	GroupNamespace = "openshift-node"
+	// MasterPool is the default MachineConfigPool of control plane nodes
+	MasterPool = "master"
+	// WorkerPool is the default MachineConfigPool of compute nodes
+	WorkerPool = "worker"
+
+	groupConfigMapPrefix = "node-config-"
+	groupConfigMapKey    = "node-config.yaml"
+	poolLabelPrefix      = "pools.operator.machineconfiguration.openshift.io/"
+	roleLabel            = "machineconfiguration.openshift.io/role"
+	nodeRoleLabelPrefix  = "node-role.kubernetes.io/"
+)
+
+// Group is an OCP3 node group with its node configuration
+type Group struct {
+	Name       string
+	NodeConfig legacyconfigv1.NodeConfig
+}
+
+// MachineConfigPoolCR is a MachineConfigPool Cluster Resource
+type MachineConfigPoolCR struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        MachineConfigPoolMetadata `json:"metadata"`
+	Spec            MachineConfigPoolSpec     `json:"spec"`
+}
+
+// MachineConfigPoolMetadata is the Metadata for a MachineConfigPool CR
+type MachineConfigPoolMetadata struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+}
+
+// MachineConfigPoolSpec is the Spec for a MachineConfigPool CR
+type MachineConfigPoolSpec struct {
+	MachineConfigSelector metav1.LabelSelector `json:"machineConfigSelector"`
+	NodeSelector          metav1.LabelSelector `json:"nodeSelector"`
+}
+
+// GroupsFromConfigMaps decodes node groups from openshift-node config maps, sorted by name
+func GroupsFromConfigMaps(configMaps []corev1.ConfigMap) ([]Group, error) {
+	var groups []Group
+	for _, configMap := range configMaps {
+		if !strings.HasPrefix(configMap.Name, groupConfigMapPrefix) {
+			continue
+		}
+
+		content, ok := configMap.Data[groupConfigMapKey]
+		if !ok {
+			continue
+		}
+
+		nodeConfig, err := decode.NodeConfig([]byte(content))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to decode node group %s", configMap.Name)
+		}
+		groups = append(groups, Group{Name: configMap.Name, NodeConfig: *nodeConfig})
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups, nil
+}
+
+// PoolName proposes the MachineConfigPool a node group maps to.
+// Groups holding masters go to the master pool, compute groups to the worker pool,
+// any other group gets a custom pool named after its role.
+func PoolName(groupName string) string {
+	role := strings.TrimPrefix(groupName, groupConfigMapPrefix)
+	role = strings.TrimSuffix(role, "-crio")
+
+	switch {
+	case strings.Contains(role, "master"), role == "all-in-one":
+		return MasterPool
+	case role == "compute":
+		return WorkerPool
+	}
+
+	return role
+}
+
+// IsDefaultPool tells if the pool exists on every OCP4 cluster
+func IsDefaultPool(pool string) bool {
+	return pool == MasterPool || pool == WorkerPool
+}
+
+// PoolSelector returns labels selecting a MachineConfigPool
+func PoolSelector(pool string) map[string]string {
+	return map[string]string{poolLabelPrefix + pool: ""}
+}
+
+// TranslatePool builds a custom MachineConfigPool extending the worker pool
+func TranslatePool(pool string) *MachineConfigPoolCR {
+	var poolCR MachineConfigPoolCR
+	poolCR.APIVersion = apiVersion
+	poolCR.Kind = "MachineConfigPool"
+	poolCR.Metadata.Name = pool
+	poolCR.Metadata.Labels = PoolSelector(pool)
+	poolCR.Spec.MachineConfigSelector.MatchExpressions = []metav1.LabelSelectorRequirement{
+		{
+			Key:      roleLabel,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   []string{WorkerPool, pool},
+		},
+	}
+	poolCR.Spec.NodeSelector.MatchLabels = map[string]string{nodeRoleLabelPrefix + pool: ""}
+
+	return &poolCR
+}
+
+// GroupNodes returns names of nodes carrying every label the node group sets through node-labels
+func GroupNodes(group Group, nodes []corev1.Node) []string {
+	values, ok := group.NodeConfig.KubeletArguments["node-labels"]
+	if !ok {
+		return nil
+	}
+
+	labels, err := parsePairs(values, "=")
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, node := range nodes {
+		if hasLabels(node.Labels, labels) {
+			names = append(names, node.Name)
+		}
+	}
+
+	return names
+}
+
+func hasLabels(nodeLabels, labels map[string]string) bool {
+	for key, value := range labels {
+		if nodeValue, ok := nodeLabels[key]; !ok || nodeValue != value {
+			return false
+		}
+	}
+
+	return true
+}
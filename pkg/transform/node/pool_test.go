@@ -0,0 +1,93 @@
+package node_test
+
+import (
+	"testing"
+
+	cpmatest "github.com/konveyor/cpma/pkg/transform/internal/test"
+	"github.com/konveyor/cpma/pkg/transform/node"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGroupsFromConfigMaps(t *testing.T) {
+	t.Parallel()
+	configMaps, err := cpmatest.LoadNodeGroupConfigMaps(
+		"testdata/node-config-infra.yaml",
+		"testdata/node-config-compute.yaml",
+	)
+	require.NoError(t, err)
+
+	// Config maps which are not node groups are ignored
+	configMaps = append(configMaps, corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "sync-config"},
+		Data:       map[string]string{"node-config.yaml": "invalid"},
+	})
+
+	groups, err := node.GroupsFromConfigMaps(configMaps)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "node-config-compute", groups[0].Name)
+	assert.Equal(t, []string{"250"}, groups[0].NodeConfig.KubeletArguments["max-pods"])
+	assert.Equal(t, "node-config-infra", groups[1].Name)
+
+	_, err = node.GroupsFromConfigMaps([]corev1.ConfigMap{{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-config-broken"},
+		Data:       map[string]string{"node-config.yaml": "kind: [}"},
+	}})
+	assert.Error(t, err)
+}
+
+func TestPoolName(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		group        string
+		expectedPool string
+	}{
+		{group: "node-config-master", expectedPool: "master"},
+		{group: "node-config-master-infra", expectedPool: "master"},
+		{group: "node-config-all-in-one", expectedPool: "master"},
+		{group: "node-config-compute", expectedPool: "worker"},
+		{group: "node-config-compute-crio", expectedPool: "worker"},
+		{group: "node-config-infra", expectedPool: "infra"},
+		{group: "node-config-infra-crio", expectedPool: "infra"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.group, func(t *testing.T) {
+			assert.Equal(t, tc.expectedPool, node.PoolName(tc.group))
+		})
+	}
+}
+
+func TestTranslatePool(t *testing.T) {
+	t.Parallel()
+	poolCR := node.TranslatePool("infra")
+
+	assert.Equal(t, "MachineConfigPool", poolCR.Kind)
+	assert.Equal(t, "infra", poolCR.Metadata.Name)
+	assert.Equal(t, node.PoolSelector("infra"), poolCR.Metadata.Labels)
+	assert.Equal(t, []metav1.LabelSelectorRequirement{{
+		Key:      "machineconfiguration.openshift.io/role",
+		Operator: metav1.LabelSelectorOpIn,
+		Values:   []string{"worker", "infra"},
+	}}, poolCR.Spec.MachineConfigSelector.MatchExpressions)
+	assert.Equal(t, map[string]string{"node-role.kubernetes.io/infra": ""}, poolCR.Spec.NodeSelector.MatchLabels)
+}
+
+func TestGroupNodes(t *testing.T) {
+	t.Parallel()
+	configMaps, err := cpmatest.LoadNodeGroupConfigMaps("testdata/node-config-compute.yaml")
+	require.NoError(t, err)
+	groups, err := node.GroupsFromConfigMaps(configMaps)
+	require.NoError(t, err)
+
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "master-0", Labels: map[string]string{"node-role.kubernetes.io/master": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "compute-0", Labels: map[string]string{"node-role.kubernetes.io/compute": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "compute-1", Labels: map[string]string{"node-role.kubernetes.io/compute": "true", "zone": "a"}}},
+	}
+
+	assert.Equal(t, []string{"compute-0", "compute-1"}, node.GroupNodes(groups[0], nodes))
+}
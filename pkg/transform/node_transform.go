@@ -0,0 +1,292 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/konveyor/cpma/pkg/api"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/transform/node"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
+	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
+	"github.com/sirupsen/logrus"
+	k8sapicore "k8s.io/api/core/v1"
+)
+
+// NodeComponentName is the Node component string
+const NodeComponentName = "Node"
+
+// NodeExtraction holds Node data extracted from OCP3
+type NodeExtraction struct {
+	legacyconfigv1.NodeConfig
+	// NodeGroups holds node groups defined in openshift-node namespace, on OCP 3.10+ only
+	NodeGroups []node.Group
+	NodeList   *k8sapicore.NodeList
+}
+
+// NodeTransform is a Node specific transform
+type NodeTransform struct {
+}
+
+// nodePool is a MachineConfigPool proposed for an OCP3 node group
+type nodePool struct {
+	name          string
+	group         string
+	nodes         []string
+	kubeletConfig *node.KubeletConfigCR
+	results       []node.ArgumentResult
+}
+
+// Transform converts data collected from an OCP3 into a useful output
+func (e NodeExtraction) Transform() ([]Output, error) {
+	outputs := []Output{}
+
+	pools, conflicts := e.buildPools()
+
+	if env.Config().GetBool("Manifests") {
+		logrus.Info("NodeTransform::Transform:Manifests")
+		manifests, err := e.buildManifestOutput(pools)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, manifests)
+	}
+
+	if env.Config().GetBool("Reporting") {
+		logrus.Info("NodeTransform::Transform:Reports")
+		e.buildReportOutput(pools, conflicts)
+	}
+
+	return outputs, nil
+}
+
+// buildPools proposes a pool for each node group, or a single one for the local node configuration
+// when there is no node group. Node groups mapping to an already proposed pool are returned as conflicts.
+func (e NodeExtraction) buildPools() ([]nodePool, []nodePool) {
+	if len(e.NodeGroups) == 0 {
+		kubeletConfigCR, results := node.Translate(node.KubeletConfigName,
+			map[string]string{node.PoolSelectorLabel: node.KubeletConfigName}, e.KubeletArguments)
+		return []nodePool{{kubeletConfig: kubeletConfigCR, results: results}}, nil
+	}
+
+	var nodes []k8sapicore.Node
+	if e.NodeList != nil {
+		nodes = e.NodeList.Items
+	}
+
+	var pools, conflicts []nodePool
+	for _, group := range e.NodeGroups {
+		pool := nodePool{
+			name:  node.PoolName(group.Name),
+			group: group.Name,
+			nodes: node.GroupNodes(group, nodes),
+		}
+
+		conflict := false
+		for _, proposed := range pools {
+			if proposed.name == pool.name {
+				conflict = true
+				break
+			}
+		}
+		if conflict {
+			conflicts = append(conflicts, pool)
+			continue
+		}
+
+		pool.kubeletConfig, pool.results = node.Translate(node.KubeletConfigName+"-"+pool.name,
+			node.PoolSelector(pool.name), group.NodeConfig.KubeletArguments)
+		pools = append(pools, pool)
+	}
+
+	return pools, conflicts
+}
+
+func (e NodeExtraction) buildManifestOutput(pools []nodePool) (Output, error) {
+	var manifests []Manifest
+
+	for _, pool := range pools {
+		if pool.name != "" && !node.IsDefaultPool(pool.name) {
+			poolCRYAML, err := GenYAML(node.TranslatePool(pool.name))
+			if err != nil {
+				return nil, err
+			}
+
+			manifest := Manifest{Name: "100_CPMA-machineconfigpool-" + pool.name + ".yaml", CRD: poolCRYAML}
+			manifests = append(manifests, manifest)
+		}
+
+		if pool.kubeletConfig == nil {
+			continue
+		}
+
+		kubeletConfigCRYAML, err := GenYAML(pool.kubeletConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		filename := "100_CPMA-kubelet-config.yaml"
+		if pool.name != "" {
+			filename = "100_CPMA-kubelet-config-" + pool.name + ".yaml"
+		}
+		manifest := Manifest{Name: filename, CRD: kubeletConfigCRYAML}
+		manifests = append(manifests, manifest)
+	}
+
+	return ManifestOutput{
+		Manifests: manifests,
+	}, nil
+}
+
+func (e NodeExtraction) buildReportOutput(pools, conflicts []nodePool) {
+	componentReport := reportoutput.ComponentReport{
+		Component: NodeComponentName,
+	}
+
+	for _, pool := range pools {
+		kind := "KubeletArguments"
+		if pool.group != "" {
+			comment := fmt.Sprintf("Node group maps to MachineConfigPool %s", pool.name)
+			if !node.IsDefaultPool(pool.name) {
+				comment += ", the pool has to be created and nodes labelled accordingly"
+			}
+			componentReport.Reports = append(componentReport.Reports,
+				reportoutput.Report{
+					Name:       pool.group,
+					Kind:       "NodeGroup",
+					Supported:  true,
+					Confidence: ModerateConfidence,
+					Comment:    comment + "; " + describeNodes(pool.nodes),
+				})
+			kind += ":" + pool.group
+		}
+
+		for _, result := range pool.results {
+			report := reportoutput.Report{
+				Name:       result.Name,
+				Kind:       kind,
+				Supported:  result.Supported,
+				Confidence: NoConfidence,
+				Comment:    result.Comment,
+			}
+			if result.Supported {
+				report.Confidence = HighConfidence
+			}
+			componentReport.Reports = append(componentReport.Reports, report)
+		}
+	}
+
+	for _, conflict := range conflicts {
+		componentReport.Reports = append(componentReport.Reports,
+			reportoutput.Report{
+				Name:       conflict.group,
+				Kind:       "NodeGroup",
+				Supported:  false,
+				Confidence: NoConfidence,
+				Comment: fmt.Sprintf("MachineConfigPool %s is already configured from another node group, kubelet arguments must be merged manually; %s",
+					conflict.name, describeNodes(conflict.nodes)),
+			})
+	}
+
+	FinalReportOutput.AddComponentReport(componentReport)
+}
+
+func describeNodes(nodes []string) string {
+	if len(nodes) == 0 {
+		return "no node matches the node group labels"
+	}
+
+	return "nodes: " + strings.Join(nodes, ", ")
+}
+
+// Extract collects Node configuration from an OCP3 cluster, node groups are read from
+// openshift-node namespace and local node configuration file is used when there is none
+func (e NodeTransform) Extract() (Extraction, error) {
+	logrus.Info("NodeTransform::Extract")
+
+	configMaps, nodeList, err := e.listNodeResources()
+	if err != nil {
+		return nil, err
+	}
+
+	var extraction NodeExtraction
+	extraction.NodeList = nodeList
+
+	nodeGroups, err := node.GroupsFromConfigMaps(configMaps.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nodeGroups) > 0 {
+		extraction.NodeGroups = nodeGroups
+		return extraction, nil
+	}
+
+	logrus.Debugf("NodeTransform::Extract:No node group found in %s namespace, using node configuration file", node.GroupNamespace)
+	nodeConfig, err := SourceSnapshot.NodeConfig()
+	if err != nil {
+		return nil, err
+	}
+	extraction.NodeConfig = *nodeConfig
+
+	return extraction, nil
+}
+
+// listNodeResources lists openshift-node config maps and nodes, from the cluster dump when one is set
+func (e NodeTransform) listNodeResources() (*k8sapicore.ConfigMapList, *k8sapicore.NodeList, error) {
+	if env.Config().GetString("ClusterDump") != "" {
+		dump, err := SourceSnapshot.ClusterDump()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		configMaps, err := dump.ConfigMaps(node.GroupNamespace)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		nodeList, err := dump.Nodes()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return configMaps, nodeList, nil
+	}
+
+	var nodesErr error
+	chanNodes := make(chan *k8sapicore.NodeList)
+	go func() {
+		nodeList, err := api.ListNodes(api.K8sClient)
+		nodesErr = err
+		chanNodes <- nodeList
+	}()
+
+	configMaps, err := api.ListConfigMaps(api.K8sClient, node.GroupNamespace)
+	nodeList := <-chanNodes
+	if err != nil {
+		return nil, nil, err
+	}
+	if nodesErr != nil {
+		return nil, nil, nodesErr
+	}
+
+	return configMaps, nodeList, nil
+}
+
+// Validate confirms we have recieved good Node configuration data during Extract
+func (e NodeExtraction) Validate() error {
+	if len(e.NodeGroups) > 0 {
+		return nil
+	}
+
+	if err := node.Validate(e.NodeConfig); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Name returns a human readable name for the transform
+func (e NodeTransform) Name() string {
+	return NodeComponentName
+}
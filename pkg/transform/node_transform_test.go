@@ -0,0 +1,184 @@
+package transform_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/api"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io"
+	"github.com/konveyor/cpma/pkg/transform"
+	cpmatest "github.com/konveyor/cpma/pkg/transform/internal/test"
+	"github.com/konveyor/cpma/pkg/transform/node"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8sapicore "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func loadNodeGroupsExtraction() (transform.NodeExtraction, error) {
+	var extraction transform.NodeExtraction
+
+	configMaps, err := cpmatest.LoadNodeGroupConfigMaps(
+		"testdata/node-config-master.yaml",
+		"testdata/node-config-compute.yaml",
+		"testdata/node-config-compute-crio.yaml",
+		"testdata/node-config-infra.yaml",
+	)
+	if err != nil {
+		return extraction, err
+	}
+
+	extraction.NodeGroups, err = node.GroupsFromConfigMaps(configMaps)
+	extraction.NodeList = &k8sapicore.NodeList{Items: []k8sapicore.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "master-0", Labels: map[string]string{"node-role.kubernetes.io/master": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "compute-0", Labels: map[string]string{"node-role.kubernetes.io/compute": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "infra-0", Labels: map[string]string{"node-role.kubernetes.io/infra": "true"}}},
+	}}
+
+	return extraction, err
+}
+
+// expectedManifest is a manifest name along with the file holding its expected content
+type expectedManifest struct {
+	name string
+	file string
+}
+
+func loadExpectedManifests(expected []expectedManifest) ([]transform.Manifest, error) {
+	var manifests []transform.Manifest
+	for _, manifest := range expected {
+		content, err := ioutil.ReadFile(manifest.file)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, transform.Manifest{Name: manifest.name, CRD: content})
+	}
+
+	return manifests, nil
+}
+
+func loadExpectedReport(file string) (reportoutput.ReportOutput, error) {
+	report := reportoutput.ReportOutput{}
+	jsonData, err := io.ReadFile(file)
+	if err != nil {
+		return report, err
+	}
+
+	err = json.Unmarshal(jsonData, &report)
+	return report, err
+}
+
+func TestNodeExtractionTransform(t *testing.T) {
+	expectedManifests, err := loadExpectedManifests([]expectedManifest{
+		{name: "100_CPMA-kubelet-config.yaml", file: "testdata/expected-CR-kubelet-config.yaml"},
+	})
+	require.NoError(t, err)
+
+	expectedReport, err := loadExpectedReport("testdata/expected-report-node.json")
+	require.NoError(t, err)
+
+	nodeConfig, err := cpmatest.LoadNodeConfig("testdata/node-config.yaml")
+	require.NoError(t, err)
+
+	expectedGroupManifests, err := loadExpectedManifests([]expectedManifest{
+		{name: "100_CPMA-kubelet-config-worker.yaml", file: "testdata/expected-CR-kubelet-config-worker.yaml"},
+		{name: "100_CPMA-machineconfigpool-infra.yaml", file: "testdata/expected-CR-machineconfigpool-infra.yaml"},
+		{name: "100_CPMA-kubelet-config-infra.yaml", file: "testdata/expected-CR-kubelet-config-infra.yaml"},
+		{name: "100_CPMA-kubelet-config-master.yaml", file: "testdata/expected-CR-kubelet-config-master.yaml"},
+	})
+	require.NoError(t, err)
+
+	expectedGroupReport, err := loadExpectedReport("testdata/expected-report-node-groups.json")
+	require.NoError(t, err)
+
+	groupsExtraction, err := loadNodeGroupsExtraction()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name              string
+		extraction        transform.NodeExtraction
+		expectedManifests []transform.Manifest
+		expectedReports   reportoutput.ReportOutput
+	}{
+		{
+			name:              "transform node extraction",
+			extraction:        transform.NodeExtraction{NodeConfig: *nodeConfig},
+			expectedManifests: expectedManifests,
+			expectedReports:   expectedReport,
+		},
+		{
+			name:              "transform node groups extraction",
+			extraction:        groupsExtraction,
+			expectedManifests: expectedGroupManifests,
+			expectedReports:   expectedGroupReport,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualManifestsChan := make(chan []transform.Manifest)
+			actualReportsChan := make(chan reportoutput.ReportOutput)
+			transform.FinalReportOutput = transform.Report{}
+
+			// Override flush method
+			transform.ManifestOutputFlush = func(manifests []transform.Manifest) error {
+				actualManifestsChan <- manifests
+				return nil
+			}
+			transform.ReportOutputFlush = func(reports transform.Report) error {
+				actualReportsChan <- reports.Report
+				return nil
+			}
+
+			testExtraction := tc.extraction
+
+			go func() {
+				env.Config().Set("Reporting", true)
+				env.Config().Set("Manifests", true)
+
+				transformOutput, err := testExtraction.Transform()
+				if err != nil {
+					t.Error(err)
+				}
+				for _, output := range transformOutput {
+					output.Flush()
+				}
+				transform.FinalReportOutput.Flush()
+			}()
+
+			actualManifests := <-actualManifestsChan
+			assert.Equal(t, actualManifests, tc.expectedManifests)
+			actualReports := <-actualReportsChan
+			assert.Equal(t, actualReports.ComponentReports, tc.expectedReports.ComponentReports)
+		})
+	}
+}
+
+func TestNodeExtractListError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/nodes" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403,"message":"nodes is forbidden"}`))
+			return
+		}
+		w.Write([]byte(`{"kind":"ConfigMapList","apiVersion":"v1","items":[]}`))
+	}))
+	defer server.Close()
+
+	k8sClient := api.K8sClient
+	defer func() { api.K8sClient = k8sClient }()
+	api.K8sClient = kubernetes.NewForConfigOrDie(&rest.Config{Host: server.URL})
+	defer env.Config().Set("ClusterDump", env.Config().Get("ClusterDump"))
+	env.Config().Set("ClusterDump", "")
+
+	_, err := transform.NodeTransform{}.Extract()
+	assert.EqualError(t, err, "Unable to list nodes: nodes is forbidden")
+}
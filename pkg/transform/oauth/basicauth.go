@@ -37,7 +37,7 @@ func buildBasicAuthIP(serializer *json.Serializer, p IdentityProvider) (*Provide
 	}
 
 	if basicAuth.CertFile != "" {
-		certSecretName := "basicauth-client-cert-secret"
+		certSecretName := providerSecretName(p, "client-cert")
 		idP.BasicAuth.TLSClientCert.Name = certSecretName
 
 		certSecret, err := secrets.Opaque(certSecretName, p.CrtData, OAuthNamespace, "tls.crt")
@@ -46,7 +46,7 @@ func buildBasicAuthIP(serializer *json.Serializer, p IdentityProvider) (*Provide
 		}
 		providerSecrets = append(providerSecrets, certSecret)
 
-		keySecretName := "basicauth-client-key-secret"
+		keySecretName := providerSecretName(p, "client-key")
 		idP.BasicAuth.TLSClientKey.Name = keySecretName
 
 		keySecret, err := secrets.Opaque(keySecretName, p.KeyData, OAuthNamespace, "tls.key")
@@ -44,7 +44,7 @@ func buildGitHubIP(serializer *json.Serializer, p IdentityProvider) (*ProviderRe
 		providerConfigMaps = append(providerConfigMaps, caConfigmap)
 	}
 
-	secretName := "github-secret"
+	secretName := providerSecretName(p, "client-secret")
 	idP.GitHub.ClientSecret.Name = secretName
 	secretContent, err := io.FetchStringSource(github.ClientSecret)
 	if err != nil {
@@ -81,10 +81,6 @@ func validateGithubProvider(serializer *json.Serializer, p IdentityProvider) err
 		}
 	}
 
-	if github.ClientSecret.KeyFile != "" {
-		return errors.New("Usage of encrypted files as secret value is not supported")
-	}
-
 	if err := validateClientData(github.ClientID, github.ClientSecret); err != nil {
 		return err
 	}
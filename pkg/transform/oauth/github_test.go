@@ -86,10 +86,9 @@ func TestGithubValidation(t *testing.T) {
 			expectedErr:  errors.New("Client Secret can't be empty"),
 		},
 		{
-			name:         "fail if key file is present for client secret in github provider",
-			requireError: true,
-			inputFile:    "testdata/github/invalid-keyfile-master-config.yaml",
-			expectedErr:  errors.New("Usage of encrypted files as secret value is not supported"),
+			name:         "accept encrypted client secret in github provider",
+			requireError: false,
+			inputFile:    "testdata/github/encrypted-clientsecret-master-config.yaml",
 		},
 	}
 
@@ -38,7 +38,7 @@ func buildGitLabIP(serializer *json.Serializer, p IdentityProvider) (*ProviderRe
 		providerConfigMaps = append(providerConfigMaps, caConfigmap)
 	}
 
-	secretName := "gitlab-secret"
+	secretName := providerSecretName(p, "client-secret")
 	idP.GitLab.ClientSecret.Name = secretName
 	secretContent, err := io.FetchStringSource(gitlab.ClientSecret)
 	if err != nil {
@@ -77,10 +77,6 @@ func validateGitLabProvider(serializer *json.Serializer, p IdentityProvider) err
 		return errors.New("URL can't be empty")
 	}
 
-	if gitlab.ClientSecret.KeyFile != "" {
-		return errors.New("Usage of encrypted files as secret value is not supported")
-	}
-
 	if err := validateClientData(gitlab.ClientID, gitlab.ClientSecret); err != nil {
 		return err
 	}
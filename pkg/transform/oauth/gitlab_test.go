@@ -83,10 +83,9 @@ func TestGitlabValidation(t *testing.T) {
 			expectedErr:  errors.New("Client Secret can't be empty"),
 		},
 		{
-			name:         "fail if key file is present for client secret in gitlab provider",
-			requireError: true,
-			inputFile:    "testdata/gitlab/invalid-keyfile-master-config.yaml",
-			expectedErr:  errors.New("Usage of encrypted files as secret value is not supported"),
+			name:         "accept encrypted client secret in gitlab provider",
+			requireError: false,
+			inputFile:    "testdata/gitlab/encrypted-clientsecret-master-config.yaml",
 		},
 	}
 
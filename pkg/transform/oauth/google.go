@@ -30,7 +30,7 @@ func buildGoogleIP(serializer *json.Serializer, p IdentityProvider) (*ProviderRe
 	idP.Google.ClientID = google.ClientID
 	idP.Google.HostedDomain = google.HostedDomain
 
-	secretName := "google-secret"
+	secretName := providerSecretName(p, "client-secret")
 	idP.Google.ClientSecret.Name = secretName
 	secretContent, err := io.FetchStringSource(google.ClientSecret)
 	if err != nil {
@@ -64,10 +64,6 @@ func validateGoogleProvider(serializer *json.Serializer, p IdentityProvider) err
 		return err
 	}
 
-	if google.ClientSecret.KeyFile != "" {
-		return errors.New("Usage of encrypted files as secret value is not supported")
-	}
-
 	if err := validateClientData(google.ClientID, google.ClientSecret); err != nil {
 		return err
 	}
@@ -77,10 +77,9 @@ func TestGoogleValidation(t *testing.T) {
 			expectedErr:  errors.New("Client Secret can't be empty"),
 		},
 		{
-			name:         "fail if key file is present for client secret in google provider",
-			requireError: true,
-			inputFile:    "testdata/google/invalid-keyfile-master-config.yaml",
-			expectedErr:  errors.New("Usage of encrypted files as secret value is not supported"),
+			name:         "accept encrypted client secret in google provider",
+			requireError: false,
+			inputFile:    "testdata/google/encrypted-clientsecret-master-config.yaml",
 		},
 	}
 
@@ -26,7 +26,7 @@ func buildHTPasswdIP(serializer *json.Serializer, p IdentityProvider) (*Provider
 	idP.Type = "HTPasswd"
 	idP.MappingMethod = configv1.MappingMethodType(p.MappingMethod)
 
-	secretName := "htpasswd-secret"
+	secretName := providerSecretName(p, "htpasswd")
 	idP.HTPasswd = &configv1.HTPasswdIdentityProvider{}
 	idP.HTPasswd.FileData.Name = secretName
 
@@ -43,17 +43,17 @@ func buildKeystoneIP(serializer *json.Serializer, p IdentityProvider) (*Provider
 	}
 
 	if keystone.CertFile != "" {
-		certSecretName := "keystone-client-cert-secret"
+		certSecretName := providerSecretName(p, "client-cert")
 		idP.Keystone.TLSClientCert.Name = certSecretName
-		certSecret, err := secrets.Opaque(certSecretName, p.CrtData, OAuthNamespace, "keystone")
+		certSecret, err := secrets.Opaque(certSecretName, p.CrtData, OAuthNamespace, "tls.crt")
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to generate cert secret for keystone, see error")
 		}
 		providerSecrets = append(providerSecrets, certSecret)
 
-		keySecretName := "keystone-client-key-secret"
+		keySecretName := providerSecretName(p, "client-key")
 		idP.Keystone.TLSClientKey.Name = keySecretName
-		keySecret, err := secrets.Opaque(keySecretName, p.KeyData, OAuthNamespace, "keystone")
+		keySecret, err := secrets.Opaque(keySecretName, p.KeyData, OAuthNamespace, "tls.key")
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to generate key secret for keystone, see error")
 		}
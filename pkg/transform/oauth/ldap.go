@@ -3,6 +3,7 @@ package oauth
 import (
 	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/configmaps"
+	"github.com/konveyor/cpma/pkg/transform/secrets"
 	configv1 "github.com/openshift/api/config/v1"
 	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
 	"github.com/pkg/errors"
@@ -15,6 +16,7 @@ func buildLdapIP(serializer *json.Serializer, p IdentityProvider) (*ProviderReso
 	var (
 		err                error
 		idP                = &configv1.IdentityProvider{}
+		providerSecrets    []*corev1.Secret
 		providerConfigMaps []*corev1.ConfigMap
 		ldap               legacyconfigv1.LDAPPasswordIdentityProvider
 	)
@@ -39,7 +41,13 @@ func buildLdapIP(serializer *json.Serializer, p IdentityProvider) (*ProviderReso
 			return nil, errors.Wrap(err, "Failed to fetch bind password for ldap")
 		}
 
-		idP.LDAP.BindPassword.Name = bindPassword
+		secretName := providerSecretName(p, "bind-password")
+		idP.LDAP.BindPassword.Name = secretName
+		secret, err := secrets.Opaque(secretName, []byte(bindPassword), OAuthNamespace, "bindPassword")
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to generate bind password secret for ldap")
+		}
+		providerSecrets = append(providerSecrets, secret)
 	}
 
 	if ldap.CA != "" {
@@ -53,6 +61,7 @@ func buildLdapIP(serializer *json.Serializer, p IdentityProvider) (*ProviderReso
 
 	return &ProviderResources{
 		IDP:        idP,
+		Secrets:    providerSecrets,
 		ConfigMaps: providerConfigMaps,
 	}, nil
 }
@@ -92,9 +101,5 @@ func validateLDAPProvider(serializer *json.Serializer, p IdentityProvider) error
 		return errors.New("URL can't be empty")
 	}
 
-	if ldap.BindPassword.KeyFile != "" {
-		return errors.New("Usage of encrypted files as bind password value is not supported")
-	}
-
 	return nil
 }
@@ -95,10 +95,9 @@ func TestLDAPValidation(t *testing.T) {
 			expectedErr:  errors.New("URL can't be empty"),
 		},
 		{
-			name:         "fail if key file is present for bind password in ldap provider",
-			requireError: true,
-			inputFile:    "testdata/ldap/invalid-bpass-master-config.yaml",
-			expectedErr:  errors.New("Usage of encrypted files as bind password value is not supported"),
+			name:         "accept encrypted bind password in ldap provider",
+			requireError: false,
+			inputFile:    "testdata/ldap/encrypted-bpass-master-config.yaml",
 		},
 	}
 
@@ -1,7 +1,11 @@
 package oauth
 
 import (
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 
 	configv1 "github.com/openshift/api/config/v1"
 	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
@@ -48,6 +52,8 @@ type IdentityProvider struct {
 	CAData        []byte
 	CrtData       []byte
 	KeyData       []byte
+	// secretPrefix starts names of the provider secrets, it is unique among providers
+	secretPrefix string
 }
 
 // ResultResources stores all oAuth config parts
@@ -83,6 +89,7 @@ func Translate(identityProviders []IdentityProvider, tokenConfig TokenConfig, te
 	var secretsSlice []*corev1.Secret
 	var сonfigMapSlice []*corev1.ConfigMap
 	var providerResources *ProviderResources
+	secretPrefixes := map[string]bool{}
 
 	// Translate configuration of diffent oAuth providers to CRD, secrets and config maps
 	var oauthCrd configv1.OAuth
@@ -98,6 +105,7 @@ func Translate(identityProviders []IdentityProvider, tokenConfig TokenConfig, te
 		}
 
 		kind := p.Kind
+		p.secretPrefix = uniqueSecretPrefix(p.Name, secretPrefixes)
 
 		switch kind {
 		case "GitHubIdentityProvider":
@@ -194,6 +202,49 @@ func Validate(identityProviders []IdentityProvider) error {
 	return nil
 }
 
+// invalidNameChars matches characters which can't be part of a secret name
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Secret names are DNS subdomains, the longest credential suffix is client-secret
+const (
+	maxSecretNameLength = 253
+	maxCredentialLength = len("client-secret")
+)
+
+// providerSecretName returns the name of the secret holding credential of identity provider p. Secrets are named after
+// their provider, so that providers of the same kind don't share secrets.
+func providerSecretName(p IdentityProvider, credential string) string {
+	prefix := p.secretPrefix
+	if prefix == "" {
+		prefix = uniqueSecretPrefix(p.Name, map[string]bool{})
+	}
+
+	return prefix + "-" + credential
+}
+
+// uniqueSecretPrefix returns the prefix of secret names of provider name, made of its valid characters. A short hash
+// of name is appended when the prefix is already used by another provider or too long, and used alone when name has
+// no valid character.
+func uniqueSecretPrefix(name string, used map[string]bool) string {
+	prefix := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	maxLength := maxSecretNameLength - len("-") - maxCredentialLength
+
+	if prefix == "" || used[prefix] || len(prefix) > maxLength {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:8]
+		if len(prefix) > maxLength-len(hash)-1 {
+			prefix = strings.TrimRight(prefix[:maxLength-len(hash)-1], "-")
+		}
+		if prefix == "" {
+			prefix = hash
+		} else {
+			prefix += "-" + hash
+		}
+	}
+	used[prefix] = true
+
+	return prefix
+}
+
 func validateMappingMethod(method string) error {
 	switch method {
 	case "claim", "lookup", "generate", "add":
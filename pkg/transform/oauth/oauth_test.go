@@ -3,8 +3,10 @@ package oauth_test
 import (
 	"encoding/json"
 	"io/ioutil"
+	"strings"
 	"testing"
 
+	cpmatest "github.com/konveyor/cpma/pkg/transform/internal/test"
 	"github.com/konveyor/cpma/pkg/transform/oauth"
 	configv1 "github.com/openshift/api/config/v1"
 	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
@@ -92,3 +94,72 @@ func TestTransformMasterConfig(t *testing.T) {
 	}
 
 }
+
+func TestSecretNames(t *testing.T) {
+	t.Parallel()
+	identityProviders, _, err := cpmatest.LoadIPTestData("testdata/github/master_config.yaml")
+	require.NoError(t, err)
+
+	named := func(names ...string) []oauth.IdentityProvider {
+		var providers []oauth.IdentityProvider
+		for _, name := range names {
+			p := identityProviders[0]
+			p.Name = name
+			providers = append(providers, p)
+		}
+		return providers
+	}
+
+	testCases := []struct {
+		name              string
+		identityProviders []oauth.IdentityProvider
+		expectedProviders []string
+		expectedSecrets   []string
+	}{
+		{
+			name:              "providers of the same kind get their own secrets",
+			identityProviders: named("github_Corp", "github.public"),
+			expectedProviders: []string{"github_Corp", "github.public"},
+			expectedSecrets:   []string{"github-corp-client-secret", "github-public-client-secret"},
+		},
+		{
+			name:              "hash appended when secret names clash",
+			identityProviders: named("github_corp", "github-corp"),
+			expectedProviders: []string{"github_corp", "github-corp"},
+			expectedSecrets:   []string{"github-corp-client-secret", "github-corp-b3a28825-client-secret"},
+		},
+		{
+			name:              "hash used for names without valid characters",
+			identityProviders: named("_-_"),
+			expectedProviders: []string{"_-_"},
+			expectedSecrets:   []string{"77767769-client-secret"},
+		},
+		{
+			name:              "long names are truncated",
+			identityProviders: named(strings.Repeat("x", 300)),
+			expectedProviders: []string{strings.Repeat("x", 300)},
+			expectedSecrets:   []string{strings.Repeat("x", 230) + "-0d4e2ca9-client-secret"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oauthResources, err := oauth.Translate(tc.identityProviders, oauth.TokenConfig{}, legacyconfigv1.OAuthTemplates{})
+			require.NoError(t, err)
+
+			var providers, secrets []string
+			for _, idP := range oauthResources.OAuthCRD.Spec.IdentityProviders {
+				providers = append(providers, idP.Name)
+				assert.Contains(t, tc.expectedSecrets, idP.GitHub.ClientSecret.Name)
+			}
+			for _, secret := range oauthResources.Secrets {
+				secrets = append(secrets, secret.Name)
+			}
+			assert.Equal(t, tc.expectedProviders, providers)
+			assert.Equal(t, tc.expectedSecrets, secrets)
+			for _, secret := range secrets {
+				assert.True(t, len(secret) <= 253, "secret name %s is too long", secret)
+			}
+		})
+	}
+}
@@ -32,7 +32,7 @@ func buildOpenIDIP(serializer *json.Serializer, p IdentityProvider) (*ProviderRe
 	idP.OpenID.Claims.Name = openID.Claims.Name
 	idP.OpenID.Claims.Email = openID.Claims.Email
 
-	secretName := "openid-secret"
+	secretName := providerSecretName(p, "client-secret")
 	idP.OpenID.ClientSecret.Name = secretName
 	secretContent, err := io.FetchStringSource(openID.ClientSecret)
 	if err != nil {
@@ -66,10 +66,6 @@ func validateOpenIDProvider(serializer *json.Serializer, p IdentityProvider) err
 		return err
 	}
 
-	if openID.ClientSecret.KeyFile != "" {
-		return errors.New("Usage of encrypted files as secret value is not supported")
-	}
-
 	if err := validateClientData(openID.ClientID, openID.ClientSecret); err != nil {
 		return err
 	}
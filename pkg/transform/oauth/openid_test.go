@@ -102,10 +102,9 @@ func TestOpenIDValidation(t *testing.T) {
 			expectedErr:  errors.New("Token endpoint can't be empty"),
 		},
 		{
-			name:         "fail if key file is present for client secret in openid provider",
-			requireError: true,
-			inputFile:    "testdata/openid/invalid-keyfile-master-config.yaml",
-			expectedErr:  errors.New("Usage of encrypted files as secret value is not supported"),
+			name:         "accept encrypted client secret in openid provider",
+			requireError: false,
+			inputFile:    "testdata/openid/encrypted-clientsecret-master-config.yaml",
 		},
 	}
 
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/konveyor/cpma/pkg/decode"
 	"github.com/konveyor/cpma/pkg/env"
 	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/oauth"
@@ -214,18 +213,13 @@ func (e OAuthExtraction) buildReportOutput() {
 			Comment:    "Translation of SessionConfig is not supported",
 		})
 
-	FinalReportOutput.Report.ComponentReports = append(FinalReportOutput.Report.ComponentReports, componentReport)
+	FinalReportOutput.AddComponentReport(componentReport)
 }
 
 // Extract collects OAuth configuration from an OCP3 cluster
 func (e OAuthTransform) Extract() (Extraction, error) {
 	logrus.Info("OAuthTransform::Extract")
-	content, err := io.FetchFile(env.Config().GetString("MasterConfigFile"))
-	if err != nil {
-		return nil, err
-	}
-
-	masterConfig, err := decode.MasterConfig(content)
+	masterConfig, err := SourceSnapshot.MasterConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -248,7 +242,7 @@ func (e OAuthTransform) Extract() (Extraction, error) {
 			}
 
 			if provider.File != "" {
-				htContent, err = io.FetchFile(provider.File)
+				htContent, err = io.FetchSecretFile(provider.File)
 				if err != nil {
 					return nil, err
 				}
@@ -266,7 +260,7 @@ func (e OAuthTransform) Extract() (Extraction, error) {
 				}
 			}
 			if provider.KeyFile != "" {
-				keyContent, err = io.FetchFile(provider.KeyFile)
+				keyContent, err = io.FetchSecretFile(provider.KeyFile)
 				if err != nil {
 					return nil, err
 				}
@@ -317,3 +311,8 @@ func (e OAuthExtraction) Validate() error {
 func (e OAuthTransform) Name() string {
 	return OAuthComponentName
 }
+
+// Dependencies returns API, which reads the master configuration first
+func (e OAuthTransform) Dependencies() []string {
+	return []string{APIComponentName}
+}
@@ -26,47 +26,52 @@ func TestOAuthExtractionTransform(t *testing.T) {
 	expectedSecretBasicAuthProviderClientCertCRYAML, err := ioutil.ReadFile("testdata/expected-CR-secret-basicauth-client-cert-secret.yaml")
 	require.NoError(t, err)
 	expectedManifests = append(expectedManifests,
-		transform.Manifest{Name: "100_CPMA-cluster-config-secret-basicauth-client-cert-secret.yaml", CRD: expectedSecretBasicAuthProviderClientCertCRYAML})
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-my-remote-basic-auth-provider-client-cert.yaml", CRD: expectedSecretBasicAuthProviderClientCertCRYAML})
 
 	expectedSecretBasicAuthProviderClientKeyCRYAML, err := ioutil.ReadFile("testdata/expected-CR-secret-basicauth-client-key-secret.yaml")
 	require.NoError(t, err)
 	expectedManifests = append(expectedManifests,
-		transform.Manifest{Name: "100_CPMA-cluster-config-secret-basicauth-client-key-secret.yaml", CRD: expectedSecretBasicAuthProviderClientKeyCRYAML})
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-my-remote-basic-auth-provider-client-key.yaml", CRD: expectedSecretBasicAuthProviderClientKeyCRYAML})
 
 	expectedSecretGithubProvider, err := ioutil.ReadFile("testdata/expected-CR-secret-github-secret.yaml")
 	require.NoError(t, err)
 	expectedManifests = append(expectedManifests,
-		transform.Manifest{Name: "100_CPMA-cluster-config-secret-github-secret.yaml", CRD: expectedSecretGithubProvider})
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-github123456789-client-secret.yaml", CRD: expectedSecretGithubProvider})
 
 	expectedSecretGitlabProvider, err := ioutil.ReadFile("testdata/expected-CR-secret-gitlab-secret.yaml")
 	require.NoError(t, err)
 	expectedManifests = append(expectedManifests,
-		transform.Manifest{Name: "100_CPMA-cluster-config-secret-gitlab-secret.yaml", CRD: expectedSecretGitlabProvider})
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-gitlab123456789-client-secret.yaml", CRD: expectedSecretGitlabProvider})
 
 	expectedSecretGoogleProvider, err := ioutil.ReadFile("testdata/expected-CR-secret-google.yaml")
 	require.NoError(t, err)
 	expectedManifests = append(expectedManifests,
-		transform.Manifest{Name: "100_CPMA-cluster-config-secret-google-secret.yaml", CRD: expectedSecretGoogleProvider})
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-google123456789123456789-client-secret.yaml", CRD: expectedSecretGoogleProvider})
 
 	expectedSecretHtpasswdProvider, err := ioutil.ReadFile("testdata/expected-CR-secret-htpasswd.yaml")
 	require.NoError(t, err)
 	expectedManifests = append(expectedManifests,
-		transform.Manifest{Name: "100_CPMA-cluster-config-secret-htpasswd-secret.yaml", CRD: expectedSecretHtpasswdProvider})
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-htpasswd-auth-htpasswd.yaml", CRD: expectedSecretHtpasswdProvider})
 
 	expectedSecretKeystoneProviderCert, err := ioutil.ReadFile("testdata/expected-CR-secret-keystone-client-cert.yaml")
 	require.NoError(t, err)
 	expectedManifests = append(expectedManifests,
-		transform.Manifest{Name: "100_CPMA-cluster-config-secret-keystone-client-cert-secret.yaml", CRD: expectedSecretKeystoneProviderCert})
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-my-keystone-provider-client-cert.yaml", CRD: expectedSecretKeystoneProviderCert})
 
 	expectedSecretKeystoneProviderKey, err := ioutil.ReadFile("testdata/expected-CR-secret-keystone-client-key.yaml")
 	require.NoError(t, err)
 	expectedManifests = append(expectedManifests,
-		transform.Manifest{Name: "100_CPMA-cluster-config-secret-keystone-client-key-secret.yaml", CRD: expectedSecretKeystoneProviderKey})
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-my-keystone-provider-client-key.yaml", CRD: expectedSecretKeystoneProviderKey})
+
+	expectedSecretLDAPProvider, err := ioutil.ReadFile("testdata/expected-CR-secret-ldap.yaml")
+	require.NoError(t, err)
+	expectedManifests = append(expectedManifests,
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-my-ldap-provider-bind-password.yaml", CRD: expectedSecretLDAPProvider})
 
 	expectedSecretOpenidProvider, err := ioutil.ReadFile("testdata/expected-CR-secret-openid.yaml")
 	require.NoError(t, err)
 	expectedManifests = append(expectedManifests,
-		transform.Manifest{Name: "100_CPMA-cluster-config-secret-openid-secret.yaml", CRD: expectedSecretOpenidProvider})
+		transform.Manifest{Name: "100_CPMA-cluster-config-secret-my-openid-connect-client-secret.yaml", CRD: expectedSecretOpenidProvider})
 
 	expectedSecretTemplateLogin, err := ioutil.ReadFile("testdata/expected-CR-secret-templates-login-secret.yaml")
 	require.NoError(t, err)
@@ -181,3 +186,32 @@ func TestOAuthExtractionTransform(t *testing.T) {
 		})
 	}
 }
+
+func TestOAuthCRHoldsNoCredentials(t *testing.T) {
+	identityProviders, templates, err := cpmatest.LoadIPTestData("testdata/master_config-bulk.yaml")
+	require.NoError(t, err)
+	for i := range identityProviders {
+		identityProviders[i].CrtData = []byte("client certificate of " + identityProviders[i].Name)
+		identityProviders[i].KeyData = []byte("client key of " + identityProviders[i].Name)
+		identityProviders[i].HTFileData = []byte("htpasswd of " + identityProviders[i].Name)
+	}
+
+	oauthResources, err := oauth.Translate(identityProviders, oauth.TokenConfig{}, *templates)
+	require.NoError(t, err)
+	oauthCR, err := transform.GenYAML(oauthResources.OAuthCRD)
+	require.NoError(t, err)
+
+	credentials := 0
+	for _, secret := range oauthResources.Secrets {
+		assert.Equal(t, oauth.OAuthNamespace, secret.Namespace)
+		assert.Contains(t, string(oauthCR), "name: "+secret.Name, "secret %s isn't referenced", secret.Name)
+		for key, value := range secret.Data {
+			require.NotEmpty(t, value, "%s of secret %s", key, secret.Name)
+			assert.NotContains(t, string(oauthCR), string(value), "%s of secret %s is in the OAuth CR", key, secret.Name)
+			credentials++
+		}
+	}
+	// Client certificates and keys of basic auth and keystone, client secrets of github, gitlab, google and openid,
+	// the htpasswd file, the LDAP bind password and templates
+	assert.Equal(t, 13, credentials)
+}
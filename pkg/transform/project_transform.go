@@ -3,9 +3,7 @@ package transform
 import (
 	"fmt"
 
-	"github.com/konveyor/cpma/pkg/decode"
 	"github.com/konveyor/cpma/pkg/env"
-	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/project"
 	"github.com/konveyor/cpma/pkg/transform/reportoutput"
 	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
@@ -125,19 +123,14 @@ func (e ProjectExtraction) buildReportOutput() {
 			Comment:    fmt.Sprintf("Not supported in OCP4: %s", e.ProjectConfig.SecurityAllocator.UIDAllocatorRange),
 		})
 
-	FinalReportOutput.Report.ComponentReports = append(FinalReportOutput.Report.ComponentReports, componentReport)
+	FinalReportOutput.AddComponentReport(componentReport)
 }
 
 // Extract collects Project configuration information from an OCP3 cluster
 func (e ProjectTransform) Extract() (Extraction, error) {
 	logrus.Info("ProjectTransform::Extract")
 
-	content, err := io.FetchFile(env.Config().GetString("MasterConfigFile"))
-	if err != nil {
-		return nil, err
-	}
-
-	masterConfig, err := decode.MasterConfig(content)
+	masterConfig, err := SourceSnapshot.MasterConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -161,3 +154,8 @@ func (e ProjectExtraction) Validate() error {
 func (e ProjectTransform) Name() string {
 	return ProjectComponentName
 }
+
+// Dependencies returns API, which reads the master configuration first
+func (e ProjectTransform) Dependencies() []string {
+	return []string{APIComponentName}
+}
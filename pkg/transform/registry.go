@@ -0,0 +1,118 @@
+package transform
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// transformRegistry holds every available transform, in run order
+var transformRegistry = []Transform{
+	APITransform{},
+	ClusterTransform{},
+	CrioTransform{},
+	DockerTransform{},
+	ETCDTransform{},
+	OAuthTransform{},
+	SDNTransform{},
+	ImageTransform{},
+	ProjectTransform{},
+	SchedulerTransform{},
+	NodeTransform{},
+}
+
+// RegisteredTransforms returns names of all available transforms
+func RegisteredTransforms() []string {
+	names := make([]string, 0, len(transformRegistry))
+	for _, transform := range transformRegistry {
+		names = append(names, transform.Name())
+	}
+
+	return names
+}
+
+// SelectTransforms picks registered transforms to run. When only is not empty, just the listed
+// transforms and their dependencies are selected, then transforms listed in skip are left out.
+// Names are case insensitive, it returns selected transforms and names of the ones left out.
+func SelectTransforms(only, skip []string) ([]Transform, []string, error) {
+	only = splitComponentNames(only)
+	skip = splitComponentNames(skip)
+
+	for _, name := range append(append([]string{}, only...), skip...) {
+		if lookupTransform(name) == nil {
+			return nil, nil, errors.Errorf("unknown component %s, available components are: %s",
+				name, strings.Join(RegisteredTransforms(), ", "))
+		}
+	}
+
+	only = withDependencies(only)
+
+	var selected []Transform
+	var skipped []string
+	for _, transform := range transformRegistry {
+		if (len(only) > 0 && !containsName(only, transform.Name())) || containsName(skip, transform.Name()) {
+			skipped = append(skipped, transform.Name())
+			continue
+		}
+		selected = append(selected, transform)
+	}
+
+	for _, transform := range selected {
+		for _, dependency := range dependenciesOf(transform) {
+			if containsName(skipped, dependency) {
+				return nil, nil, errors.Errorf("component %s depends on skipped component %s", transform.Name(), dependency)
+			}
+		}
+	}
+
+	return selected, skipped, nil
+}
+
+// withDependencies adds the dependencies of listed transforms to names, recursively
+func withDependencies(names []string) []string {
+	names = append([]string{}, names...)
+	for i := 0; i < len(names); i++ {
+		for _, dependency := range dependenciesOf(lookupTransform(names[i])) {
+			if !containsName(names, dependency) {
+				names = append(names, dependency)
+			}
+		}
+	}
+
+	return names
+}
+
+// lookupTransform returns the registered transform with given name, nil if there is none
+func lookupTransform(name string) Transform {
+	for _, transform := range transformRegistry {
+		if strings.EqualFold(transform.Name(), name) {
+			return transform
+		}
+	}
+
+	return nil
+}
+
+// splitComponentNames accepts names passed as a list or as comma separated values
+func splitComponentNames(values []string) []string {
+	var names []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,78 @@
+package transform_test
+
+import (
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/transform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectTransforms(t *testing.T) {
+	testCases := []struct {
+		name             string
+		only             []string
+		skip             []string
+		expectedSelected []string
+		expectedSkipped  []string
+		expectedErr      string
+	}{
+		{
+			name:             "select all transforms",
+			expectedSelected: transform.RegisteredTransforms(),
+		},
+		{
+			name:             "select only some transforms",
+			only:             []string{"api", "OAuth"},
+			expectedSelected: []string{"API", "OAuth"},
+			expectedSkipped:  []string{"Cluster", "Crio", "Docker", "ETCD", "SDN", "Image", "Project", "Scheduler", "Node"},
+		},
+		{
+			name:             "skip comma separated transforms",
+			skip:             []string{"etcd,cluster"},
+			expectedSelected: []string{"API", "Crio", "Docker", "OAuth", "SDN", "Image", "Project", "Scheduler", "Node"},
+			expectedSkipped:  []string{"Cluster", "ETCD"},
+		},
+		{
+			name:             "select dependencies of selected transforms",
+			only:             []string{"scheduler"},
+			expectedSelected: []string{"API", "Scheduler"},
+			expectedSkipped:  []string{"Cluster", "Crio", "Docker", "ETCD", "OAuth", "SDN", "Image", "Project", "Node"},
+		},
+		{
+			name:             "skip takes precedence over only",
+			only:             []string{"SDN", "Image"},
+			skip:             []string{"Image"},
+			expectedSelected: []string{"API", "SDN"},
+			expectedSkipped:  []string{"Cluster", "Crio", "Docker", "ETCD", "OAuth", "Image", "Project", "Scheduler", "Node"},
+		},
+		{
+			name:        "skip dependency of selected transform",
+			skip:        []string{"API"},
+			expectedErr: "component OAuth depends on skipped component API",
+		},
+		{
+			name:        "unknown component",
+			skip:        []string{"Router"},
+			expectedErr: "unknown component Router, available components are: API, Cluster, Crio, Docker, ETCD, OAuth, SDN, Image, Project, Scheduler, Node",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, skipped, err := transform.SelectTransforms(tc.only, tc.skip)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var selectedNames []string
+			for _, s := range selected {
+				selectedNames = append(selectedNames, s.Name())
+			}
+			assert.Equal(t, tc.expectedSelected, selectedNames)
+			assert.Equal(t, tc.expectedSkipped, skipped)
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package transform
 
 import (
+	"sort"
+	"sync"
+
+	"github.com/konveyor/cpma/pkg/transform/cluster"
 	"github.com/konveyor/cpma/pkg/transform/reportoutput"
 	"github.com/sirupsen/logrus"
 )
@@ -10,11 +14,79 @@ type Report struct {
 	Report reportoutput.ReportOutput
 }
 
+// reportMutex guards FinalReportOutput, transforms can report concurrently
+var reportMutex sync.Mutex
+
 // Flush reports to files
 func (r Report) Flush() error {
 	return ReportOutputFlush(r)
 }
 
+// AddComponentReport appends a component report, safe for concurrent use
+func (r *Report) AddComponentReport(componentReport reportoutput.ComponentReport) {
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	r.Report.ComponentReports = append(r.Report.ComponentReports, componentReport)
+}
+
+// SetClusterReport sets the cluster report, safe for concurrent use
+func (r *Report) SetClusterReport(clusterReport cluster.Report) {
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	r.Report.ClusterReport = clusterReport
+}
+
+// AddComponentStatus records how a component was handled, safe for concurrent use
+func (r *Report) AddComponentStatus(componentStatus reportoutput.ComponentStatus) {
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	r.Report.RunStatus = append(r.Report.RunStatus, componentStatus)
+}
+
+// SetFiles sets files fetched from the source cluster, safe for concurrent use
+func (r *Report) SetFiles(files []reportoutput.FileStatus) {
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	r.Report.Files = files
+}
+
+// SetEnv sets env variables looked up in the master service environment, safe for concurrent use
+func (r *Report) SetEnv(env []reportoutput.EnvStatus) {
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	r.Report.Env = env
+}
+
+// sortComponentReports orders component reports and run status following the given component
+// names, so the final report doesn't depend on which transform finished first.
+// Components not listed keep their relative order and are placed last.
+func (r *Report) sortComponentReports(order []string) {
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+
+	position := make(map[string]int, len(order))
+	for i, name := range order {
+		position[name] = i
+	}
+
+	rank := func(component string) int {
+		if i, ok := position[component]; ok {
+			return i
+		}
+		return len(order)
+	}
+
+	reports := r.Report.ComponentReports
+	sort.SliceStable(reports, func(i, j int) bool {
+		return rank(reports[i].Component) < rank(reports[j].Component)
+	})
+
+	statuses := r.Report.RunStatus
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return rank(statuses[i].Component) < rank(statuses[j].Component)
+	})
+}
+
 // ReportOutputFlush flush reports to disk
 var ReportOutputFlush = func(r Report) error {
 	logrus.Info("Flushing reports to disk")
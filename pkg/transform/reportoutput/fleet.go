@@ -0,0 +1,219 @@
+package reportoutput
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// FleetReport aggregates reports of several clusters
+type FleetReport struct {
+	Clusters    []FleetCluster     `json:"clusters"`
+	Unsupported []FleetUnsupported `json:"unsupported,omitempty"`
+	// Conflicts are found when consolidating clusters into a single one
+	Conflicts []FleetConflict `json:"conflicts,omitempty"`
+}
+
+// FleetCluster summarizes the run of a single cluster
+type FleetCluster struct {
+	Name    string `json:"name"`
+	WorkDir string `json:"workDir"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	// Report is the path of the cluster HTML report, relative to the fleet report
+	Report            string            `json:"report,omitempty"`
+	IdentityProviders []string          `json:"identityProviders,omitempty"`
+	NetworkPlugins    []string          `json:"networkPlugins,omitempty"`
+	RunStatus         []ComponentStatus `json:"runStatus,omitempty"`
+}
+
+// FleetUnsupported lists clusters on which a component configuration is not supported
+type FleetUnsupported struct {
+	Component string   `json:"component"`
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Clusters  []string `json:"clusters"`
+}
+
+// FleetConflict is a setting with different values on clusters consolidated into a single one
+type FleetConflict struct {
+	Kind    string               `json:"kind"`
+	Field   string               `json:"field"`
+	Comment string               `json:"comment"`
+	Values  []FleetConflictValue `json:"values"`
+}
+
+// FleetConflictValue is the value of a conflicting setting on a cluster
+type FleetConflictValue struct {
+	Cluster string `json:"cluster"`
+	Value   string `json:"value"`
+}
+
+// Component report kinds summarized per cluster
+const (
+	identityProvidersKind = "IdentityProviders"
+	networkPluginKind     = "NetworkPlugin"
+)
+
+var (
+	fleetHTMLFileName = "fleet-report.html"
+	fleetJSONFileName = "fleet-report.json"
+)
+
+// AddCluster adds the report of a cluster, report is nil when the cluster run didn't produce one
+// and runErr is the error the run failed with. A cluster with a report is partial when some of its
+// components failed and others succeeded, it is failed when none succeeded.
+func (f *FleetReport) AddCluster(name, workDir string, report *ReportOutput, runErr error) {
+	cluster := FleetCluster{
+		Name:    name,
+		WorkDir: workDir,
+		Status:  StatusSucceeded,
+	}
+
+	if runErr != nil {
+		cluster.Status = StatusFailed
+		cluster.Error = runErr.Error()
+	}
+
+	if report != nil {
+		cluster.Report = filepath.Join(name, htmlFileName)
+		cluster.RunStatus = report.RunStatus
+		cluster.Status = runStatus(report.RunStatus, runErr)
+
+		for _, componentReport := range report.ComponentReports {
+			for _, r := range componentReport.Reports {
+				switch r.Kind {
+				case identityProvidersKind:
+					cluster.IdentityProviders = appendUnique(cluster.IdentityProviders, r.Name)
+				case networkPluginKind:
+					cluster.NetworkPlugins = appendUnique(cluster.NetworkPlugins, r.Name)
+				}
+
+				if !r.Supported {
+					f.addUnsupported(componentReport.Component, r, name)
+				}
+			}
+		}
+	}
+
+	f.Clusters = append(f.Clusters, cluster)
+}
+
+// runStatus returns the status of a cluster run which produced a report from the status of its components
+func runStatus(statuses []ComponentStatus, runErr error) string {
+	failed, succeeded := runErr != nil, false
+	for _, s := range statuses {
+		switch s.Status {
+		case StatusFailed:
+			failed = true
+		case StatusSucceeded:
+			succeeded = true
+		}
+	}
+
+	switch {
+	case !failed:
+		return StatusSucceeded
+	case succeeded:
+		return StatusPartial
+	default:
+		return StatusFailed
+	}
+}
+
+// FailedComponents returns components which failed on the cluster
+func (c FleetCluster) FailedComponents() []string {
+	return c.componentsWithStatus(StatusFailed)
+}
+
+// SkippedComponents returns components which were not selected to run on the cluster, or whose dependency failed
+func (c FleetCluster) SkippedComponents() []string {
+	return c.componentsWithStatus(StatusSkipped)
+}
+
+func (c FleetCluster) componentsWithStatus(status string) []string {
+	components := []string{}
+	for _, s := range c.RunStatus {
+		if s.Status == status {
+			components = append(components, s.Component)
+		}
+	}
+
+	return components
+}
+
+// addUnsupported adds cluster to clusters on which configuration r of component is not supported
+func (f *FleetReport) addUnsupported(component string, r Report, cluster string) {
+	for i, u := range f.Unsupported {
+		if u.Component == component && u.Kind == r.Kind && u.Name == r.Name {
+			f.Unsupported[i].Clusters = appendUnique(u.Clusters, cluster)
+			return
+		}
+	}
+
+	f.Unsupported = append(f.Unsupported, FleetUnsupported{
+		Component: component,
+		Kind:      r.Kind,
+		Name:      r.Name,
+		Clusters:  []string{cluster},
+	})
+
+	sort.SliceStable(f.Unsupported, func(i, j int) bool {
+		a, b := f.Unsupported[i], f.Unsupported[j]
+		if a.Component != b.Component {
+			return a.Component < b.Component
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+}
+
+// DumpFleetReport writes the fleet report as JSON and HTML to the work directory
+func DumpFleetReport(f FleetReport) error {
+	jsonReport, err := json.MarshalIndent(f, "", " ")
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal fleet report")
+	}
+
+	if err := io.WriteFile(jsonReport, fleetJSONFileName); err != nil {
+		return errors.Wrapf(err, "unable to write to report file: %s", fleetJSONFileName)
+	}
+	logrus.Infof("Report:Added: %s", fleetJSONFileName)
+
+	htmlTemplate, err := parseTemplates()
+	if err != nil {
+		return errors.Wrap(err, "unable to parse templates")
+	}
+
+	path := filepath.Join(env.Config().GetString("WorkDir"), fleetHTMLFileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to create html file")
+	}
+	defer file.Close()
+
+	if err := htmlTemplate.ExecuteTemplate(file, "fleet-report", f); err != nil {
+		return errors.Wrap(err, "unable to apply parsed template")
+	}
+	logrus.Infof("Report:Added: %s", fleetHTMLFileName)
+
+	return nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
@@ -0,0 +1,137 @@
+package reportoutput
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFleetReportAddCluster(t *testing.T) {
+	cluster1 := &ReportOutput{
+		ComponentReports: []ComponentReport{
+			{
+				Component: "OAuth",
+				Reports: []Report{
+					{Name: "HTPasswdPasswordIdentityProvider", Kind: "IdentityProviders", Supported: true},
+					{Name: "LDAPPasswordIdentityProvider", Kind: "IdentityProviders", Supported: true},
+					{Name: "Issuer", Kind: "IdentityProviders:OpenIDIdentityProvider", Supported: true},
+				},
+			},
+			{
+				Component: "SDN",
+				Reports: []Report{
+					{Name: "redhat/openshift-ovs-subnet", Kind: "NetworkPlugin", Supported: true},
+					{Name: "HostSubnetLength", Kind: "ClusterNetwork", Supported: false},
+				},
+			},
+		},
+		RunStatus: []ComponentStatus{{Component: "OAuth", Status: StatusSucceeded}},
+	}
+
+	cluster2 := &ReportOutput{
+		ComponentReports: []ComponentReport{
+			{
+				Component: "SDN",
+				Reports: []Report{
+					{Name: "cni", Kind: "NetworkPlugin", Supported: false},
+					{Name: "HostSubnetLength", Kind: "ClusterNetwork", Supported: false},
+				},
+			},
+		},
+	}
+
+	partialStatus := []ComponentStatus{
+		{Component: "OAuth", Status: StatusSucceeded},
+		{Component: "SDN", Status: StatusFailed, Errors: []string{"Network plugin cni is not supported"}},
+		{Component: "ETCD", Status: StatusSkipped, Comment: "Component was not selected to run"},
+	}
+	cluster4 := &ReportOutput{RunStatus: partialStatus}
+
+	fleet := FleetReport{}
+	fleet.AddCluster("cluster1", "data/cluster1", cluster1, nil)
+	fleet.AddCluster("cluster2", "data/cluster2", cluster2, errors.New("exit status 1"))
+	fleet.AddCluster("cluster3", "data/cluster3", nil, errors.New("report.json not found"))
+	fleet.AddCluster("cluster4", "data/cluster4", cluster4, errors.New("exit status 1"))
+
+	expectedClusters := []FleetCluster{
+		{
+			Name:              "cluster1",
+			WorkDir:           "data/cluster1",
+			Status:            StatusSucceeded,
+			Report:            "cluster1/report.html",
+			IdentityProviders: []string{"HTPasswdPasswordIdentityProvider", "LDAPPasswordIdentityProvider"},
+			NetworkPlugins:    []string{"redhat/openshift-ovs-subnet"},
+			RunStatus:         []ComponentStatus{{Component: "OAuth", Status: StatusSucceeded}},
+		},
+		{
+			Name:           "cluster2",
+			WorkDir:        "data/cluster2",
+			Status:         StatusFailed,
+			Error:          "exit status 1",
+			Report:         "cluster2/report.html",
+			NetworkPlugins: []string{"cni"},
+		},
+		{
+			Name:    "cluster3",
+			WorkDir: "data/cluster3",
+			Status:  StatusFailed,
+			Error:   "report.json not found",
+		},
+		{
+			Name:      "cluster4",
+			WorkDir:   "data/cluster4",
+			Status:    StatusPartial,
+			Error:     "exit status 1",
+			Report:    "cluster4/report.html",
+			RunStatus: partialStatus,
+		},
+	}
+	assert.Equal(t, expectedClusters, fleet.Clusters)
+	assert.Equal(t, []string{"SDN"}, fleet.Clusters[3].FailedComponents())
+	assert.Equal(t, []string{"ETCD"}, fleet.Clusters[3].SkippedComponents())
+
+	expectedUnsupported := []FleetUnsupported{
+		{Component: "SDN", Kind: "ClusterNetwork", Name: "HostSubnetLength", Clusters: []string{"cluster1", "cluster2"}},
+		{Component: "SDN", Kind: "NetworkPlugin", Name: "cni", Clusters: []string{"cluster2"}},
+	}
+	assert.Equal(t, expectedUnsupported, fleet.Unsupported)
+}
+
+func TestDumpFleetReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cpma-fleet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	savedWorkDir := env.Config().GetString("WorkDir")
+	defer env.Config().Set("WorkDir", savedWorkDir)
+	env.Config().Set("WorkDir", dir)
+
+	fleet := FleetReport{}
+	fleet.AddCluster("cluster1", filepath.Join(dir, "cluster1"), &ReportOutput{
+		ComponentReports: []ComponentReport{
+			{
+				Component: "SDN",
+				Reports:   []Report{{Name: "cni", Kind: "NetworkPlugin", Supported: false}},
+			},
+		},
+	}, nil)
+	require.NoError(t, DumpFleetReport(fleet))
+
+	jsonReport, err := ioutil.ReadFile(filepath.Join(dir, "fleet-report.json"))
+	require.NoError(t, err)
+	actual := FleetReport{}
+	require.NoError(t, json.Unmarshal(jsonReport, &actual))
+	assert.Equal(t, fleet, actual)
+
+	htmlReport, err := ioutil.ReadFile(filepath.Join(dir, "fleet-report.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(htmlReport), `<a href="cluster1/report.html">cluster1</a>`)
+	assert.Contains(t, string(htmlReport), "Unsupported components")
+}
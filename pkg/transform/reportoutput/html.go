@@ -36,6 +36,8 @@ func htmlOutput(report ReportOutput) {
 	if err != nil {
 		panic(errors.Wrap(err, "unable to apply parsed template"))
 	}
+
+	logrus.Infof("Report:Added: %s", htmlFileName)
 }
 
 func parseTemplates() (*template.Template, error) {
@@ -117,6 +119,10 @@ func parseTemplates() (*template.Template, error) {
 		"templates/rbac.gohtml",
 		"templates/cluster-report.gohtml",
 		"templates/component-report.gohtml",
+		"templates/run-status.gohtml",
+		"templates/files.gohtml",
+		"templates/env.gohtml",
+		"templates/fleet-report.gohtml",
 		"templates/main.gohtml",
 	}
 
@@ -128,8 +134,6 @@ func parseTemplates() (*template.Template, error) {
 		htmlTemplate = template.Must(htmlTemplate.Parse(stringTemplate))
 	}
 
-	logrus.Infof("Report:Added: %s", htmlFileName)
-
 	return htmlTemplate, nil
 }
 
@@ -8,6 +8,51 @@ import (
 type ReportOutput struct {
 	ClusterReport    cluster.Report    `json:"cluster,omitempty"`
 	ComponentReports []ComponentReport `json:"components,omitempty"`
+	RunStatus        []ComponentStatus `json:"runStatus,omitempty"`
+	Files            []FileStatus      `json:"files,omitempty"`
+	Env              []EnvStatus       `json:"env,omitempty"`
+}
+
+const (
+	// StatusSucceeded is the status of a component processed without error
+	StatusSucceeded = "succeeded"
+	// StatusSkipped is the status of a component left out on purpose
+	StatusSkipped = "skipped"
+	// StatusFailed is the status of a component that could not be processed
+	StatusFailed = "failed"
+	// StatusPartial is the status of a cluster on which some components failed while others succeeded
+	StatusPartial = "partial"
+)
+
+// ComponentStatus holds how a component was handled during the run
+type ComponentStatus struct {
+	Component string   `json:"component"`
+	Status    string   `json:"status"`
+	Comment   string   `json:"comment,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// FileStatus describes a file fetched from the source cluster: fetched, empty, missing or failed.
+// Attributes are set when the file could be read.
+type FileStatus struct {
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+	State  string `json:"state"`
+	Size   int64  `json:"size,omitempty"`
+	Mode   string `json:"mode,omitempty"`
+	UID    uint32 `json:"uid,omitempty"`
+	GID    uint32 `json:"gid,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// EnvStatus describes an env variable looked up in the master service environment: resolved or unresolved.
+// Source tells where a resolved variable was found, its value isn't reported.
+type EnvStatus struct {
+	Host   string `json:"host"`
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Source string `json:"source,omitempty"`
 }
 
 // ComponentReport holds a collection of ocp3 config reports
@@ -1,19 +1,69 @@
 package scheduler
 
 import (
-	"errors"
+	"encoding/json"
 
 	configv1 "github.com/openshift/api/config/v1"
 	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
 	apiVersion = "operator.openshift.io/v1"
 	kind       = "Scheduler"
 	name       = "cluster"
+
+	// PolicyConfigMapName is the name of the ConfigMap holding the scheduler policy
+	PolicyConfigMapName = "scheduler-policy"
+	// PolicyConfigMapKey is the ConfigMap key the scheduler reads the policy from
+	PolicyConfigMapKey = "policy.cfg"
+	policyNamespace    = "openshift-config"
 )
 
+// Policy holds predicates and priorities of a scheduler policy file
+type Policy struct {
+	Kind       string            `json:"kind"`
+	APIVersion string            `json:"apiVersion"`
+	Predicates []PredicatePolicy `json:"predicates"`
+	Priorities []PriorityPolicy  `json:"priorities"`
+}
+
+// PredicatePolicy is a predicate of a scheduler policy
+type PredicatePolicy struct {
+	Name string `json:"name"`
+}
+
+// PriorityPolicy is a priority of a scheduler policy
+type PriorityPolicy struct {
+	Name   string `json:"name"`
+	Weight int64  `json:"weight"`
+}
+
+// UnsupportedPredicates maps predicates OCP4 scheduler doesn't support to an explanation
+var UnsupportedPredicates = map[string]string{
+	"PodFitsPorts":            "Replaced by PodFitsHostPorts",
+	"NoVolumeNodeConflict":    "Replaced by CheckVolumeBinding",
+	"CheckNodeCondition":      "Node conditions are handled by taints in OCP4",
+	"CheckNodeMemoryPressure": "Node conditions are handled by taints in OCP4",
+	"CheckNodeDiskPressure":   "Node conditions are handled by taints in OCP4",
+	"CheckNodePIDPressure":    "Node conditions are handled by taints in OCP4",
+}
+
+// UnsupportedPriorities maps priorities OCP4 scheduler doesn't support to an explanation
+var UnsupportedPriorities = map[string]string{
+	"ServiceSpreadingPriority": "Replaced by SelectorSpreadPriority",
+}
+
+// IsConfigured tells whether master config sets a default node selector or a policy file, there is nothing to
+// migrate otherwise
+func IsConfigured(masterConfig legacyconfigv1.MasterConfig) bool {
+	return masterConfig.ProjectConfig.DefaultNodeSelector != "" || masterConfig.KubernetesMasterConfig.SchedulerConfigFile != ""
+}
+
 // Translate ProjectPolicyConfig definitions
 func Translate(masterConfig legacyconfigv1.MasterConfig) (*configv1.Scheduler, error) {
 	var schedulerCR configv1.Scheduler
@@ -27,13 +77,46 @@ func Translate(masterConfig legacyconfigv1.MasterConfig) (*configv1.Scheduler, e
 		schedulerCR.Spec.DefaultNodeSelector = masterConfig.ProjectConfig.DefaultNodeSelector
 	}
 
+	if masterConfig.KubernetesMasterConfig.SchedulerConfigFile != "" {
+		schedulerCR.Spec.Policy.Name = PolicyConfigMapName
+	}
+
 	return &schedulerCR, nil
 }
 
+// TranslatePolicy generates the ConfigMap holding scheduler policy file content
+func TranslatePolicy(policyContent []byte) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PolicyConfigMapName,
+			Namespace: policyNamespace,
+		},
+		Data: map[string]string{
+			PolicyConfigMapKey: string(policyContent),
+		},
+	}
+}
+
+// ParsePolicy decodes a scheduler policy file
+func ParsePolicy(policyContent []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := json.Unmarshal(policyContent, policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
 // Validate registry data collected from an OCP3 cluster
 func Validate(e legacyconfigv1.MasterConfig) error {
-	if len(e.ProjectConfig.DefaultNodeSelector) == 0 {
-		return errors.New("DefaultNodeSelector can't be empty")
+	if e.ProjectConfig.DefaultNodeSelector != "" {
+		if _, err := labels.Parse(e.ProjectConfig.DefaultNodeSelector); err != nil {
+			return errors.Wrap(err, "Invalid DefaultNodeSelector")
+		}
 	}
 
 	return nil
@@ -7,6 +7,7 @@ import (
 	cpmatest "github.com/konveyor/cpma/pkg/transform/internal/test"
 	"github.com/konveyor/cpma/pkg/transform/scheduler"
 	configv1 "github.com/openshift/api/config/v1"
+	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,25 +40,64 @@ func TestTransformScheduler(t *testing.T) {
 	expectedCrd, err := loadExpectedScheduler("testdata/expected-CR-scheduler.yaml")
 	require.NoError(t, err)
 
+	policyMasterConfig, err := cpmatest.LoadMasterConfig("testdata/master_config-policy.yaml")
+	require.NoError(t, err)
+
+	expectedPolicyCrd, err := loadExpectedScheduler("testdata/expected-CR-scheduler-policy.yaml")
+	require.NoError(t, err)
+
 	testCases := []struct {
-		name        string
-		expectedCrd *configv1.Scheduler
+		name         string
+		masterConfig *legacyconfigv1.MasterConfig
+		expectedCrd  *configv1.Scheduler
 	}{
 		{
-			name:        "build basic scheduler",
-			expectedCrd: expectedCrd,
+			name:         "build basic scheduler",
+			masterConfig: masterConfig,
+			expectedCrd:  expectedCrd,
+		},
+		{
+			name:         "build scheduler referencing policy",
+			masterConfig: policyMasterConfig,
+			expectedCrd:  expectedPolicyCrd,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			schedulerResources, err := scheduler.Translate(*masterConfig)
+			schedulerResources, err := scheduler.Translate(*tc.masterConfig)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expectedCrd, schedulerResources)
 		})
 	}
 }
 
+func TestTranslatePolicy(t *testing.T) {
+	t.Parallel()
+	policyContent, err := ioutil.ReadFile("testdata/scheduler.json")
+	require.NoError(t, err)
+
+	configMap := scheduler.TranslatePolicy(policyContent)
+	assert.Equal(t, "scheduler-policy", configMap.Name)
+	assert.Equal(t, "openshift-config", configMap.Namespace)
+	assert.Equal(t, string(policyContent), configMap.Data["policy.cfg"])
+
+	policy, err := scheduler.ParsePolicy(policyContent)
+	require.NoError(t, err)
+	assert.Equal(t, []scheduler.PredicatePolicy{
+		{Name: "NoVolumeZoneConflict"},
+		{Name: "PodFitsPorts"},
+		{Name: "MatchNodeSelector"},
+	}, policy.Predicates)
+	assert.Equal(t, []scheduler.PriorityPolicy{
+		{Name: "LeastRequestedPriority", Weight: 1},
+		{Name: "ServiceSpreadingPriority", Weight: 1},
+	}, policy.Priorities)
+
+	_, err = scheduler.ParsePolicy([]byte("predicates: []"))
+	assert.Error(t, err)
+}
+
 func TestBasicAuthValidation(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -67,10 +107,20 @@ func TestBasicAuthValidation(t *testing.T) {
 		expectedErr  error
 	}{
 		{
-			name:         "fail on invalid ",
+			name:         "no default node selector nor policy",
+			requireError: false,
+			inputFile:    "testdata/master_config-empty-defaultnodeselector.yaml",
+		},
+		{
+			name:         "fail on invalid default node selector",
 			requireError: true,
 			inputFile:    "testdata/master_config-invalid-defaultnodeselector.yaml",
-			expectedErr:  errors.New("DefaultNodeSelector can't be empty"),
+			expectedErr:  errors.New("Invalid DefaultNodeSelector: unable to parse requirement: found '', expected: ',' or ')'"),
+		},
+		{
+			name:         "policy without default node selector",
+			requireError: false,
+			inputFile:    "testdata/master_config-policy.yaml",
 		},
 	}
 
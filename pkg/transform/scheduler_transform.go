@@ -1,7 +1,8 @@
 package transform
 
 import (
-	"github.com/konveyor/cpma/pkg/decode"
+	"fmt"
+
 	"github.com/konveyor/cpma/pkg/env"
 	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/reportoutput"
@@ -16,6 +17,8 @@ const SchedulerComponentName = "Scheduler"
 // SchedulerExtraction is a Scheduler specific extraction
 type SchedulerExtraction struct {
 	legacyconfigv1.MasterConfig
+	// PolicyContent holds content of the scheduler policy file referenced by master config
+	PolicyContent []byte
 }
 
 // SchedulerTransform is a Scheduler specific transform
@@ -46,6 +49,10 @@ func (e SchedulerExtraction) Transform() ([]Output, error) {
 func (e SchedulerExtraction) buildManifestOutput() (Output, error) {
 	var manifests []Manifest
 
+	if !scheduler.IsConfigured(e.MasterConfig) {
+		return ManifestOutput{}, nil
+	}
+
 	schedulerCR, err := scheduler.Translate(e.MasterConfig)
 	if err != nil {
 		return nil, err
@@ -59,6 +66,16 @@ func (e SchedulerExtraction) buildManifestOutput() (Output, error) {
 	manifest := Manifest{Name: "100_CPMA-cluster-config-scheduler.yaml", CRD: schedulerCRYAML}
 	manifests = append(manifests, manifest)
 
+	if e.PolicyContent != nil {
+		policyConfigMapYAML, err := GenYAML(scheduler.TranslatePolicy(e.PolicyContent))
+		if err != nil {
+			return nil, err
+		}
+
+		filename := "100_CPMA-cluster-config-configmap-" + scheduler.PolicyConfigMapName + ".yaml"
+		manifests = append(manifests, Manifest{Name: filename, CRD: policyConfigMapYAML})
+	}
+
 	return ManifestOutput{
 		Manifests: manifests,
 	}, nil
@@ -69,28 +86,87 @@ func (e SchedulerExtraction) buildReportOutput() {
 		Component: SchedulerComponentName,
 	}
 
+	comment := ""
+	if !scheduler.IsConfigured(e.MasterConfig) {
+		comment = "Neither a default node selector nor a scheduler policy is set, there is nothing to migrate"
+	}
+
 	componentReport.Reports = append(componentReport.Reports,
 		reportoutput.Report{
 			Name:       "DefaultNodeSelector",
 			Kind:       "ProjectConfig",
 			Supported:  true,
 			Confidence: HighConfidence,
-			Comment:    "",
+			Comment:    comment,
 		})
 
-	FinalReportOutput.Report.ComponentReports = append(FinalReportOutput.Report.ComponentReports, componentReport)
+	if e.PolicyContent != nil {
+		componentReport.Reports = append(componentReport.Reports, e.buildPolicyReports()...)
+	}
+
+	FinalReportOutput.AddComponentReport(componentReport)
 }
 
-// Extract collects Scheduler configuration information from an OCP3 cluster
-func (e SchedulerTransform) Extract() (Extraction, error) {
-	logrus.Info("SchedulerTransform::Extract")
+func (e SchedulerExtraction) buildPolicyReports() []reportoutput.Report {
+	reports := []reportoutput.Report{
+		{
+			Name:       e.KubernetesMasterConfig.SchedulerConfigFile,
+			Kind:       "SchedulerConfigFile",
+			Supported:  true,
+			Confidence: HighConfidence,
+			Comment:    fmt.Sprintf("Policy is set in ConfigMap %s, referenced by the Scheduler CR", scheduler.PolicyConfigMapName),
+		},
+	}
 
-	content, err := io.FetchFile(env.Config().GetString("MasterConfigFile"))
+	policy, err := scheduler.ParsePolicy(e.PolicyContent)
 	if err != nil {
-		return nil, err
+		return append(reports, reportoutput.Report{
+			Name:       e.KubernetesMasterConfig.SchedulerConfigFile,
+			Kind:       "SchedulerConfigFile",
+			Supported:  false,
+			Confidence: NoConfidence,
+			Comment:    fmt.Sprintf("Unable to parse scheduler policy, it must be reviewed manually: %s", err),
+		})
+	}
+
+	for _, predicate := range policy.Predicates {
+		report := reportoutput.Report{
+			Name:       predicate.Name,
+			Kind:       "SchedulerPolicy:Predicate",
+			Supported:  true,
+			Confidence: HighConfidence,
+		}
+		if comment, ok := scheduler.UnsupportedPredicates[predicate.Name]; ok {
+			report.Supported = false
+			report.Confidence = NoConfidence
+			report.Comment = fmt.Sprintf("Predicate is not supported in OCP4: %s", comment)
+		}
+		reports = append(reports, report)
+	}
+
+	for _, priority := range policy.Priorities {
+		report := reportoutput.Report{
+			Name:       priority.Name,
+			Kind:       "SchedulerPolicy:Priority",
+			Supported:  true,
+			Confidence: HighConfidence,
+		}
+		if comment, ok := scheduler.UnsupportedPriorities[priority.Name]; ok {
+			report.Supported = false
+			report.Confidence = NoConfidence
+			report.Comment = fmt.Sprintf("Priority is not supported in OCP4: %s", comment)
+		}
+		reports = append(reports, report)
 	}
 
-	masterConfig, err := decode.MasterConfig(content)
+	return reports
+}
+
+// Extract collects Scheduler configuration information from an OCP3 cluster
+func (e SchedulerTransform) Extract() (Extraction, error) {
+	logrus.Info("SchedulerTransform::Extract")
+
+	masterConfig, err := SourceSnapshot.MasterConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +174,13 @@ func (e SchedulerTransform) Extract() (Extraction, error) {
 	var extraction SchedulerExtraction
 	extraction.MasterConfig = *masterConfig
 
+	if policyFile := masterConfig.KubernetesMasterConfig.SchedulerConfigFile; policyFile != "" {
+		extraction.PolicyContent, err = io.FetchFile(masterConfigPath(policyFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return extraction, nil
 }
 
@@ -114,3 +197,8 @@ func (e SchedulerExtraction) Validate() error {
 func (e SchedulerTransform) Name() string {
 	return SchedulerComponentName
 }
+
+// Dependencies returns API, which reads the master configuration first
+func (e SchedulerTransform) Dependencies() []string {
+	return []string{APIComponentName}
+}
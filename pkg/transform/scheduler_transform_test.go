@@ -14,13 +14,20 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func loadSchedulerExtraction() (transform.SchedulerExtraction, error) {
+func loadSchedulerExtraction(masterConfigFile, policyFile string) (transform.SchedulerExtraction, error) {
 	var extraction transform.SchedulerExtraction
 
-	masterConfigContent, _ := ioutil.ReadFile("testdata/master_config-project.yaml")
+	masterConfigContent, _ := ioutil.ReadFile(masterConfigFile)
 	masterConfig, err := decode.MasterConfig(masterConfigContent)
+	if err != nil {
+		return extraction, err
+	}
 	extraction.MasterConfig = *masterConfig
 
+	if policyFile != "" {
+		extraction.PolicyContent, err = ioutil.ReadFile(policyFile)
+	}
+
 	return extraction, err
 }
 
@@ -40,16 +47,57 @@ func TestSchedulerExtractionTransform(t *testing.T) {
 	err = json.Unmarshal(jsonData, &expectedReport)
 	require.NoError(t, err)
 
+	var expectedPolicyManifests []transform.Manifest
+
+	expectedPolicySchedulerCRYAML, err := ioutil.ReadFile("testdata/expected-CR-scheduler-policy.yaml")
+	require.NoError(t, err)
+
+	expectedPolicyConfigMapYAML, err := ioutil.ReadFile("testdata/expected-CR-configmap-scheduler-policy.yaml")
+	require.NoError(t, err)
+
+	expectedPolicyManifests = append(expectedPolicyManifests,
+		transform.Manifest{Name: "100_CPMA-cluster-config-scheduler.yaml", CRD: expectedPolicySchedulerCRYAML},
+		transform.Manifest{Name: "100_CPMA-cluster-config-configmap-scheduler-policy.yaml", CRD: expectedPolicyConfigMapYAML})
+
+	expectedPolicyReport := reportoutput.ReportOutput{}
+	jsonData, err = io.ReadFile("testdata/expected-report-scheduler-policy.json")
+	require.NoError(t, err)
+
+	err = json.Unmarshal(jsonData, &expectedPolicyReport)
+	require.NoError(t, err)
+
+	expectedEmptyReport := reportoutput.ReportOutput{}
+	jsonData, err = io.ReadFile("testdata/expected-report-scheduler-empty.json")
+	require.NoError(t, err)
+
+	err = json.Unmarshal(jsonData, &expectedEmptyReport)
+	require.NoError(t, err)
+
 	testCases := []struct {
 		name              string
+		masterConfigFile  string
+		policyFile        string
 		expectedManifests []transform.Manifest
 		expectedReports   reportoutput.ReportOutput
 	}{
 		{
 			name:              "transform project extraction",
+			masterConfigFile:  "testdata/master_config-project.yaml",
 			expectedManifests: expectedManifests,
 			expectedReports:   expectedReport,
 		},
+		{
+			name:              "transform scheduler extraction with policy file",
+			masterConfigFile:  "testdata/master_config-scheduler.yaml",
+			policyFile:        "testdata/scheduler.json",
+			expectedManifests: expectedPolicyManifests,
+			expectedReports:   expectedPolicyReport,
+		},
+		{
+			name:             "transform scheduler extraction with nothing to migrate",
+			masterConfigFile: "scheduler/testdata/master_config-empty-defaultnodeselector.yaml",
+			expectedReports:  expectedEmptyReport,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -68,7 +116,7 @@ func TestSchedulerExtractionTransform(t *testing.T) {
 				return nil
 			}
 
-			testExtraction, err := loadSchedulerExtraction()
+			testExtraction, err := loadSchedulerExtraction(tc.masterConfigFile, tc.policyFile)
 			require.NoError(t, err)
 
 			go func() {
@@ -92,3 +140,59 @@ func TestSchedulerExtractionTransform(t *testing.T) {
 		})
 	}
 }
+
+func TestSchedulerExtractPolicyFile(t *testing.T) {
+	testCases := []struct {
+		name             string
+		masterConfigFile string
+		policyFile       string
+		expectedFetch    string
+	}{
+		{
+			name:             "absolute policy file",
+			masterConfigFile: "/etc/origin/master/master-config.yaml",
+			policyFile:       "/etc/origin/scheduler.json",
+			expectedFetch:    "/etc/origin/scheduler.json",
+		},
+		{
+			name:             "policy file relative to master config",
+			masterConfigFile: "/etc/origin/master/master-config.yaml",
+			policyFile:       "scheduler.json",
+			expectedFetch:    "/etc/origin/master/scheduler.json",
+		},
+		{
+			name:             "policy file in parent directory of master config",
+			masterConfigFile: "/etc/origin/master/master-config.yaml",
+			policyFile:       "../scheduler.json",
+			expectedFetch:    "/etc/origin/scheduler.json",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer env.Config().Set("MasterConfigFile", env.Config().Get("MasterConfigFile"))
+			env.Config().Set("MasterConfigFile", tc.masterConfigFile)
+
+			policy, err := ioutil.ReadFile("testdata/scheduler.json")
+			require.NoError(t, err)
+
+			var fetched []string
+			fetchFile := io.FetchFile
+			defer func() { io.FetchFile = fetchFile }()
+			io.FetchFile = func(src string) ([]byte, error) {
+				fetched = append(fetched, src)
+				if src == tc.masterConfigFile {
+					return []byte("kind: MasterConfig\napiVersion: v1\nkubernetesMasterConfig:\n  schedulerConfigFile: " + tc.policyFile + "\n"), nil
+				}
+				return policy, nil
+			}
+			transform.SourceSnapshot = transform.NewSnapshot()
+			defer func() { transform.SourceSnapshot = transform.NewSnapshot() }()
+
+			extraction, err := transform.SchedulerTransform{}.Extract()
+			require.NoError(t, err)
+			assert.Equal(t, []string{tc.masterConfigFile, tc.expectedFetch}, fetched)
+			assert.Equal(t, policy, extraction.(transform.SchedulerExtraction).PolicyContent)
+		})
+	}
+}
@@ -3,9 +3,7 @@ package transform
 import (
 	"fmt"
 
-	"github.com/konveyor/cpma/pkg/decode"
 	"github.com/konveyor/cpma/pkg/env"
-	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/reportoutput"
 	"github.com/konveyor/cpma/pkg/transform/sdn"
 	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
@@ -73,6 +71,27 @@ func (e SDNExtraction) buildReportOutput() {
 		Component: SDNComponentName,
 	}
 
+	pluginName := e.MasterConfig.NetworkConfig.NetworkPluginName
+	if mode, err := sdn.SelectNetworkPlugin(pluginName); err == nil {
+		componentReport.Reports = append(componentReport.Reports,
+			reportoutput.Report{
+				Name:       pluginName,
+				Kind:       "NetworkPlugin",
+				Supported:  true,
+				Confidence: HighConfidence,
+				Comment:    fmt.Sprintf("Network plugin is supported in OCP4 as OpenShiftSDN %s mode", mode),
+			})
+	} else {
+		componentReport.Reports = append(componentReport.Reports,
+			reportoutput.Report{
+				Name:       pluginName,
+				Kind:       "NetworkPlugin",
+				Supported:  false,
+				Confidence: NoConfidence,
+				Comment:    "Network plugin is not supported in OCP4",
+			})
+	}
+
 	for _, n := range e.MasterConfig.NetworkConfig.ClusterNetworks {
 		cidrComment := fmt.Sprintf("Networks must be configured during installation, it's possible to use %s", n.CIDR)
 		componentReport.Reports = append(componentReport.Reports,
@@ -121,19 +140,14 @@ func (e SDNExtraction) buildReportOutput() {
 			Comment:    "Translation of this configuration is not supported, refer to ingress operator configuration for more information",
 		})
 
-	FinalReportOutput.Report.ComponentReports = append(FinalReportOutput.Report.ComponentReports, componentReport)
+	FinalReportOutput.AddComponentReport(componentReport)
 }
 
 // Extract collects SDN configuration information from an OCP3 cluster
 func (e SDNTransform) Extract() (Extraction, error) {
 	logrus.Info("SDNTransform::Extract")
 
-	content, err := io.FetchFile(env.Config().GetString("MasterConfigFile"))
-	if err != nil {
-		return nil, err
-	}
-
-	masterConfig, err := decode.MasterConfig(content)
+	masterConfig, err := SourceSnapshot.MasterConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -157,3 +171,8 @@ func (e SDNExtraction) Validate() error {
 func (e SDNTransform) Name() string {
 	return SDNComponentName
 }
+
+// Dependencies returns API, which reads the master configuration first
+func (e SDNTransform) Dependencies() []string {
+	return []string{APIComponentName}
+}
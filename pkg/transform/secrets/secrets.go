@@ -16,7 +16,7 @@ const (
 
 // new creates a secret core without Type and Data
 func new(name string, namespace string, secretType corev1.SecretType, data map[string][]byte) (*corev1.Secret, error) {
-	nameErrors := validation.IsDNS1123Label(name)
+	nameErrors := validation.IsDNS1123Subdomain(name)
 	if nameErrors != nil {
 		return nil, errors.New(secretNameError)
 	}
@@ -0,0 +1,155 @@
+package transform
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/konveyor/cpma/pkg/api"
+	"github.com/konveyor/cpma/pkg/decode"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io"
+	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
+	"github.com/pkg/errors"
+	"gopkg.in/go-ini/ini.v1"
+)
+
+// SourceSnapshot holds OCP3 source artifacts shared by all transforms of a run
+var SourceSnapshot = NewSnapshot()
+
+// Snapshot fetches and decodes each OCP3 source artifact once,
+// it's safe for concurrent use by transforms
+type Snapshot struct {
+	mutex     sync.Mutex
+	artifacts map[string]*artifact
+}
+
+// artifact is a source file decoded on first access
+type artifact struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+// NewSnapshot creates an empty Snapshot
+func NewSnapshot() *Snapshot {
+	return &Snapshot{artifacts: make(map[string]*artifact)}
+}
+
+// load fetches the file referenced by configKey and decodes it, only on first call
+func (s *Snapshot) load(configKey string, decodeContent func([]byte) (interface{}, error)) (interface{}, error) {
+	return s.loadOnce(configKey, func() (interface{}, error) {
+		content, err := io.FetchFile(env.Config().GetString(configKey))
+		if err != nil {
+			return nil, err
+		}
+		return decodeContent(content)
+	})
+}
+
+// loadOnce calls loadArtifact on first access to key and keeps its result
+func (s *Snapshot) loadOnce(key string, loadArtifact func() (interface{}, error)) (interface{}, error) {
+	s.mutex.Lock()
+	a, ok := s.artifacts[key]
+	if !ok {
+		a = &artifact{}
+		s.artifacts[key] = a
+	}
+	s.mutex.Unlock()
+
+	a.once.Do(func() {
+		a.value, a.err = loadArtifact()
+	})
+
+	return a.value, a.err
+}
+
+// MasterConfig returns decoded master configuration file
+func (s *Snapshot) MasterConfig() (*legacyconfigv1.MasterConfig, error) {
+	value, err := s.load("MasterConfigFile", func(content []byte) (interface{}, error) {
+		return decode.MasterConfig(content)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*legacyconfigv1.MasterConfig), nil
+}
+
+// masterConfigPath resolves a file referenced by the master configuration like OpenShift does,
+// a relative path is relative to the directory of the master configuration file
+func masterConfigPath(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+
+	return filepath.Join(filepath.Dir(env.Config().GetString("MasterConfigFile")), file)
+}
+
+// NodeConfig returns decoded node configuration file
+func (s *Snapshot) NodeConfig() (*legacyconfigv1.NodeConfig, error) {
+	value, err := s.load("NodeConfigFile", func(content []byte) (interface{}, error) {
+		return decode.NodeConfig(content)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*legacyconfigv1.NodeConfig), nil
+}
+
+// CrioConfig returns decoded crio configuration file
+func (s *Snapshot) CrioConfig() (Crios, error) {
+	value, err := s.load("CrioConfigFile", func(content []byte) (interface{}, error) {
+		var config Crios
+		if _, err := toml.Decode(string(content), &config); err != nil {
+			return nil, errors.Wrap(err, "Failed to decode crio, see error")
+		}
+		return config, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(Crios), nil
+}
+
+// ETCDConfig returns decoded etcd configuration file
+func (s *Snapshot) ETCDConfig() (*ini.File, error) {
+	value, err := s.load("ETCDConfigFile", func(content []byte) (interface{}, error) {
+		return ini.Load(content)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*ini.File), nil
+}
+
+// RegistriesConfig returns decoded registries configuration file
+func (s *Snapshot) RegistriesConfig() (RegistriesExtraction, error) {
+	value, err := s.load("RegistriesConfigFile", func(content []byte) (interface{}, error) {
+		var config RegistriesExtraction
+		if _, err := toml.Decode(string(content), &config); err != nil {
+			return nil, err
+		}
+		return config, nil
+	})
+	if err != nil {
+		return RegistriesExtraction{}, err
+	}
+
+	return value.(RegistriesExtraction), nil
+}
+
+// ClusterDump returns API resources read from the cluster dump directory
+func (s *Snapshot) ClusterDump() (*api.Dump, error) {
+	value, err := s.loadOnce("ClusterDump", func() (interface{}, error) {
+		return api.LoadDump(env.Config().GetString("ClusterDump"))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*api.Dump), nil
+}
@@ -0,0 +1,80 @@
+package transform_test
+
+import (
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io"
+	"github.com/konveyor/cpma/pkg/transform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotFetchesOnce(t *testing.T) {
+	env.Config().Set("MasterConfigFile", "testdata/master_config-sdn.yaml")
+	env.Config().Set("RegistriesConfigFile", "testdata/registries.conf")
+	env.Config().Set("CrioConfigFile", "testdata/crio.conf")
+	env.Config().Set("ETCDConfigFile", "testdata/etcd.conf")
+
+	var fetches int32
+	fetchFile := io.FetchFile
+	defer func() { io.FetchFile = fetchFile }()
+	io.FetchFile = func(src string) ([]byte, error) {
+		atomic.AddInt32(&fetches, 1)
+		return ioutil.ReadFile(src)
+	}
+
+	snapshot := transform.NewSnapshot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			masterConfig, err := snapshot.MasterConfig()
+			if assert.NoError(t, err) {
+				assert.Equal(t, "10.128.0.0/14", masterConfig.NetworkConfig.ClusterNetworks[0].CIDR)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	registriesConfig, err := snapshot.RegistriesConfig()
+	require.NoError(t, err)
+	assert.NotEmpty(t, registriesConfig.Registries["search"].List)
+
+	crioConfig, err := snapshot.CrioConfig()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2048), crioConfig["crio"].Runtime.PidsLimit)
+
+	etcdConfig, err := snapshot.ETCDConfig()
+	require.NoError(t, err)
+	assert.NotEmpty(t, etcdConfig.Section("").Key("ETCD_LISTEN_CLIENT_URLS").String())
+
+	_, err = snapshot.MasterConfig()
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&fetches))
+}
+
+func TestSnapshotKeepsErrors(t *testing.T) {
+	env.Config().Set("NodeConfigFile", "testdata/missing-node-config.yaml")
+
+	var fetches int32
+	fetchFile := io.FetchFile
+	defer func() { io.FetchFile = fetchFile }()
+	io.FetchFile = func(src string) ([]byte, error) {
+		atomic.AddInt32(&fetches, 1)
+		return ioutil.ReadFile(src)
+	}
+
+	snapshot := transform.NewSnapshot()
+	_, err := snapshot.NodeConfig()
+	assert.Error(t, err)
+	_, err = snapshot.NodeConfig()
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
@@ -1,8 +1,16 @@
 package transform
 
 import (
+	"fmt"
+	"strings"
+	"sync"
+
 	"github.com/ghodss/yaml"
+	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
 	configv1 "github.com/openshift/api/config/v1"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	corev1 "k8s.io/api/core/v1"
@@ -48,8 +56,13 @@ type Manifest struct {
 	CRD  []byte
 }
 
+// DefaultParallelism is the number of transforms run concurrently when not configured
+const DefaultParallelism = 4
+
 // Runner a generic transform runner
 type Runner struct {
+	// Parallelism limits the number of transforms running at the same time
+	Parallelism int
 }
 
 // Extraction is a generic data extraction
@@ -69,74 +82,183 @@ type Output interface {
 	Flush() error
 }
 
-//Start generating manifests to be used with Openshift 4
-func Start() {
+// Start generating manifests to be used with Openshift 4
+func Start() error {
 	logrus.Info("Starting manifest and report generation")
+
+	transforms, skipped, err := SelectTransforms(env.Config().GetStringSlice("Only"), env.Config().GetStringSlice("Skip"))
+	if err != nil {
+		return err
+	}
+
+	FinalReportOutput = Report{}
+	io.ResetFetchedFiles()
+	io.ResetFetchedEnv()
+	for _, name := range skipped {
+		logrus.Infof("Transform:Skipping - %s", name)
+		FinalReportOutput.AddComponentStatus(reportoutput.ComponentStatus{
+			Component: name,
+			Status:    reportoutput.StatusSkipped,
+			Comment:   "Component was not selected to run",
+		})
+	}
+
+	SourceSnapshot = NewSnapshot()
 	runner := NewRunner()
 
-	runner.Transform([]Transform{
-		APITransform{},
-		ClusterTransform{},
-		CrioTransform{},
-		DockerTransform{},
-		ETCDTransform{},
-		OAuthTransform{},
-		SDNTransform{},
-		ImageTransform{},
-		ProjectTransform{},
-	})
-}
-
-// Transform is the process run to complete a transform
-func (r Runner) Transform(transforms []Transform) {
+	// Debug pods started by node-exec are privileged, delete them however the run ends
+	defer io.CloseDebugPodsOnInterrupt()()
+	defer io.CloseDebugPods()
+
+	err = runner.Transform(transforms)
+
+	if missing := io.MissingArchiveFiles(); len(missing) > 0 {
+		logrus.Warnf("Files not found in archive %s: %s", env.Config().GetString("Archive"), strings.Join(missing, ", "))
+	}
+
+	return err
+}
+
+// transformResult holds the outcome of a single transform
+type transformResult struct {
+	outputs []Output
+	err     error
+	// skipReason is set when the transform didn't run because of a dependency
+	skipReason string
+}
+
+// Transform is the process run to complete a transform,
+// it returns an error when at least one of the transforms failed
+func (r Runner) Transform(transforms []Transform) error {
 	logrus.Debug("TransformRunner::Transform")
 
-	// For each transform, extract the data, validate it, and run the transform.
-	// Handle any errors, and finally flush the output to it's desired destination
-	// NOTE: This should be parallelized with channels unless the transforms have
-	// some dependency on the outputs of others
-	for _, transform := range transforms {
-		logrus.Infof("Transform:Starting for - %s", transform.Name())
+	graph, err := newTransformGraph(transforms)
+	if err != nil {
+		return HandleError(err, "Runner")
+	}
+
+	parallelism := r.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
 
-		extraction, err := transform.Extract()
-		if err != nil {
-			HandleError(err, transform.Name())
-			continue
+	// Each transform waits for its dependencies to complete, then for a free slot,
+	// so independent transforms run concurrently up to the parallelism limit
+	results := make([]transformResult, len(transforms))
+	done := make([]chan struct{}, len(transforms))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	slots := make(chan struct{}, parallelism)
+
+	var wg sync.WaitGroup
+	for i := range transforms {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, j := range graph.dependencies[i] {
+				<-done[j]
+				if results[j].err != nil || results[j].skipReason != "" {
+					results[i].skipReason = fmt.Sprintf("Dependency %s did not succeed", transforms[j].Name())
+					return
+				}
+			}
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i].outputs, results[i].err = runTransform(transforms[i])
+		}(i)
+	}
+	wg.Wait()
+
+	// Outputs are flushed in declaration order, whatever order transforms finished in
+	var failed []string
+	order := make([]string, len(transforms))
+	for i, transform := range transforms {
+		order[i] = transform.Name()
+		status := reportoutput.ComponentStatus{
+			Component: transform.Name(),
+			Status:    reportoutput.StatusSucceeded,
 		}
 
-		if err := extraction.Validate(); err != nil {
-			HandleError(err, transform.Name())
+		if results[i].skipReason != "" {
+			logrus.Warnf("Skipping %s: %s", transform.Name(), results[i].skipReason)
+			status.Status = reportoutput.StatusSkipped
+			status.Comment = results[i].skipReason
+			FinalReportOutput.AddComponentStatus(status)
 			continue
 		}
 
-		outputs, err := extraction.Transform()
-		if err != nil {
-			HandleError(err, transform.Name())
-			continue
+		if results[i].err == nil {
+			results[i].err = flushOutputs(results[i].outputs)
 		}
 
-		for _, output := range outputs {
-			switch output.(type) {
-			case ManifestOutput:
-				if err := output.Flush(); err != nil {
-					HandleError(err, transform.Name())
-					continue
-				}
+		if results[i].err != nil {
+			HandleError(results[i].err, transform.Name())
+			status.Status = reportoutput.StatusFailed
+			status.Errors = errorChain(results[i].err)
+			failed = append(failed, transform.Name())
+		}
+
+		FinalReportOutput.AddComponentStatus(status)
+	}
+
+	FinalReportOutput.sortComponentReports(order)
+	FinalReportOutput.SetFiles(fileStatuses(io.FetchedFiles()))
+	FinalReportOutput.SetEnv(envStatuses(io.FetchedEnv()))
+	if err := FinalReportOutput.Flush(); err != nil {
+		return HandleError(err, "Report")
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("transformations failed for: %s", strings.Join(failed, ", "))
+	}
+
+	logrus.Info("Succesfully finished transformations")
+	return nil
+}
+
+// flushOutputs writes manifest outputs to their destination
+func flushOutputs(outputs []Output) error {
+	for _, output := range outputs {
+		switch output.(type) {
+		case ManifestOutput:
+			if err := output.Flush(); err != nil {
+				return err
 			}
 		}
 	}
 
-	err := FinalReportOutput.Flush()
+	return nil
+}
+
+// runTransform extracts the data, validates it, and runs the transform
+func runTransform(transform Transform) ([]Output, error) {
+	logrus.Infof("Transform:Starting for - %s", transform.Name())
+
+	extraction, err := transform.Extract()
 	if err != nil {
-		HandleError(err, "Report")
+		return nil, err
 	}
 
-	logrus.Info("Succesfully finished transformations")
+	if err := extraction.Validate(); err != nil {
+		return nil, err
+	}
+
+	return extraction.Transform()
 }
 
 // NewRunner creates a new Runner
 func NewRunner() *Runner {
-	return &Runner{}
+	parallelism := DefaultParallelism
+	if env.Config().IsSet("Parallelism") {
+		parallelism = env.Config().GetInt("Parallelism")
+	}
+
+	return &Runner{Parallelism: parallelism}
 }
 
 // HandleError handles errors
@@ -145,6 +267,28 @@ func HandleError(err error, transformType string) error {
 	return err
 }
 
+// errorChain returns messages of an error and of every error it wraps, outermost first
+func errorChain(err error) []string {
+	var chain []string
+	for err != nil {
+		message := err.Error()
+		if len(chain) == 0 || chain[len(chain)-1] != message {
+			chain = append(chain, message)
+		}
+
+		switch e := err.(type) {
+		case interface{ Cause() error }:
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			err = nil
+		}
+	}
+
+	return chain
+}
+
 // GenYAML returns a YAML of the CR
 func GenYAML(CR interface{}) ([]byte, error) {
 	yamlBytes, err := yaml.Marshal(CR)
@@ -154,3 +298,41 @@ func GenYAML(CR interface{}) ([]byte, error) {
 
 	return yamlBytes, nil
 }
+
+// fileStatuses converts records of fetched files for the report
+func fileStatuses(records []io.FileRecord) []reportoutput.FileStatus {
+	var statuses []reportoutput.FileStatus
+	for _, record := range records {
+		status := reportoutput.FileStatus{
+			Host:   record.Host,
+			Path:   record.Path,
+			State:  record.State,
+			Size:   record.Size,
+			UID:    record.UID,
+			GID:    record.GID,
+			SHA256: record.SHA256,
+			Error:  record.Error,
+		}
+		if record.State == io.FileFetched || record.State == io.FileEmpty {
+			status.Mode = fmt.Sprintf("%04o", record.Mode.Perm())
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// envStatuses converts records of env variables looked up for the report
+func envStatuses(records []io.EnvRecord) []reportoutput.EnvStatus {
+	var statuses []reportoutput.EnvStatus
+	for _, record := range records {
+		statuses = append(statuses, reportoutput.EnvStatus{
+			Host:   record.Host,
+			Name:   record.Name,
+			State:  record.State,
+			Source: record.Source,
+		})
+	}
+
+	return statuses
+}
@@ -7,8 +7,11 @@ import (
 
 	"github.com/konveyor/cpma/pkg/decode"
 	"github.com/konveyor/cpma/pkg/env"
+	"github.com/konveyor/cpma/pkg/io"
 	"github.com/konveyor/cpma/pkg/transform/oauth"
+	"github.com/konveyor/cpma/pkg/transform/reportoutput"
 	legacyconfigv1 "github.com/openshift/api/legacyconfig/v1"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -31,7 +34,7 @@ func TestOauthGenYAML(t *testing.T) {
 			name:                    "generate yaml for oauth providers",
 			inputConfigfile:         "testdata/master_config-bulk.yaml",
 			expectedYaml:            "testdata/expected-master_config-oauth-bulk.yaml",
-			expectedSecretsLength:   9,
+			expectedSecretsLength:   10,
 			expectedConfigMapsength: 6,
 		},
 		{
@@ -147,3 +150,43 @@ func TestAllOtherCRGenYaml(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorChain(t *testing.T) {
+	rootErr := errors.New("file not found")
+	err := errors.Wrap(errors.Wrap(rootErr, "unable to fetch master config"), "extract failed")
+
+	assert.Equal(t, []string{
+		"extract failed: unable to fetch master config: file not found",
+		"unable to fetch master config: file not found",
+		"file not found",
+	}, errorChain(err))
+	assert.Nil(t, errorChain(nil))
+}
+
+func TestFileStatuses(t *testing.T) {
+	records := []io.FileRecord{
+		{Host: "master0", Path: "/etc/origin/master/master-config.yaml", State: io.FileFetched, Size: 12, Mode: 0640, GID: 994, SHA256: "checksum"},
+		{Host: "master0", Path: "/etc/origin/master/htpasswd", State: io.FileEmpty, Mode: 0600},
+		{Host: "master0", Path: "/etc/crio/crio.conf", State: io.FileMissing},
+		{Host: "master0", Path: "/etc/etcd/etcd.conf", State: io.FileFailed, Error: "permission denied"},
+	}
+
+	assert.Equal(t, []reportoutput.FileStatus{
+		{Host: "master0", Path: "/etc/origin/master/master-config.yaml", State: "fetched", Size: 12, Mode: "0640", GID: 994, SHA256: "checksum"},
+		{Host: "master0", Path: "/etc/origin/master/htpasswd", State: "empty", Mode: "0600"},
+		{Host: "master0", Path: "/etc/crio/crio.conf", State: "missing"},
+		{Host: "master0", Path: "/etc/etcd/etcd.conf", State: "failed", Error: "permission denied"},
+	}, fileStatuses(records))
+}
+
+func TestEnvStatuses(t *testing.T) {
+	records := []io.EnvRecord{
+		{Host: "master0", Name: "GITHUB_SECRET", State: io.EnvResolved, Source: "/etc/sysconfig/atomic-openshift-master-api"},
+		{Host: "master0", Name: "LDAP_PASSWORD", State: io.EnvUnresolved},
+	}
+
+	assert.Equal(t, []reportoutput.EnvStatus{
+		{Host: "master0", Name: "GITHUB_SECRET", State: "resolved", Source: "/etc/sysconfig/atomic-openshift-master-api"},
+		{Host: "master0", Name: "LDAP_PASSWORD", State: "unresolved"},
+	}, envStatuses(records))
+}